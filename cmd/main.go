@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 
@@ -14,6 +16,21 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		if err := app.Migrate(ctx, cfg, os.Stdout, args[1:]...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if args := flag.Args(); len(args) > 0 && args[0] == "role" {
+		if err := app.SetRole(ctx, cfg, os.Stdout, args[1:]...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	application := app.New(cfg)
 	application.MustStart(ctx)
 
@@ -4,16 +4,31 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/Onnywrite/tinkoff-prod/internal/config"
 	server "github.com/Onnywrite/tinkoff-prod/internal/http-server"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/mailer"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/oidc"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/passhash"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/passpolicy"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/secretbox"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/throttle"
 	"github.com/Onnywrite/tinkoff-prod/internal/lib/tokens"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/comments"
 	"github.com/Onnywrite/tinkoff-prod/internal/services/countries"
 	"github.com/Onnywrite/tinkoff-prod/internal/services/feed"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/follows"
 	"github.com/Onnywrite/tinkoff-prod/internal/services/likes"
 	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage/memory"
 	"github.com/Onnywrite/tinkoff-prod/internal/storage/pg"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
@@ -22,16 +37,37 @@ import (
 type Application struct {
 	log *slog.Logger
 	cfg *config.Config
-	db  *pg.PgStorage
+	db  Storage
 	srv *server.Server
+
+	emailLimiter *throttle.Limiter
+	ipLimiter    *throttle.Limiter
+	passwords    *passhash.Hasher
+	policy       *passpolicy.Policy
+	emailOptions atomic.Pointer[users.EmailOptions]
+	twoFactor    atomic.Pointer[users.TwoFactorOptions]
+	mailerFile   *os.File
+}
+
+type Storage interface {
+	storage.Storage
+	storage.Transactor
+	Disconnect() error
 }
 
 func New(cfg *config.Config) *Application {
 	logger := slog.New(erolog.New(os.Stdout, cfg.MustErologConfig()))
+	// DefaultOptions are valid, so the error is never returned
+	passwords, _ := passhash.New(passhash.DefaultOptions)
+	policy, _ := passpolicy.New(passpolicy.DefaultOptions)
 
 	return &Application{
-		log: logger,
-		cfg: cfg,
+		log:          logger,
+		cfg:          cfg,
+		emailLimiter: throttle.New(throttle.Options{}),
+		ipLimiter:    throttle.New(throttle.Options{}),
+		passwords:    passwords,
+		policy:       policy,
 	}
 }
 
@@ -45,10 +81,13 @@ func (a *Application) MustStart(ctx context.Context) {
 func (a *Application) Start(ctx context.Context) (err error) {
 	a.updateConfig(*a.cfg)
 	a.cfg.StartWatch(ctx, a.updateConfig)
-	a.db, err = pg.New(a.cfg.Conn)
+	a.db, err = newStorage(a.cfg)
 	if err != nil {
 		return err
 	}
+	if err = a.migrateOnStart(ctx); err != nil {
+		return err
+	}
 
 	countriesService := countries.New(a.log, a.db, a.db)
 
@@ -58,23 +97,93 @@ func (a *Application) Start(ctx context.Context) (err error) {
 		Provider:     a.db,
 		LikesCounter: a.db,
 		LikeProvider: a.db,
+		PostsLikes:   a.db,
+	})
+
+	commentsService := comments.New(a.log, comments.Dependencies{
+		Saver:        a.db,
+		Provider:     a.db,
+		Updater:      a.db,
+		Deleter:      a.db,
+		ListProvider: a.db,
+		Counter:      a.db,
+		PostsCounter: a.db,
+		PostProvider: a.db,
 	})
 
+	denylist := tokens.NewMemoryDenylist()
+
+	mailSender, err := a.newMailer()
+	if err != nil {
+		return err
+	}
+
+	encryptionKey, err := getSecret(a.cfg.Dir(), a.cfg.TwoFactor.EncryptionKey)
+	if err != nil {
+		return err
+	}
+	secretBox, err := secretbox.New(encryptionKey)
+	if err != nil {
+		return fmt.Errorf("app.Start: two_factor.encryption_key: %w", err)
+	}
+
+	oidcProviders, err := a.newOidcProviders()
+	if err != nil {
+		return err
+	}
+
 	usersService := users.New(a.log, users.Dependencies{
-		ByIdProvider:    a.db,
-		ByEmailProvider: a.db,
-		Saver:           a.db,
+		ByIdProvider:     a.db,
+		ByEmailProvider:  a.db,
+		Saver:            a.db,
+		Updater:          a.db,
+		PasswordUpdater:  a.db,
+		Passwords:        a.passwords,
+		PasswordPolicy:   a.policy,
+		Sessions:         a.db,
+		PersonalTokens:   a.db,
+		Denylist:         denylist,
+		FollowProvider:   a.db,
+		EmailLimiter:     a.emailLimiter,
+		IpLimiter:        a.ipLimiter,
+		Mailer:           mailSender,
+		EmailVerifier:    a.db,
+		EmailOptions:     func() users.EmailOptions { return *a.emailOptions.Load() },
+		TwoFactor:        a.db,
+		SecretBox:        secretBox,
+		TwoFactorOptions: func() users.TwoFactorOptions { return *a.twoFactor.Load() },
+		Oidc:             oidcProviders,
+		Identities:       a.db,
+		Admin:            a.db,
 	},
 	)
 
+	followsService := follows.New(a.log, follows.Dependencies{
+		Saver:          a.db,
+		Deleter:        a.db,
+		Provider:       a.db,
+		Counter:        a.db,
+		FollowProvider: a.db,
+		Approver:       a.db,
+		Requests:       a.db,
+		UserProvider:   a.db,
+		Access:         usersService,
+	})
+
 	feedService := feed.New(a.log, feed.Dependencies{
 		Provider:        a.db,
 		Counter:         a.db,
 		Saver:           a.db,
 		AuthorCounter:   a.db,
 		AuthorProvider:  a.db,
-		IsLikedProvider: likesService,
+		Timeline:        a.db,
+		Access:          usersService,
 		LikesProvider:   likesService,
+		CommentsCounter: commentsService,
+		Searcher:        a.db,
+		PostProvider:    a.db,
+		Revisions:       a.db,
+		Transactor:      a.db,
 	})
 
 	relativePath := a.cfg.Dir() + "/"
@@ -82,13 +191,90 @@ func (a *Application) Start(ctx context.Context) (err error) {
 	keyPath := relativePath + a.cfg.Https.Key
 	port := fmt.Sprintf(":%d", a.cfg.Https.Port)
 
-	a.srv = server.NewServer(a.log, port, certPath, keyPath, countriesService, usersService, feedService, likesService)
+	trustedProxies := make([]*net.IPNet, 0, len(a.cfg.Https.TrustedProxies))
+	for _, cidr := range a.cfg.Https.TrustedProxies {
+		_, proxy, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("trusted proxy '%s': %w", cidr, err)
+		}
+		trustedProxies = append(trustedProxies, proxy)
+	}
+
+	a.srv = server.NewServer(a.log, port, certPath, keyPath, trustedProxies, countriesService, usersService, feedService, likesService, commentsService, followsService, denylist)
 	a.srv.Start()
 
 	a.log.Info("started")
 	return nil
 }
 
+func newStorage(cfg *config.Config) (Storage, error) {
+	switch cfg.Storage {
+	case "postgres":
+		db, err := pg.New(cfg.Conn, pg.Options{
+			MaxOpenConns:    cfg.Pool.MaxOpenConns,
+			MaxIdleConns:    cfg.Pool.MaxIdleConns,
+			ConnMaxLifetime: cfg.Pool.ConnMaxLifetime,
+			ConnMaxIdleTime: cfg.Pool.ConnMaxIdleTime,
+			QueryTimeout:    cfg.Pool.QueryTimeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	case "memory":
+		return memory.New(), nil
+	}
+	return nil, fmt.Errorf("app.newStorage: unknown storage '%s'", cfg.Storage)
+}
+
+func (a *Application) newMailer() (mailer.Mailer, error) {
+	cfg := a.cfg.Email.Mailer
+	switch cfg.Kind {
+	case "smtp":
+		return mailer.NewSMTP(mailer.SMTPOptions{
+			Host:     cfg.Smtp.Host,
+			Port:     cfg.Smtp.Port,
+			Username: cfg.Smtp.Username,
+			Password: cfg.Smtp.Password,
+			From:     cfg.From,
+		}), nil
+	case "file":
+		f, err := os.OpenFile(a.cfg.Dir()+"/"+strings.TrimPrefix(cfg.Path, "./"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		a.mailerFile = f
+		return mailer.NewWriter(f, cfg.From), nil
+	case "stdout":
+		return mailer.NewWriter(os.Stdout, cfg.From), nil
+	}
+	return nil, fmt.Errorf("app.newMailer: unknown mailer '%s'", cfg.Kind)
+}
+
+// oidcTimeout limits every request to identity providers
+const oidcTimeout = 10 * time.Second
+
+func (a *Application) newOidcProviders() (*oidc.Providers, error) {
+	cfgs := make([]oidc.ProviderConfig, 0, len(a.cfg.Oidc.Providers))
+	for _, p := range a.cfg.Oidc.Providers {
+		secret, err := getSecret(a.cfg.Dir(), p.ClientSecret)
+		if err != nil {
+			return nil, fmt.Errorf("app.newOidcProviders: client secret of %s: %w", p.Name, err)
+		}
+
+		cfgs = append(cfgs, oidc.ProviderConfig{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientId:     p.ClientId,
+			ClientSecret: strings.TrimSpace(string(secret)),
+			RedirectUrl:  p.RedirectUrl,
+			Scopes:       p.Scopes,
+		})
+	}
+
+	return oidc.NewProviders(cfgs, a.cfg.Oidc.StateTTL, &http.Client{Timeout: oidcTimeout}), nil
+}
+
 func (a *Application) MustStop() {
 	if err := a.Stop(); err != nil {
 		a.log.Error("could not stop", "error", err)
@@ -101,6 +287,11 @@ func (a *Application) Stop() error {
 	if a.db == nil {
 		return fmt.Errorf("app.Application: database is not initialized")
 	}
+	if a.mailerFile != nil {
+		if err := a.mailerFile.Close(); err != nil {
+			a.log.Error("could not close mailer file", "error", err)
+		}
+	}
 	if err := a.db.Disconnect(); err != nil {
 		a.log.Error("could not disconnect from database", "error", err)
 		return err
@@ -116,27 +307,40 @@ func (a *Application) updateConfig(cfg config.Config) {
 		a.log.Debug("updated service name")
 	}
 
-	if a.cfg.AccessToken.Secret != cfg.AccessToken.Secret {
+	if a.cfg.AccessToken.Secret != cfg.AccessToken.Secret || a.cfg.AccessToken.PreviousSecret != cfg.AccessToken.PreviousSecret ||
+		tokens.AccessKeys.Current() == nil {
 		a.cfg.AccessToken.Secret = cfg.AccessToken.Secret
-		secret, err := getSecret(a.cfg.Dir(), cfg.AccessToken.Secret)
-		if err != nil {
+		a.cfg.AccessToken.PreviousSecret = cfg.AccessToken.PreviousSecret
+		if err := updateKeys(tokens.AccessKeys, a.cfg.Dir(), cfg.AccessToken); err != nil {
 			a.log.Error("could not update access secret", slog.String("error", err.Error()))
 		} else {
-			tokens.AccessSecret = secret
-			a.log.Debug("updated access secret")
+			a.log.Debug("updated access secret", slog.String("kid", tokens.AccessKeys.Current().Id))
 		}
 	}
-	if a.cfg.RefreshToken.Secret != cfg.RefreshToken.Secret {
+	if a.cfg.RefreshToken.Secret != cfg.RefreshToken.Secret || a.cfg.RefreshToken.PreviousSecret != cfg.RefreshToken.PreviousSecret ||
+		tokens.RefreshKeys.Current() == nil {
 		a.cfg.RefreshToken.Secret = cfg.RefreshToken.Secret
-		secret, err := getSecret(a.cfg.Dir(), cfg.RefreshToken.Secret)
-		if err != nil {
+		a.cfg.RefreshToken.PreviousSecret = cfg.RefreshToken.PreviousSecret
+		if err := updateKeys(tokens.RefreshKeys, a.cfg.Dir(), cfg.RefreshToken); err != nil {
 			a.log.Error("could not update refresh secret", slog.String("error", err.Error()))
 		} else {
-			tokens.RefreshSecret = secret
-			a.log.Debug("updated refresh secret")
+			a.log.Debug("updated refresh secret", slog.String("kid", tokens.RefreshKeys.Current().Id))
 		}
 	}
 
+	if registered := registeredClaims(cfg.AccessToken); tokens.AccessRegistered != registered {
+		a.cfg.AccessToken.Issuer, a.cfg.AccessToken.Audience = cfg.AccessToken.Issuer, cfg.AccessToken.Audience
+		a.cfg.AccessToken.Leeway = cfg.AccessToken.Leeway
+		tokens.AccessRegistered = registered
+		a.log.Debug("updated access claims")
+	}
+	if registered := registeredClaims(cfg.RefreshToken); tokens.RefreshRegistered != registered {
+		a.cfg.RefreshToken.Issuer, a.cfg.RefreshToken.Audience = cfg.RefreshToken.Issuer, cfg.RefreshToken.Audience
+		a.cfg.RefreshToken.Leeway = cfg.RefreshToken.Leeway
+		tokens.RefreshRegistered = registered
+		a.log.Debug("updated refresh claims")
+	}
+
 	if tokens.AccessTTL != cfg.AccessToken.TTL {
 		a.cfg.AccessToken.TTL = cfg.AccessToken.TTL
 		tokens.AccessTTL = cfg.AccessToken.TTL
@@ -148,6 +352,47 @@ func (a *Application) updateConfig(cfg config.Config) {
 		a.log.Debug("updated refresh ttl")
 	}
 
+	if opts := throttleOptions(cfg.SignIn.Email); a.emailLimiter.Options() != opts {
+		a.cfg.SignIn.Email = cfg.SignIn.Email
+		a.emailLimiter.SetOptions(opts)
+		a.log.Debug("updated sign in throttling by email")
+	}
+	if opts := throttleOptions(cfg.SignIn.Ip); a.ipLimiter.Options() != opts {
+		a.cfg.SignIn.Ip = cfg.SignIn.Ip
+		a.ipLimiter.SetOptions(opts)
+		a.log.Debug("updated sign in throttling by ip")
+	}
+
+	if opts := passwordOptions(cfg.Password); a.passwords.Options() != opts {
+		if err := a.passwords.SetOptions(opts); err != nil {
+			a.log.Error("could not update password hashing", slog.String("error", err.Error()))
+		} else {
+			a.cfg.Password = cfg.Password
+			a.log.Debug("updated password hashing")
+		}
+	}
+	if opts := policyOptions(cfg.PasswordPolicy, a.cfg.Dir()); a.policy.Options() != opts {
+		if err := a.policy.SetOptions(opts); err != nil {
+			a.log.Error("could not update password policy", slog.String("error", err.Error()))
+		} else {
+			a.cfg.PasswordPolicy = cfg.PasswordPolicy
+			a.log.Debug("updated password policy")
+		}
+	}
+
+	if opts := emailOptions(cfg.Email); a.emailOptions.Load() == nil || *a.emailOptions.Load() != opts {
+		a.cfg.Email.RequireVerified, a.cfg.Email.LinkBase = cfg.Email.RequireVerified, cfg.Email.LinkBase
+		a.cfg.Email.VerificationTTL, a.cfg.Email.ResetTTL = cfg.Email.VerificationTTL, cfg.Email.ResetTTL
+		a.emailOptions.Store(&opts)
+		a.log.Debug("updated email options")
+	}
+
+	if opts := twoFactorOptions(cfg.TwoFactor); a.twoFactor.Load() == nil || *a.twoFactor.Load() != opts {
+		a.cfg.TwoFactor.Issuer, a.cfg.TwoFactor.ChallengeTTL = cfg.TwoFactor.Issuer, cfg.TwoFactor.ChallengeTTL
+		a.twoFactor.Store(&opts)
+		a.log.Debug("updated two-factor options")
+	}
+
 	a.cfg.ResetWatchFreq(cfg.WatchFreq)
 
 	if erologger, ok := a.log.Handler().(*erolog.Logger); ok {
@@ -156,6 +401,96 @@ func (a *Application) updateConfig(cfg config.Config) {
 	a.log.Debug("updated config")
 }
 
+func throttleOptions(cfg config.ThrottleConfig) throttle.Options {
+	return throttle.Options{
+		FreeAttempts:    cfg.FreeAttempts,
+		BaseDelay:       cfg.BaseDelay,
+		MaxDelay:        cfg.MaxDelay,
+		LockoutAttempts: cfg.LockoutAttempts,
+		Lockout:         cfg.Lockout,
+		Window:          cfg.Window,
+	}
+}
+
+func passwordOptions(cfg config.PasswordConfig) passhash.Options {
+	return passhash.Options{
+		Algorithm:  cfg.Algorithm,
+		BcryptCost: cfg.BcryptCost,
+		Argon2: passhash.Argon2Options{
+			Time:       cfg.Argon2.Time,
+			Memory:     cfg.Argon2.Memory,
+			Threads:    cfg.Argon2.Threads,
+			SaltLength: cfg.Argon2.SaltLength,
+			KeyLength:  cfg.Argon2.KeyLength,
+		},
+	}
+}
+
+func policyOptions(cfg config.PasswordPolicyConfig, dir string) passpolicy.Options {
+	path := cfg.BreachedPath
+	if path != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return passpolicy.Options{
+		MinLength:        cfg.MinLength,
+		MinEntropy:       cfg.MinEntropy,
+		RejectPersonal:   cfg.RejectPersonal,
+		BreachedPath:     path,
+		BreachedMinCount: cfg.BreachedMinCount,
+	}
+}
+
+func emailOptions(cfg config.EmailConfig) users.EmailOptions {
+	return users.EmailOptions{
+		RequireVerified: cfg.RequireVerified,
+		VerificationTTL: cfg.VerificationTTL,
+		ResetTTL:        cfg.ResetTTL,
+		LinkBase:        strings.TrimSuffix(cfg.LinkBase, "/"),
+	}
+}
+
+func twoFactorOptions(cfg config.TwoFactorConfig) users.TwoFactorOptions {
+	return users.TwoFactorOptions{
+		Issuer:       cfg.Issuer,
+		ChallengeTTL: cfg.ChallengeTTL,
+	}
+}
+
+func registeredClaims(cfg config.TokenConfig) tokens.Registered {
+	return tokens.Registered{
+		Issuer:   cfg.Issuer,
+		Audience: cfg.Audience,
+		Leeway:   cfg.Leeway,
+	}
+}
+
+// updateKeys loads the secret and the previous one. Without previous_secret the replaced key
+// is kept for verification, so tokens it has signed stay valid until the next change
+func updateKeys(keys *tokens.KeySet, dir string, cfg config.TokenConfig) error {
+	current, err := loadKey(dir, cfg.Secret)
+	if err != nil {
+		return err
+	}
+
+	previous := keys.Current()
+	if cfg.PreviousSecret != "" {
+		if previous, err = loadKey(dir, cfg.PreviousSecret); err != nil {
+			return err
+		}
+	}
+
+	keys.Set(current, previous)
+	return nil
+}
+
+func loadKey(dir, secretSomething string) (*tokens.Key, error) {
+	secret, err := getSecret(dir, secretSomething)
+	if err != nil {
+		return nil, err
+	}
+	return tokens.ParseKey(secret)
+}
+
 func getSecret(relativePath, secretSomething string) ([]byte, error) {
 	secret, isFile := strings.CutPrefix(secretSomething, "file://")
 	if isFile {
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/config"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage/pg"
+	"github.com/Onnywrite/tinkoff-prod/migrations"
+)
+
+var ErrMigrateUsage = errors.New("usage: migrate up|down|status|goto VERSION")
+
+// Migrate runs migrate subcommand with args and writes its result to out
+func Migrate(ctx context.Context, cfg *config.Config, out io.Writer, args ...string) error {
+	if len(args) == 0 {
+		return ErrMigrateUsage
+	}
+	if cfg.Storage != "postgres" {
+		return fmt.Errorf("app.Migrate: storage '%s' has no migrations", cfg.Storage)
+	}
+
+	db, err := pg.New(cfg.Conn, pg.Options{})
+	if err != nil {
+		return err
+	}
+	defer db.Disconnect()
+
+	migrator, err := db.Migrator(migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	var done []pg.Migration
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		done, err = migrator.Up(ctx)
+	case args[0] == "down" && len(args) == 1:
+		done, err = migrator.Down(ctx)
+	case args[0] == "goto" && len(args) == 2:
+		version, parseErr := strconv.ParseUint(args[1], 10, 64)
+		if parseErr != nil {
+			return fmt.Errorf("%w: %s", ErrMigrateUsage, parseErr)
+		}
+		done, err = migrator.Goto(ctx, version)
+	case args[0] == "status" && len(args) == 1:
+		return printStatus(ctx, migrator, out)
+	default:
+		return ErrMigrateUsage
+	}
+
+	for _, m := range done {
+		fmt.Fprintf(out, "%s %d_%s\n", args[0], m.Version, m.Name)
+	}
+	if err == nil && len(done) == 0 {
+		fmt.Fprintln(out, "no change")
+	}
+	return err
+}
+
+func printStatus(ctx context.Context, migrator *pg.Migrator, out io.Writer) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range statuses {
+		applied := "pending"
+		if s.Applied {
+			applied = s.AppliedAt.Format(time.DateTime)
+		}
+		fmt.Fprintf(out, "%d_%s\t%s\n", s.Version, s.Name, applied)
+	}
+	return nil
+}
+
+func (a *Application) migrateOnStart(ctx context.Context) error {
+	db, ok := a.db.(*pg.PgStorage)
+	if !a.cfg.MigrateOnStart || !ok {
+		return nil
+	}
+
+	migrator, err := db.Migrator(migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	done, err := migrator.Up(ctx)
+	for _, m := range done {
+		a.log.Info("applied migration", "version", m.Version, "name", m.Name)
+	}
+	return err
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/config"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage/pg"
+)
+
+var ErrRoleUsage = errors.New("usage: role EMAIL user|moderator|admin")
+
+// SetRole runs role subcommand, it grants the first admin, who can grant roles with the admin api then
+func SetRole(ctx context.Context, cfg *config.Config, out io.Writer, args ...string) error {
+	if len(args) != 2 || !models.Role(args[1]).Valid() {
+		return ErrRoleUsage
+	}
+	if cfg.Storage != "postgres" {
+		return fmt.Errorf("app.SetRole: storage '%s' is not persistent", cfg.Storage)
+	}
+
+	db, err := pg.New(cfg.Conn, pg.Options{})
+	if err != nil {
+		return err
+	}
+	defer db.Disconnect()
+
+	user, eroErr := db.UserByEmail(ctx, strings.ToLower(args[0]))
+	if eroErr != nil {
+		return eroErr
+	}
+	if eroErr = db.SetRole(ctx, user.Id, models.Role(args[1])); eroErr != nil {
+		return eroErr
+	}
+
+	fmt.Fprintf(out, "%s is %s\n", user.Email, args[1])
+	return nil
+}
@@ -17,13 +17,24 @@ var (
 )
 
 type Config struct {
-	Conn        string        `yaml:"conn"`
-	WatchFreq   time.Duration `yaml:"watch_freq"`
-	ServiceName string        `yaml:"service_name"`
+	// Storage is either "postgres" or "memory"
+	Storage string     `yaml:"storage" env-default:"postgres"`
+	Conn    string     `yaml:"conn"`
+	Pool    PoolConfig `yaml:"pool"`
+	// MigrateOnStart applies all embedded migrations before the service starts
+	MigrateOnStart bool          `yaml:"migrate_on_start"`
+	WatchFreq      time.Duration `yaml:"watch_freq"`
+	ServiceName    string        `yaml:"service_name"`
 
-	Https        TransportConfig `yaml:"https"`
-	AccessToken  TokenConfig     `yaml:"access_token" dynamic:"true"`
-	RefreshToken TokenConfig     `yaml:"refresh_token" dynamic:"true"`
+	Https          TransportConfig      `yaml:"https"`
+	AccessToken    TokenConfig          `yaml:"access_token" dynamic:"true"`
+	RefreshToken   TokenConfig          `yaml:"refresh_token" dynamic:"true"`
+	SignIn         SignInConfig         `yaml:"sign_in" dynamic:"true"`
+	Password       PasswordConfig       `yaml:"password" dynamic:"true"`
+	PasswordPolicy PasswordPolicyConfig `yaml:"password_policy" dynamic:"true"`
+	Email          EmailConfig          `yaml:"email"`
+	TwoFactor      TwoFactorConfig      `yaml:"two_factor"`
+	Oidc           OidcConfig           `yaml:"oidc"`
 
 	Logger LoggerConfig `yaml:"logger"`
 
@@ -32,18 +43,119 @@ type Config struct {
 	t    *time.Ticker
 }
 
+type PoolConfig struct {
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	QueryTimeout    time.Duration `yaml:"query_timeout"`
+}
+
 type TransportConfig struct {
 	Port uint16 `yaml:"port"`
 	Cert string `yaml:"cert"`
 	Key  string `yaml:"key"`
+	// TrustedProxies are CIDRs of reverse proxies whose X-Forwarded-For is trusted
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
 type TokenConfig struct {
-	Secret   string        `yaml:"secret" dynamic:"true"`
-	TTL      time.Duration `yaml:"ttl" dynamic:"true"`
-	Issuer   string        `yaml:"issuer"`
-	Audience string        `yaml:"audience"`
-	Subject  string        `yaml:"subject"`
+	Secret         string        `yaml:"secret" dynamic:"true"`
+	PreviousSecret string        `yaml:"previous_secret" dynamic:"true"`
+	TTL            time.Duration `yaml:"ttl" dynamic:"true"`
+	Issuer         string        `yaml:"issuer" dynamic:"true"`
+	Audience       string        `yaml:"audience" dynamic:"true"`
+	Leeway         time.Duration `yaml:"leeway" dynamic:"true"`
+}
+
+// SignInConfig throttles failed sign ins of every email and from every IP separately
+type SignInConfig struct {
+	Email ThrottleConfig `yaml:"email" dynamic:"true"`
+	Ip    ThrottleConfig `yaml:"ip" dynamic:"true"`
+}
+
+type ThrottleConfig struct {
+	FreeAttempts    int           `yaml:"free_attempts" dynamic:"true"`
+	BaseDelay       time.Duration `yaml:"base_delay" dynamic:"true"`
+	MaxDelay        time.Duration `yaml:"max_delay" dynamic:"true"`
+	LockoutAttempts int           `yaml:"lockout_attempts" dynamic:"true"`
+	Lockout         time.Duration `yaml:"lockout" dynamic:"true"`
+	Window          time.Duration `yaml:"window" dynamic:"true"`
+}
+
+// PasswordConfig is for new hashes, passwords with other ones are rehashed on sign in
+type PasswordConfig struct {
+	// Algorithm is either "argon2id" or "bcrypt"
+	Algorithm  string       `yaml:"algorithm" env-default:"argon2id" dynamic:"true"`
+	BcryptCost int          `yaml:"bcrypt_cost" env-default:"10" dynamic:"true"`
+	Argon2     Argon2Config `yaml:"argon2" dynamic:"true"`
+}
+
+type Argon2Config struct {
+	Time uint32 `yaml:"time" env-default:"3" dynamic:"true"`
+	// Memory is in KiB
+	Memory     uint32 `yaml:"memory" env-default:"65536" dynamic:"true"`
+	Threads    uint8  `yaml:"threads" env-default:"2" dynamic:"true"`
+	SaltLength uint32 `yaml:"salt_length" env-default:"16" dynamic:"true"`
+	KeyLength  uint32 `yaml:"key_length" env-default:"32" dynamic:"true"`
+}
+
+// PasswordPolicyConfig applies to new passwords on registration, password change and reset
+type PasswordPolicyConfig struct {
+	MinLength int `yaml:"min_length" env-default:"8" dynamic:"true"`
+	// MinEntropy is in bits of the strength estimate
+	MinEntropy     float64 `yaml:"min_entropy" env-default:"32" dynamic:"true"`
+	RejectPersonal bool    `yaml:"reject_personal" env-default:"true" dynamic:"true"`
+	// BreachedPath is a directory of SHA-1 ranges or a single file of hashes, relative to the config.
+	// Breached passwords are not checked if it is empty
+	BreachedPath     string `yaml:"breached_path" dynamic:"true"`
+	BreachedMinCount int    `yaml:"breached_min_count" env-default:"1" dynamic:"true"`
+}
+
+type EmailConfig struct {
+	RequireVerified bool          `yaml:"require_verified" dynamic:"true"`
+	VerificationTTL time.Duration `yaml:"verification_ttl" env-default:"24h" dynamic:"true"`
+	ResetTTL        time.Duration `yaml:"reset_ttl" env-default:"1h" dynamic:"true"`
+	LinkBase        string        `yaml:"link_base" dynamic:"true"`
+	Mailer          MailerConfig  `yaml:"mailer"`
+}
+
+type MailerConfig struct {
+	// Kind is either "smtp", "file" or "stdout"
+	Kind string     `yaml:"kind" env-default:"stdout"`
+	From string     `yaml:"from"`
+	Path string     `yaml:"path"`
+	Smtp SmtpConfig `yaml:"smtp"`
+}
+
+type SmtpConfig struct {
+	Host     string `yaml:"host"`
+	Port     uint16 `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type TwoFactorConfig struct {
+	// EncryptionKey seals TOTP secrets, it cannot be changed without losing them
+	EncryptionKey string        `yaml:"encryption_key"`
+	Issuer        string        `yaml:"issuer" dynamic:"true"`
+	ChallengeTTL  time.Duration `yaml:"challenge_ttl" env-default:"5m" dynamic:"true"`
+}
+
+type OidcConfig struct {
+	// StateTTL is how long a user may take to sign in at a provider
+	StateTTL  time.Duration        `yaml:"state_ttl" env-default:"10m"`
+	Providers []OidcProviderConfig `yaml:"providers"`
+}
+
+type OidcProviderConfig struct {
+	// Name is used in urls, e.g. /api/auth/oidc/{name}
+	Name         string   `yaml:"name"`
+	Issuer       string   `yaml:"issuer"`
+	ClientId     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectUrl  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes"`
 }
 
 type LoggerConfig struct {
@@ -0,0 +1,26 @@
+package adminhandler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/labstack/echo/v4"
+)
+
+type PostRemover interface {
+	RemovePost(ctx context.Context, moderatorId, postId uint64) ero.Error
+}
+
+// DeletePost deletes a post of any author
+func DeletePost(remover PostRemover) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		eroErr := remover.RemovePost(context.TODO(), c.Get("id").(uint64), c.Get("post_id").(uint64))
+		if eroErr != nil {
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.JSONBlob(http.StatusOK, []byte(`{}`))
+	}
+}
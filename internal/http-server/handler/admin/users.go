@@ -0,0 +1,113 @@
+package adminhandler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/http-server/handler"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/labstack/echo/v4"
+)
+
+type UsersProvider interface {
+	Users(ctx context.Context, opts users.UsersOptions) (*users.PagedUsers, ero.Error)
+}
+
+type Suspender interface {
+	Suspend(ctx context.Context, actorId, userId uint64) ero.Error
+}
+
+type Unsuspender interface {
+	Unsuspend(ctx context.Context, actorId, userId uint64) ero.Error
+}
+
+type RoleSetter interface {
+	SetRole(ctx context.Context, actorId, userId uint64, change users.RoleChange) ero.Error
+}
+
+// GetUsers lists users, the q query param searches them by email, name or surname
+func GetUsers(provider UsersProvider) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		fullTimestamp, err := strconv.ParseBool(c.QueryParam("full_timestamp"))
+		if err != nil {
+			fullTimestamp = false
+		}
+
+		paged, eroErr := provider.Users(context.TODO(), users.UsersOptions{
+			Query:    c.QueryParam("q"),
+			Page:     c.Get("page").(uint64),
+			PageSize: c.Get("page_size").(uint64),
+			FormatDate: func(t time.Time) string {
+				if fullTimestamp {
+					return t.Format(time.DateTime)
+				} else {
+					return t.Format(time.DateOnly)
+				}
+			},
+		})
+		switch {
+		case errors.Is(eroErr, users.ErrNoUsers):
+			c.JSONBlob(http.StatusNoContent, []byte(eroErr.Error()))
+			return eroErr
+		case eroErr != nil:
+			c.JSONBlob(http.StatusInternalServerError, []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.JSON(http.StatusOK, paged)
+	}
+}
+
+func PostSuspension(suspender Suspender) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		eroErr := suspender.Suspend(context.TODO(), c.Get("id").(uint64), c.Get("user_id").(uint64))
+		if eroErr != nil {
+			c.JSONBlob(httpCode(eroErr), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func DeleteSuspension(unsuspender Unsuspender) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		eroErr := unsuspender.Unsuspend(context.TODO(), c.Get("id").(uint64), c.Get("user_id").(uint64))
+		if eroErr != nil {
+			c.JSONBlob(httpCode(eroErr), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func PutRole(setter RoleSetter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var change users.RoleChange
+		if err := c.Bind(&change); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		eroErr := setter.SetRole(context.TODO(), c.Get("id").(uint64), c.Get("user_id").(uint64), change)
+		if eroErr != nil {
+			c.JSONBlob(httpCode(eroErr), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// httpCode is 403 for acting on a user with the same or a higher role, ero.ToHttpCode would make it 412
+func httpCode(eroErr ero.Error) int {
+	if errors.Is(eroErr, users.ErrInsufficientRole) {
+		return http.StatusForbidden
+	}
+	return ero.ToHttpCode(eroErr.Code())
+}
@@ -0,0 +1,97 @@
+package authhandler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/http-server/handler"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/labstack/echo/v4"
+)
+
+type VerificationSender interface {
+	SendVerification(ctx context.Context, req users.EmailRequest) ero.Error
+}
+
+type EmailVerifier interface {
+	VerifyEmail(ctx context.Context, confirm users.ActionConfirm) ero.Error
+}
+
+type PasswordResetRequester interface {
+	RequestPasswordReset(ctx context.Context, req users.EmailRequest) ero.Error
+}
+
+type PasswordResetter interface {
+	ResetPassword(ctx context.Context, reset users.PasswordReset) ero.Error
+}
+
+// PostEmailVerification responds the same whether the email is known or not
+func PostEmailVerification(sender VerificationSender) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req users.EmailRequest
+		if err := c.Bind(&req); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		if eroErr := sender.SendVerification(context.TODO(), req); eroErr != nil {
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.NoContent(http.StatusAccepted)
+	}
+}
+
+func PostEmailVerificationConfirm(verifier EmailVerifier) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var confirm users.ActionConfirm
+		if err := c.Bind(&confirm); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		if eroErr := verifier.VerifyEmail(context.TODO(), confirm); eroErr != nil {
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// PostPasswordReset responds the same whether the email is known or not
+func PostPasswordReset(requester PasswordResetRequester) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req users.EmailRequest
+		if err := c.Bind(&req); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		if eroErr := requester.RequestPasswordReset(context.TODO(), req); eroErr != nil {
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.NoContent(http.StatusAccepted)
+	}
+}
+
+func PostPasswordResetConfirm(resetter PasswordResetter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var reset users.PasswordReset
+		if err := c.Bind(&reset); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		if eroErr := resetter.ResetPassword(context.TODO(), reset); eroErr != nil {
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,27 @@
+package authhandler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/labstack/echo/v4"
+)
+
+type LogoutProvider interface {
+	Logout(ctx context.Context, userId uint64, sessionId, jti string, expiresAt time.Time) ero.Error
+}
+
+// PostLogout must be behind middleware.Authorized, the token it has verified is revoked
+func PostLogout(provider LogoutProvider) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		eroErr := provider.Logout(context.TODO(), c.Get("id").(uint64), c.Get("sid").(string), c.Get("jti").(string), c.Get("exp").(time.Time))
+		if eroErr != nil {
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,53 @@
+package authhandler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/http-server/handler"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/labstack/echo/v4"
+)
+
+type OidcAuthorizer interface {
+	OidcAuthorization(ctx context.Context, provider string) (*users.OidcAuthorization, ero.Error)
+}
+
+type OidcIdentityProvider interface {
+	SignInOidc(ctx context.Context, provider string, callback users.OidcCallback, device models.DeviceInfo) (*users.SignedIn, ero.Error)
+}
+
+// GetOidc responds with the url of the provider the user must be sent to and the binding the client must keep
+func GetOidc(authorizer OidcAuthorizer) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		authorization, eroErr := authorizer.OidcAuthorization(context.TODO(), c.Param("provider"))
+		if eroErr != nil {
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.JSON(http.StatusOK, authorization)
+	}
+}
+
+// PostOidcCallback responds as PostSignIn, the code and state are from the redirect of the provider,
+// the binding is the one GetOidc has responded with to the same client
+func PostOidcCallback(provider OidcIdentityProvider) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var callback users.OidcCallback
+		if err := c.Bind(&callback); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		signedIn, eroErr := provider.SignInOidc(context.TODO(), c.Param("provider"), callback, handler.Device(c))
+		if eroErr != nil {
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.JSON(http.StatusOK, signedIn)
+	}
+}
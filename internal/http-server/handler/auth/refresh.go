@@ -6,13 +6,14 @@ import (
 
 	"github.com/Onnywrite/tinkoff-prod/internal/http-server/handler"
 	"github.com/Onnywrite/tinkoff-prod/internal/lib/tokens"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 	"github.com/labstack/echo/v4"
 )
 
 type AccessTokenUpdater interface {
-	Refresh(ctx context.Context, refresh tokens.RefreshString) (*users.AuthorizedUser, ero.Error)
+	Refresh(ctx context.Context, refresh tokens.RefreshString, device models.DeviceInfo) (*users.AuthorizedUser, ero.Error)
 }
 
 func PostRefresh(updater AccessTokenUpdater) echo.HandlerFunc {
@@ -27,7 +28,7 @@ func PostRefresh(updater AccessTokenUpdater) echo.HandlerFunc {
 			return err
 		}
 
-		authUser, eroErr := updater.Refresh(context.TODO(), token.Refresh)
+		authUser, eroErr := updater.Refresh(context.TODO(), token.Refresh, handler.Device(c))
 		if eroErr != nil {
 			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
 			return eroErr
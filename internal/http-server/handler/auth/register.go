@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/Onnywrite/tinkoff-prod/internal/http-server/handler"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 
@@ -12,7 +13,7 @@ import (
 )
 
 type UserRegistrator interface {
-	Register(ctx context.Context, userData users.RegisterData) (*users.AuthorizedUser, ero.Error)
+	Register(ctx context.Context, userData users.RegisterData, device models.DeviceInfo) (*users.AuthorizedUser, ero.Error)
 }
 
 func PostRegister(registrator UserRegistrator) echo.HandlerFunc {
@@ -23,7 +24,7 @@ func PostRegister(registrator UserRegistrator) echo.HandlerFunc {
 			return err
 		}
 
-		authUser, eroErr := registrator.Register(context.TODO(), u)
+		authUser, eroErr := registrator.Register(context.TODO(), u, handler.Device(c))
 		if eroErr != nil {
 			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
 			return eroErr
@@ -5,13 +5,18 @@ import (
 	"net/http"
 
 	"github.com/Onnywrite/tinkoff-prod/internal/http-server/handler"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 	"github.com/labstack/echo/v4"
 )
 
 type IdentityProvider interface {
-	SignIn(ctx context.Context, creds users.Credentials) (*users.AuthorizedUser, ero.Error)
+	SignIn(ctx context.Context, creds users.Credentials, device models.DeviceInfo) (*users.SignedIn, ero.Error)
+}
+
+type TwoFactorIdentityProvider interface {
+	SignInTwoFactor(ctx context.Context, data users.TwoFactorSignIn, device models.DeviceInfo) (*users.AuthorizedUser, ero.Error)
 }
 
 func PostSignIn(provider IdentityProvider) echo.HandlerFunc {
@@ -22,14 +27,35 @@ func PostSignIn(provider IdentityProvider) echo.HandlerFunc {
 			return err
 		}
 
-		authUser, eroErr := provider.SignIn(context.TODO(), data)
+		signedIn, eroErr := provider.SignIn(context.TODO(), data, handler.Device(c))
 		if eroErr != nil {
+			handler.SetRetryAfter(c, eroErr)
 			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
 			return eroErr
 		}
 
-		c.JSON(http.StatusOK, authUser)
+		c.JSON(http.StatusOK, signedIn)
 
 		return nil
 	}
 }
+
+// PostSignInTwoFactor exchanges the challenge of PostSignIn and a code for tokens
+func PostSignInTwoFactor(provider TwoFactorIdentityProvider) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var data users.TwoFactorSignIn
+		if err := c.Bind(&data); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		authUser, eroErr := provider.SignInTwoFactor(context.TODO(), data, handler.Device(c))
+		if eroErr != nil {
+			handler.SetRetryAfter(c, eroErr)
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.JSON(http.StatusOK, authUser)
+	}
+}
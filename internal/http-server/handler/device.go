@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"net"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+// Device describes the client of the request for sessions.
+// An ip that cannot be parsed is dropped, so it is never stored
+func Device(c echo.Context) models.DeviceInfo {
+	device := models.DeviceInfo{UserAgent: c.Request().UserAgent()}
+	if ip := net.ParseIP(c.RealIP()); ip != nil {
+		device.Ip = ip.String()
+	}
+	return device
+}
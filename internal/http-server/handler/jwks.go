@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/tokens"
+
+	"github.com/labstack/echo/v4"
+)
+
+type JwksProvider interface {
+	JWKS() tokens.JWKS
+}
+
+// GetJwks publishes public keys verifying access tokens, see tokens.KeySet
+func GetJwks(provider JwksProvider) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set("Cache-Control", "public, max-age=300")
+		return c.JSON(http.StatusOK, provider.JWKS())
+	}
+}
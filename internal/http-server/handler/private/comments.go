@@ -0,0 +1,134 @@
+package privatehandler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/http-server/handler"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/comments"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/labstack/echo/v4"
+)
+
+type CommentCreator interface {
+	Create(ctx context.Context, comment comments.NewComment) (uint64, ero.Error)
+}
+
+type CommentsProvider interface {
+	Comments(ctx context.Context, opts comments.CommentsOptions) (*comments.Page, ero.Error)
+}
+
+type CommentEditor interface {
+	Edit(ctx context.Context, opts comments.EditOptions) ero.Error
+}
+
+type CommentDeleter interface {
+	Delete(ctx context.Context, postId, commentId, userId uint64) ero.Error
+}
+
+func PostComment(creator CommentCreator) echo.HandlerFunc {
+	type comment struct {
+		ParentId *uint64 `json:"parent_id"`
+		Content  *string `json:"content"`
+	}
+
+	return func(c echo.Context) error {
+		var cm comment
+		if err := c.Bind(&cm); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		id, eroErr := creator.Create(context.TODO(), comments.NewComment{
+			PostId:   c.Get("post_id").(uint64),
+			AuthorId: c.Get("id").(uint64),
+			ParentId: cm.ParentId,
+			Content:  cm.Content,
+		})
+		if eroErr != nil {
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.JSONBlob(http.StatusCreated, []byte(`{"id":`+strconv.FormatUint(id, 10)+`}`))
+	}
+}
+
+func GetComments(provider CommentsProvider) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		fullTimestamp, err := strconv.ParseBool(c.QueryParam("full_timestamp"))
+		if err != nil {
+			fullTimestamp = false
+		}
+		tree, err := strconv.ParseBool(c.QueryParam("tree"))
+		if err != nil {
+			tree = false
+		}
+
+		page, eroErr := provider.Comments(context.TODO(), comments.CommentsOptions{
+			PostId:   c.Get("post_id").(uint64),
+			Page:     c.Get("page").(uint64),
+			PageSize: c.Get("page_size").(uint64),
+			Tree:     tree,
+			FormatDate: func(t time.Time) string {
+				if fullTimestamp {
+					return t.Format(time.DateTime)
+				} else {
+					return t.Format(time.DateOnly)
+				}
+			},
+		})
+		switch {
+		case errors.Is(eroErr, comments.ErrNoComments):
+			c.JSONBlob(http.StatusNoContent, []byte(eroErr.Error()))
+			return eroErr
+		case eroErr != nil:
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.JSON(http.StatusOK, page)
+	}
+}
+
+func PatchComment(editor CommentEditor) echo.HandlerFunc {
+	type comment struct {
+		Content *string `json:"content"`
+	}
+
+	return func(c echo.Context) error {
+		var cm comment
+		if err := c.Bind(&cm); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		eroErr := editor.Edit(context.TODO(), comments.EditOptions{
+			PostId:    c.Get("post_id").(uint64),
+			CommentId: c.Get("comment_id").(uint64),
+			UserId:    c.Get("id").(uint64),
+			Content:   cm.Content,
+		})
+		if eroErr != nil {
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.JSONBlob(http.StatusOK, []byte(`{}`))
+	}
+}
+
+func DeleteComment(deleter CommentDeleter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		eroErr := deleter.Delete(context.TODO(), c.Get("post_id").(uint64), c.Get("comment_id").(uint64), c.Get("id").(uint64))
+		if eroErr != nil {
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.JSONBlob(http.StatusOK, []byte(`{}`))
+	}
+}
@@ -30,6 +30,7 @@ func GetFeed(provider AllFeedProvider) echo.HandlerFunc {
 		posts, eroErr := provider.AllFeed(context.Background(), feed.AllFeedOptions{
 			Page:       c.Get("page").(uint64),
 			PageSize:   c.Get("page_size").(uint64),
+			Cursor:     c.Get("cursor").(string),
 			UserId:     c.Get("id").(uint64),
 			LikesCount: likesCount,
 			FormatDate: func(t time.Time) string {
@@ -44,6 +45,9 @@ func GetFeed(provider AllFeedProvider) echo.HandlerFunc {
 		case errors.Is(eroErr, feed.ErrNoPosts):
 			c.JSONBlob(http.StatusNoContent, []byte(eroErr.Error()))
 			return eroErr
+		case errors.Is(eroErr, feed.ErrInvalidCursor):
+			c.JSONBlob(http.StatusBadRequest, []byte(eroErr.Error()))
+			return eroErr
 		case eroErr != nil:
 			c.JSONBlob(http.StatusInternalServerError, []byte(eroErr.Error()))
 			return eroErr
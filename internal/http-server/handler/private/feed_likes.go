@@ -64,6 +64,7 @@ func GetLikes(provider LikesProvider) echo.HandlerFunc {
 		likesPage, eroErr := provider.Likes(context.TODO(), likes.LikesOptions{
 			Page:     c.Get("page").(uint64),
 			PageSize: c.Get("page_size").(uint64),
+			Cursor:   c.Get("cursor").(string),
 			PostId:   c.Get("post_id").(uint64),
 			FormatDate: func(t time.Time) string {
 				if fullTimestamp {
@@ -78,6 +79,9 @@ func GetLikes(provider LikesProvider) echo.HandlerFunc {
 		case errors.Is(eroErr, likes.ErrNoLikes):
 			c.JSONBlob(http.StatusNoContent, []byte(eroErr.Error()))
 			return eroErr
+		case errors.Is(eroErr, likes.ErrInvalidCursor):
+			c.JSONBlob(http.StatusBadRequest, []byte(eroErr.Error()))
+			return eroErr
 		case eroErr != nil:
 			c.JSONBlob(http.StatusInternalServerError, []byte(eroErr.Error()))
 			return eroErr
@@ -4,18 +4,21 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/Onnywrite/tinkoff-prod/internal/http-server/handler"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 	"github.com/labstack/echo/v4"
 )
 
 type UserProvider interface {
-	UserById(ctx context.Context, id uint64, hasFullAccess bool) (users.PrivateOrPublicProfile, ero.Error)
+	UserById(ctx context.Context, id, viewerId uint64) (users.PrivateOrPublicProfile, ero.Error)
 }
 
 func GetMe(provider UserProvider) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		privateOrPublic, err := provider.UserById(context.TODO(), c.Get("id").(uint64), true)
+		id := c.Get("id").(uint64)
+		privateOrPublic, err := provider.UserById(context.TODO(), id, id)
 		if err != nil {
 			return c.JSONBlob(ero.ToHttpCode(err.Code()), []byte(err.Error()))
 		}
@@ -30,3 +33,47 @@ func GetMe(provider UserProvider) echo.HandlerFunc {
 		)
 	}
 }
+
+type UserUpdater interface {
+	Update(ctx context.Context, id uint64, data users.UpdateData) (*users.Profile, ero.Error)
+}
+
+func PatchMe(updater UserUpdater) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var data users.UpdateData
+		if err := c.Bind(&data); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		profile, eroErr := updater.Update(context.TODO(), c.Get("id").(uint64), data)
+		if eroErr != nil {
+			return c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+		}
+
+		return c.JSON(http.StatusOK, profile)
+	}
+}
+
+type PasswordChanger interface {
+	ChangePassword(ctx context.Context, id uint64, change users.PasswordChange, device models.DeviceInfo) (*users.AuthorizedUser, ero.Error)
+}
+
+// PostMePassword changes the password and responds with new tokens,
+// refresh tokens of all other devices become invalid
+func PostMePassword(changer PasswordChanger) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var change users.PasswordChange
+		if err := c.Bind(&change); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		authUser, eroErr := changer.ChangePassword(context.TODO(), c.Get("id").(uint64), change, handler.Device(c))
+		if eroErr != nil {
+			return c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+		}
+
+		return c.JSON(http.StatusOK, authUser)
+	}
+}
@@ -0,0 +1,94 @@
+package privatehandler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/http-server/handler"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/labstack/echo/v4"
+)
+
+type TotpEnroller interface {
+	EnrollTotp(ctx context.Context, userId uint64) (*users.TotpEnrollment, ero.Error)
+}
+
+type TotpConfirmer interface {
+	ConfirmTotp(ctx context.Context, userId uint64, code users.TwoFactorCode) (*users.RecoveryCodes, ero.Error)
+}
+
+type TotpDisabler interface {
+	DisableTotp(ctx context.Context, userId uint64, code users.TwoFactorCode) ero.Error
+}
+
+type RecoveryCodesGenerator interface {
+	RegenerateRecoveryCodes(ctx context.Context, userId uint64, code users.TwoFactorCode) (*users.RecoveryCodes, ero.Error)
+}
+
+// PostMeTotp responds with the secret to be added to an authenticator app,
+// 2FA is not enabled until PostMeTotpConfirm
+func PostMeTotp(enroller TotpEnroller) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		enrollment, eroErr := enroller.EnrollTotp(context.TODO(), c.Get("id").(uint64))
+		if eroErr != nil {
+			return c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+		}
+
+		return c.JSON(http.StatusCreated, enrollment)
+	}
+}
+
+// PostMeTotpConfirm responds with recovery codes, they are never shown again
+func PostMeTotpConfirm(confirmer TotpConfirmer) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var code users.TwoFactorCode
+		if err := c.Bind(&code); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		codes, eroErr := confirmer.ConfirmTotp(context.TODO(), c.Get("id").(uint64), code)
+		if eroErr != nil {
+			return c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+		}
+
+		return c.JSON(http.StatusOK, codes)
+	}
+}
+
+func PostMeTotpDisable(disabler TotpDisabler) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var code users.TwoFactorCode
+		if err := c.Bind(&code); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		if eroErr := disabler.DisableTotp(context.TODO(), c.Get("id").(uint64), code); eroErr != nil {
+			handler.SetRetryAfter(c, eroErr)
+			return c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// PostMeRecoveryCodes replaces all recovery codes, the new ones are never shown again
+func PostMeRecoveryCodes(generator RecoveryCodesGenerator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var code users.TwoFactorCode
+		if err := c.Bind(&code); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		codes, eroErr := generator.RegenerateRecoveryCodes(context.TODO(), c.Get("id").(uint64), code)
+		if eroErr != nil {
+			handler.SetRetryAfter(c, eroErr)
+			return c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+		}
+
+		return c.JSON(http.StatusOK, codes)
+	}
+}
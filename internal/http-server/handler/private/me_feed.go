@@ -6,7 +6,6 @@ import (
 	"strconv"
 
 	"github.com/Onnywrite/tinkoff-prod/internal/http-server/handler"
-	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/Onnywrite/tinkoff-prod/internal/services/feed"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 	"github.com/labstack/echo/v4"
@@ -18,8 +17,8 @@ type PostCreator interface {
 
 func PostMeFeed(creator PostCreator) echo.HandlerFunc {
 	type post struct {
-		Content    *string  `json:"content"`
-		ImagesUrls []string `json:"images_urls"`
+		Content    *string   `json:"content"`
+		ImagesUrls *[]string `json:"images_urls"`
 	}
 
 	return func(c echo.Context) error {
@@ -32,7 +31,7 @@ func PostMeFeed(creator PostCreator) echo.HandlerFunc {
 		postId, eroErr := creator.CreatePost(context.TODO(), feed.NewPost{
 			AuthorId:   c.Get("id").(uint64),
 			Content:    p.Content,
-			ImagesUrls: models.StringSlice(p.ImagesUrls),
+			ImagesUrls: p.ImagesUrls,
 		})
 		if eroErr != nil {
 			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
@@ -0,0 +1,49 @@
+package privatehandler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/services/follows"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/labstack/echo/v4"
+)
+
+type FollowRequestsProvider interface {
+	Requests(ctx context.Context, opts follows.FollowsOptions) (*follows.PagedFollows, ero.Error)
+}
+
+type FollowRequestApprover interface {
+	Approve(ctx context.Context, userId, followerId uint64) ero.Error
+}
+
+type FollowRequestRejecter interface {
+	Reject(ctx context.Context, userId, followerId uint64) ero.Error
+}
+
+// GetMeFollowRequests lists pending requests to follow the current user
+func GetMeFollowRequests(provider FollowRequestsProvider) echo.HandlerFunc {
+	return getFollows(provider.Requests, "id")
+}
+
+func PostApproveFollowRequest(approver FollowRequestApprover) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		eroErr := approver.Approve(context.TODO(), c.Get("id").(uint64), c.Get("user_id").(uint64))
+		if eroErr != nil {
+			return c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func DeleteFollowRequest(rejecter FollowRequestRejecter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		eroErr := rejecter.Reject(context.TODO(), c.Get("id").(uint64), c.Get("user_id").(uint64))
+		if eroErr != nil {
+			return c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,62 @@
+package privatehandler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/labstack/echo/v4"
+)
+
+type SessionsProvider interface {
+	Sessions(ctx context.Context, opts users.SessionsOptions) ([]users.Session, ero.Error)
+}
+
+type SessionRevoker interface {
+	RevokeSession(ctx context.Context, userId, sessionId uint64) ero.Error
+}
+
+func GetMeSessions(provider SessionsProvider) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		fullTimestamp, err := strconv.ParseBool(c.QueryParam("full_timestamp"))
+		if err != nil {
+			fullTimestamp = false
+		}
+
+		sessions, eroErr := provider.Sessions(context.TODO(), users.SessionsOptions{
+			UserId: c.Get("id").(uint64),
+			FormatDate: func(t time.Time) string {
+				if fullTimestamp {
+					return t.Format(time.DateTime)
+				} else {
+					return t.Format(time.DateOnly)
+				}
+			},
+		})
+		switch {
+		case errors.Is(eroErr, users.ErrNoSessions):
+			c.JSONBlob(http.StatusNoContent, []byte(eroErr.Error()))
+			return eroErr
+		case eroErr != nil:
+			c.JSONBlob(http.StatusInternalServerError, []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.JSON(http.StatusOK, sessions)
+	}
+}
+
+func DeleteMeSession(revoker SessionRevoker) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		eroErr := revoker.RevokeSession(context.TODO(), c.Get("id").(uint64), c.Get("session_id").(uint64))
+		if eroErr != nil {
+			return c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,85 @@
+package privatehandler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/http-server/handler"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/labstack/echo/v4"
+)
+
+type PersonalTokenCreator interface {
+	CreatePersonalToken(ctx context.Context, userId uint64, data users.PersonalTokenData) (*users.CreatedPersonalToken, ero.Error)
+}
+
+type PersonalTokensProvider interface {
+	PersonalTokens(ctx context.Context, opts users.PersonalTokensOptions) ([]users.PersonalToken, ero.Error)
+}
+
+type PersonalTokenRevoker interface {
+	RevokePersonalToken(ctx context.Context, userId, tokenId uint64) ero.Error
+}
+
+// PostMeToken responds with the token itself, it is never shown again
+func PostMeToken(creator PersonalTokenCreator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var data users.PersonalTokenData
+		if err := c.Bind(&data); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		created, eroErr := creator.CreatePersonalToken(context.TODO(), c.Get("id").(uint64), data)
+		if eroErr != nil {
+			return c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+		}
+
+		return c.JSON(http.StatusCreated, created)
+	}
+}
+
+func GetMeTokens(provider PersonalTokensProvider) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		fullTimestamp, err := strconv.ParseBool(c.QueryParam("full_timestamp"))
+		if err != nil {
+			fullTimestamp = false
+		}
+
+		personalTokens, eroErr := provider.PersonalTokens(context.TODO(), users.PersonalTokensOptions{
+			UserId: c.Get("id").(uint64),
+			FormatDate: func(t time.Time) string {
+				if fullTimestamp {
+					return t.Format(time.DateTime)
+				} else {
+					return t.Format(time.DateOnly)
+				}
+			},
+		})
+		switch {
+		case errors.Is(eroErr, users.ErrNoTokens):
+			c.JSONBlob(http.StatusNoContent, []byte(eroErr.Error()))
+			return eroErr
+		case eroErr != nil:
+			c.JSONBlob(http.StatusInternalServerError, []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.JSON(http.StatusOK, personalTokens)
+	}
+}
+
+func DeleteMeToken(revoker PersonalTokenRevoker) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		eroErr := revoker.RevokePersonalToken(context.TODO(), c.Get("id").(uint64), c.Get("token_id").(uint64))
+		if eroErr != nil {
+			return c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
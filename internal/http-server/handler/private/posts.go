@@ -0,0 +1,99 @@
+package privatehandler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/http-server/handler"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/feed"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/labstack/echo/v4"
+)
+
+type PostEditor interface {
+	EditPost(ctx context.Context, postId uint64, post feed.NewPost) ero.Error
+}
+
+type PostDeleter interface {
+	DeletePost(ctx context.Context, userId, postId uint64) ero.Error
+}
+
+type PostRevisionsProvider interface {
+	PostRevisions(ctx context.Context, opts feed.RevisionsOptions) ([]feed.Revision, ero.Error)
+}
+
+func PatchPost(editor PostEditor) echo.HandlerFunc {
+	type post struct {
+		Content    *string   `json:"content"`
+		ImagesUrls *[]string `json:"images_urls"`
+	}
+
+	return func(c echo.Context) error {
+		var p post
+		if err := c.Bind(&p); err != nil {
+			c.JSONBlob(http.StatusBadRequest, handler.ErrorMessage("could not bind the body").Blob())
+			return err
+		}
+
+		eroErr := editor.EditPost(context.TODO(), c.Get("post_id").(uint64), feed.NewPost{
+			AuthorId:   c.Get("id").(uint64),
+			Content:    p.Content,
+			ImagesUrls: p.ImagesUrls,
+		})
+		if eroErr != nil {
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.JSONBlob(http.StatusOK, []byte(`{}`))
+	}
+}
+
+func DeletePost(deleter PostDeleter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		eroErr := deleter.DeletePost(context.TODO(), c.Get("id").(uint64), c.Get("post_id").(uint64))
+		if eroErr != nil {
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.JSONBlob(http.StatusOK, []byte(`{}`))
+	}
+}
+
+func GetPostRevisions(provider PostRevisionsProvider) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		fullTimestamp, err := strconv.ParseBool(c.QueryParam("full_timestamp"))
+		if err != nil {
+			fullTimestamp = false
+		}
+
+		revisions, eroErr := provider.PostRevisions(context.TODO(), feed.RevisionsOptions{
+			PostId: c.Get("post_id").(uint64),
+			UserId: c.Get("id").(uint64),
+			FormatDate: func(t time.Time) string {
+				if fullTimestamp {
+					return t.Format(time.DateTime)
+				} else {
+					return t.Format(time.DateOnly)
+				}
+			},
+		})
+		switch {
+		case errors.Is(eroErr, feed.ErrNoRevisions):
+			c.JSONBlob(http.StatusNoContent, []byte(eroErr.Error()))
+			return eroErr
+		case errors.Is(eroErr, feed.ErrPrivateProfile):
+			c.JSONBlob(http.StatusForbidden, []byte(eroErr.Error()))
+			return eroErr
+		case eroErr != nil:
+			c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.JSON(http.StatusOK, revisions)
+	}
+}
@@ -11,9 +11,7 @@ import (
 
 func GetProfile(provider UserProvider) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		userId := c.Get("user_id").(uint64)
-		id := c.Get("id").(uint64)
-		privateOrPublic, err := provider.UserById(context.TODO(), userId, userId == id)
+		privateOrPublic, err := provider.UserById(context.TODO(), c.Get("user_id").(uint64), c.Get("id").(uint64))
 		if err != nil {
 			return c.JSONBlob(ero.ToHttpCode(err.Code()), []byte(err.Error()))
 		}
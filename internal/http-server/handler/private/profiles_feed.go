@@ -30,6 +30,7 @@ func GetProfileFeed(provider AuthorFeedProvider) echo.HandlerFunc {
 		posts, eroErr := provider.AuthorFeed(context.Background(), feed.AuthorFeedOptions{
 			Page:       c.Get("page").(uint64),
 			PageSize:   c.Get("page_size").(uint64),
+			Cursor:     c.Get("cursor").(string),
 			AuthorId:   c.Get("user_id").(uint64),
 			UserId:     c.Get("id").(uint64),
 			LikesCount: likesCount,
@@ -45,6 +46,15 @@ func GetProfileFeed(provider AuthorFeedProvider) echo.HandlerFunc {
 		case errors.Is(eroErr, feed.ErrNoPosts):
 			c.JSONBlob(http.StatusNoContent, []byte(eroErr.Error()))
 			return eroErr
+		case errors.Is(eroErr, feed.ErrInvalidCursor):
+			c.JSONBlob(http.StatusBadRequest, []byte(eroErr.Error()))
+			return eroErr
+		case errors.Is(eroErr, feed.ErrPrivateProfile):
+			c.JSONBlob(http.StatusForbidden, []byte(eroErr.Error()))
+			return eroErr
+		case errors.Is(eroErr, feed.ErrAuthorNotFound):
+			c.JSONBlob(http.StatusNotFound, []byte(eroErr.Error()))
+			return eroErr
 		case eroErr != nil:
 			c.JSONBlob(http.StatusInternalServerError, []byte(eroErr.Error()))
 			return eroErr
@@ -0,0 +1,100 @@
+package privatehandler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/services/follows"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/labstack/echo/v4"
+)
+
+type Follower interface {
+	Follow(ctx context.Context, followerId, followeeId uint64) (*follows.FollowStatus, ero.Error)
+}
+
+type Unfollower interface {
+	Unfollow(ctx context.Context, followerId, followeeId uint64) ero.Error
+}
+
+type FollowsProvider interface {
+	Followers(ctx context.Context, opts follows.FollowsOptions) (*follows.PagedFollows, ero.Error)
+	Followings(ctx context.Context, opts follows.FollowsOptions) (*follows.PagedFollows, ero.Error)
+}
+
+func PostFollow(follower Follower) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		status, eroErr := follower.Follow(context.TODO(), c.Get("id").(uint64), c.Get("user_id").(uint64))
+		if eroErr != nil {
+			return c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+		}
+
+		return c.JSON(http.StatusCreated, status)
+	}
+}
+
+func DeleteFollow(unfollower Unfollower) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		eroErr := unfollower.Unfollow(context.TODO(), c.Get("id").(uint64), c.Get("user_id").(uint64))
+		if eroErr != nil {
+			return c.JSONBlob(ero.ToHttpCode(eroErr.Code()), []byte(eroErr.Error()))
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func GetFollowers(provider FollowsProvider) echo.HandlerFunc {
+	return getFollows(provider.Followers, "user_id")
+}
+
+func GetFollowings(provider FollowsProvider) echo.HandlerFunc {
+	return getFollows(provider.Followings, "user_id")
+}
+
+// getFollows lists follows of the user whose id is stored in the context under idKey
+func getFollows(list func(context.Context, follows.FollowsOptions) (*follows.PagedFollows, ero.Error), idKey string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		fullTimestamp, err := strconv.ParseBool(c.QueryParam("full_timestamp"))
+		if err != nil {
+			fullTimestamp = false
+		}
+
+		followsPage, eroErr := list(context.TODO(), follows.FollowsOptions{
+			Page:     c.Get("page").(uint64),
+			PageSize: c.Get("page_size").(uint64),
+			Cursor:   c.Get("cursor").(string),
+			UserId:   c.Get(idKey).(uint64),
+			ViewerId: c.Get("id").(uint64),
+			FormatDate: func(t time.Time) string {
+				if fullTimestamp {
+					return t.Format(time.DateTime)
+				} else {
+					return t.Format(time.DateOnly)
+				}
+			},
+		})
+		switch {
+		case errors.Is(eroErr, follows.ErrNoFollows):
+			c.JSONBlob(http.StatusNoContent, []byte(eroErr.Error()))
+			return eroErr
+		case errors.Is(eroErr, follows.ErrPrivateProfile):
+			c.JSONBlob(http.StatusForbidden, []byte(eroErr.Error()))
+			return eroErr
+		case errors.Is(eroErr, follows.ErrUserNotFound):
+			c.JSONBlob(http.StatusNotFound, []byte(eroErr.Error()))
+			return eroErr
+		case errors.Is(eroErr, follows.ErrInvalidCursor):
+			c.JSONBlob(http.StatusBadRequest, []byte(eroErr.Error()))
+			return eroErr
+		case eroErr != nil:
+			c.JSONBlob(http.StatusInternalServerError, []byte(eroErr.Error()))
+			return eroErr
+		}
+
+		return c.JSON(http.StatusOK, followsPage)
+	}
+}
@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
+	"github.com/labstack/echo/v4"
+)
+
+// SetRetryAfter tells a throttled client when to try again
+func SetRetryAfter(c echo.Context, err error) {
+	var tooMany *users.TooManyAttemptsError
+	if errors.As(err, &tooMany) {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(tooMany.RetryAfter.Seconds()))))
+	}
+}
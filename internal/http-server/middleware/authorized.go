@@ -1,15 +1,25 @@
 package middleware
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/Onnywrite/tinkoff-prod/internal/lib/tokens"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 	"github.com/labstack/echo/v4"
 )
 
-func Authorized() echo.MiddlewareFunc {
+type PersonalTokenAuthorizer interface {
+	AuthorizePersonalToken(ctx context.Context, token string, scope tokens.Scope) (uint64, ero.Error)
+}
+
+// Authorized verifies the access token and rejects denied ones, see tokens.Denylist.
+// Access tokens of sessions have every scope, personal access tokens are let in only with the scope.
+// Only access tokens carry the role, see Role
+func Authorized(denylist tokens.Denylist, personal PersonalTokenAuthorizer, scope tokens.Scope) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			auth := c.Request().Header["Authorization"]
@@ -18,9 +28,20 @@ func Authorized() echo.MiddlewareFunc {
 			}
 
 			bearerToken := strings.Split(auth[0], " ")
-			if bearerToken[0] != "Bearer" {
+			if len(bearerToken) != 2 || bearerToken[0] != "Bearer" {
 				return c.JSONBlob(http.StatusUnauthorized, ErrorMessage("invalid authorization header format, required 'Bearer <token>''").Blob())
 			}
+
+			if tokens.IsPersonal(bearerToken[1]) {
+				userId, eroErr := personal.AuthorizePersonalToken(c.Request().Context(), bearerToken[1], scope)
+				if eroErr != nil {
+					return c.JSONBlob(personalTokenHttpCode(eroErr), []byte(eroErr.Error()))
+				}
+
+				c.Set("id", userId)
+				return next(c)
+			}
+
 			access := tokens.AccessString(bearerToken[1])
 
 			token, err := access.ParseVerify()
@@ -28,14 +49,42 @@ func Authorized() echo.MiddlewareFunc {
 			case errors.Is(err, tokens.ErrExpired):
 				c.JSONBlob(http.StatusUnauthorized, ErrorMessage("access token has expired").Blob())
 				return err
+			case errors.Is(err, tokens.ErrWrongType):
+				c.JSONBlob(http.StatusUnauthorized, ErrorMessage("not an access token").Blob())
+				return err
 			case err != nil:
 				c.JSONBlob(http.StatusUnauthorized, ErrorMessage("invalid token").Blob())
 				return err
 			}
+
+			denied, err := denylist.IsDenied(c.Request().Context(), token.Jti)
+			if err == nil && !denied {
+				denied, err = denylist.IsUserDenied(c.Request().Context(), token.Id, token.IssuedAt)
+			}
+			switch {
+			case err != nil:
+				c.JSONBlob(http.StatusInternalServerError, ErrorMessage("could not check token").Blob())
+				return err
+			case denied:
+				return c.JSONBlob(http.StatusUnauthorized, ErrorMessage("access token has been revoked").Blob())
+			}
+
 			c.Set("email", token.Email)
 			c.Set("id", token.Id)
+			c.Set("jti", token.Jti)
+			c.Set("sid", token.SessionId)
+			c.Set("role", token.Role)
+			c.Set("exp", time.Unix(token.Exp, 0))
 
 			return next(c)
 		}
 	}
 }
+
+// personalTokenHttpCode is 403 for insufficient scope, ero.ToHttpCode would make it 412
+func personalTokenHttpCode(eroErr ero.Error) int {
+	if eroErr.Code() == ero.CodePermissionDenied {
+		return http.StatusForbidden
+	}
+	return ero.ToHttpCode(eroErr.Code())
+}
@@ -20,6 +20,8 @@ func Pagination(defaultPageSize uint64) echo.MiddlewareFunc {
 
 			c.Set("page", page)
 			c.Set("page_size", pageSize)
+			// opaque cursor takes precedence over page if set
+			c.Set("cursor", c.QueryParam("cursor"))
 
 			return next(c)
 		}
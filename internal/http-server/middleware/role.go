@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+// Role lets in users with the minimum role or a higher one, it must follow Authorized.
+// Personal access tokens have no role, so they are never let in
+func Role(minimum models.Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, _ := c.Get("role").(models.Role)
+			if role.Rank() < minimum.Rank() {
+				return c.JSONBlob(http.StatusForbidden, ErrorMessage("insufficient role").Blob())
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -2,12 +2,16 @@ package server
 
 import (
 	"log/slog"
+	"net"
 	"net/http"
 
 	"github.com/Onnywrite/tinkoff-prod/internal/http-server/handler"
+	adminhandler "github.com/Onnywrite/tinkoff-prod/internal/http-server/handler/admin"
 	authhandler "github.com/Onnywrite/tinkoff-prod/internal/http-server/handler/auth"
 	privatehandler "github.com/Onnywrite/tinkoff-prod/internal/http-server/handler/private"
 	mymiddleware "github.com/Onnywrite/tinkoff-prod/internal/http-server/middleware"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/tokens"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -17,11 +21,17 @@ type Server struct {
 	address           string
 	logger            *slog.Logger
 	certPath, keyPath string
+	// trustedProxies may set X-Forwarded-For, the IP of any other client is the one it connects from
+	trustedProxies []*net.IPNet
 
 	countriesService CountriesService
 	usersService     UsersService
 	feedService      FeedService
 	likesService     LikesService
+	commentsService  CommentsService
+	followsService   FollowsService
+
+	denylist tokens.Denylist
 }
 
 type CountriesService interface {
@@ -32,14 +42,60 @@ type CountriesService interface {
 type UsersService interface {
 	authhandler.UserRegistrator
 	authhandler.IdentityProvider
+	authhandler.TwoFactorIdentityProvider
+	authhandler.OidcAuthorizer
+	authhandler.OidcIdentityProvider
 	authhandler.AccessTokenUpdater
+	authhandler.LogoutProvider
+	authhandler.VerificationSender
+	authhandler.EmailVerifier
+	authhandler.PasswordResetRequester
+	authhandler.PasswordResetter
 	privatehandler.UserProvider
+	privatehandler.UserUpdater
+	privatehandler.PasswordChanger
+	privatehandler.SessionsProvider
+	privatehandler.SessionRevoker
+	privatehandler.PersonalTokenCreator
+	privatehandler.PersonalTokensProvider
+	privatehandler.PersonalTokenRevoker
+	privatehandler.TotpEnroller
+	privatehandler.TotpConfirmer
+	privatehandler.TotpDisabler
+	privatehandler.RecoveryCodesGenerator
+	adminhandler.UsersProvider
+	adminhandler.Suspender
+	adminhandler.Unsuspender
+	adminhandler.RoleSetter
+	mymiddleware.PersonalTokenAuthorizer
 }
 
 type FeedService interface {
 	privatehandler.PostCreator
 	privatehandler.AllFeedProvider
 	privatehandler.AuthorFeedProvider
+	privatehandler.PostsSearcher
+	privatehandler.PostEditor
+	privatehandler.PostDeleter
+	privatehandler.PostRevisionsProvider
+	privatehandler.TimelineProvider
+	adminhandler.PostRemover
+}
+
+type CommentsService interface {
+	privatehandler.CommentCreator
+	privatehandler.CommentsProvider
+	privatehandler.CommentEditor
+	privatehandler.CommentDeleter
+}
+
+type FollowsService interface {
+	privatehandler.Follower
+	privatehandler.Unfollower
+	privatehandler.FollowsProvider
+	privatehandler.FollowRequestsProvider
+	privatehandler.FollowRequestApprover
+	privatehandler.FollowRequestRejecter
 }
 
 type LikesService interface {
@@ -48,26 +104,52 @@ type LikesService interface {
 	privatehandler.LikesProvider
 }
 
-func NewServer(logger *slog.Logger, address, certPath, keyPath string,
-	countriesService CountriesService, usersService UsersService, feedService FeedService, likesService LikesService) *Server {
+func NewServer(logger *slog.Logger, address, certPath, keyPath string, trustedProxies []*net.IPNet,
+	countriesService CountriesService, usersService UsersService, feedService FeedService, likesService LikesService,
+	commentsService CommentsService, followsService FollowsService, denylist tokens.Denylist) *Server {
 	return &Server{
 		logger:           logger,
 		address:          address,
 		certPath:         certPath,
 		keyPath:          keyPath,
+		trustedProxies:   trustedProxies,
 		feedService:      feedService,
 		countriesService: countriesService,
 		likesService:     likesService,
 		usersService:     usersService,
+		commentsService:  commentsService,
+		followsService:   followsService,
+		denylist:         denylist,
 	}
 }
 
+// authorized must be declared on every private route, personal access tokens are let in only with the scope.
+// It comes before the route's other middlewares, so unauthorized requests are never validated
+func (s *Server) authorized(scope tokens.Scope) echo.MiddlewareFunc {
+	return mymiddleware.Authorized(s.denylist, s.usersService, scope)
+}
+
+// ipExtractor never trusts headers of clients, otherwise anyone could rotate X-Forwarded-For
+// to get around throttling by IP
+func (s *Server) ipExtractor() echo.IPExtractor {
+	if len(s.trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range s.trustedProxies {
+		options = append(options, echo.TrustIPRange(proxy))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
 func (s *Server) Start() error {
 	e := echo.New()
+	e.IPExtractor = s.ipExtractor()
 
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"*"},
-		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodOptions, http.MethodPut, http.MethodDelete},
+		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodOptions, http.MethodPut, http.MethodPatch, http.MethodDelete},
 		AllowHeaders: []string{"*"},
 	}))
 
@@ -75,6 +157,7 @@ func (s *Server) Start() error {
 		g := e.Group("api/", mymiddleware.Logger(s.logger), middleware.Recover())
 
 		g.GET("ping", handler.GetPing())
+		g.GET(".well-known/jwks.json", handler.GetJwks(tokens.AccessKeys))
 		g.GET("countries", handler.GetCountries(s.countriesService))
 		g.GET("countries/:alpha2", handler.GetCountryAlpha(s.countriesService))
 		{
@@ -82,28 +165,78 @@ func (s *Server) Start() error {
 
 			authg.POST("register", authhandler.PostRegister(s.usersService))
 			authg.POST("sign-in", authhandler.PostSignIn(s.usersService))
+			authg.POST("sign-in/2fa", authhandler.PostSignInTwoFactor(s.usersService))
+			authg.GET("oidc/:provider", authhandler.GetOidc(s.usersService))
+			authg.POST("oidc/:provider/callback", authhandler.PostOidcCallback(s.usersService))
 			authg.POST("refresh", authhandler.PostRefresh(s.usersService))
+			authg.POST("logout", authhandler.PostLogout(s.usersService), s.authorized(tokens.ScopeAccount))
+			authg.POST("email-verification", authhandler.PostEmailVerification(s.usersService))
+			authg.POST("email-verification/confirm", authhandler.PostEmailVerificationConfirm(s.usersService))
+			authg.POST("password-reset", authhandler.PostPasswordReset(s.usersService))
+			authg.POST("password-reset/confirm", authhandler.PostPasswordResetConfirm(s.usersService))
 		}
 		{
-			privateg := g.Group("private/", mymiddleware.Authorized())
-
-			privateg.GET("me", privatehandler.GetMe(s.usersService))
-			privateg.POST("me/feed", privatehandler.PostMeFeed(s.feedService))
-			privateg.GET("feed", privatehandler.GetFeed(s.feedService), mymiddleware.Pagination(100))
+			privateg := g.Group("private/")
+
+			privateg.GET("me", privatehandler.GetMe(s.usersService), s.authorized(tokens.ScopeProfileRead))
+			privateg.PATCH("me", privatehandler.PatchMe(s.usersService), s.authorized(tokens.ScopeProfileWrite))
+			privateg.POST("me/password", privatehandler.PostMePassword(s.usersService), s.authorized(tokens.ScopeAccount))
+			privateg.GET("me/sessions", privatehandler.GetMeSessions(s.usersService), s.authorized(tokens.ScopeAccount))
+			privateg.DELETE("me/sessions/:session_id", privatehandler.DeleteMeSession(s.usersService), s.authorized(tokens.ScopeAccount), mymiddleware.IdParam("session_id"))
+			privateg.GET("me/tokens", privatehandler.GetMeTokens(s.usersService), s.authorized(tokens.ScopeAccount))
+			privateg.POST("me/tokens", privatehandler.PostMeToken(s.usersService), s.authorized(tokens.ScopeAccount))
+			privateg.DELETE("me/tokens/:token_id", privatehandler.DeleteMeToken(s.usersService), s.authorized(tokens.ScopeAccount), mymiddleware.IdParam("token_id"))
+			privateg.POST("me/2fa/totp", privatehandler.PostMeTotp(s.usersService), s.authorized(tokens.ScopeAccount))
+			privateg.POST("me/2fa/totp/confirm", privatehandler.PostMeTotpConfirm(s.usersService), s.authorized(tokens.ScopeAccount))
+			privateg.POST("me/2fa/totp/disable", privatehandler.PostMeTotpDisable(s.usersService), s.authorized(tokens.ScopeAccount))
+			privateg.POST("me/2fa/recovery-codes", privatehandler.PostMeRecoveryCodes(s.usersService), s.authorized(tokens.ScopeAccount))
+			privateg.POST("me/feed", privatehandler.PostMeFeed(s.feedService), s.authorized(tokens.ScopeFeedWrite))
+			privateg.GET("me/timeline", privatehandler.GetMeTimeline(s.feedService), s.authorized(tokens.ScopeFeedRead), mymiddleware.Pagination(100))
+			privateg.GET("me/follow-requests", privatehandler.GetMeFollowRequests(s.followsService), s.authorized(tokens.ScopeProfileRead), mymiddleware.Pagination(100))
+			privateg.POST("me/follow-requests/:user_id/approve", privatehandler.PostApproveFollowRequest(s.followsService), s.authorized(tokens.ScopeFollowsWrite), mymiddleware.IdParam("user_id"))
+			privateg.DELETE("me/follow-requests/:user_id", privatehandler.DeleteFollowRequest(s.followsService), s.authorized(tokens.ScopeFollowsWrite), mymiddleware.IdParam("user_id"))
+			privateg.GET("feed", privatehandler.GetFeed(s.feedService), s.authorized(tokens.ScopeFeedRead), mymiddleware.Pagination(100))
+			privateg.GET("search/posts", privatehandler.GetSearchPosts(s.feedService), s.authorized(tokens.ScopeFeedRead), mymiddleware.Pagination(100))
 			{
-				feedg := privateg.Group("posts/", mymiddleware.IdParam("post_id"))
-
-				feedg.GET(":post_id/likes", privatehandler.GetLikes(s.likesService), mymiddleware.Pagination(100))
-				feedg.POST(":post_id/like", privatehandler.PostLike(s.likesService))
-				feedg.DELETE(":post_id/like", privatehandler.DeleteLike(s.likesService))
+				feedg := privateg.Group("posts/")
+				postId := mymiddleware.IdParam("post_id")
+
+				feedg.PATCH(":post_id", privatehandler.PatchPost(s.feedService), s.authorized(tokens.ScopeFeedWrite), postId)
+				feedg.DELETE(":post_id", privatehandler.DeletePost(s.feedService), s.authorized(tokens.ScopeFeedWrite), postId)
+				feedg.GET(":post_id/revisions", privatehandler.GetPostRevisions(s.feedService), s.authorized(tokens.ScopeFeedRead), postId)
+				feedg.GET(":post_id/likes", privatehandler.GetLikes(s.likesService), s.authorized(tokens.ScopeFeedRead), postId, mymiddleware.Pagination(100))
+				feedg.POST(":post_id/like", privatehandler.PostLike(s.likesService), s.authorized(tokens.ScopeLikesWrite), postId)
+				feedg.DELETE(":post_id/like", privatehandler.DeleteLike(s.likesService), s.authorized(tokens.ScopeLikesWrite), postId)
+
+				feedg.POST(":post_id/comments", privatehandler.PostComment(s.commentsService), s.authorized(tokens.ScopeCommentsWrite), postId)
+				feedg.GET(":post_id/comments", privatehandler.GetComments(s.commentsService), s.authorized(tokens.ScopeFeedRead), postId, mymiddleware.Pagination(100))
+				feedg.PATCH(":post_id/comments/:comment_id", privatehandler.PatchComment(s.commentsService), s.authorized(tokens.ScopeCommentsWrite), postId, mymiddleware.IdParam("comment_id"))
+				feedg.DELETE(":post_id/comments/:comment_id", privatehandler.DeleteComment(s.commentsService), s.authorized(tokens.ScopeCommentsWrite), postId, mymiddleware.IdParam("comment_id"))
 			}
 			{
-				profilesg := privateg.Group("profiles/", mymiddleware.IdParam("user_id"))
-
-				profilesg.GET(":user_id", privatehandler.GetProfile(s.usersService))
-				profilesg.GET(":user_id/feed", privatehandler.GetProfileFeed(s.feedService), mymiddleware.Pagination(100))
+				profilesg := privateg.Group("profiles/")
+				userId := mymiddleware.IdParam("user_id")
+
+				profilesg.GET(":user_id", privatehandler.GetProfile(s.usersService), s.authorized(tokens.ScopeProfileRead), userId)
+				profilesg.GET(":user_id/feed", privatehandler.GetProfileFeed(s.feedService), s.authorized(tokens.ScopeFeedRead), userId, mymiddleware.Pagination(100))
+				profilesg.POST(":user_id/follow", privatehandler.PostFollow(s.followsService), s.authorized(tokens.ScopeFollowsWrite), userId)
+				profilesg.DELETE(":user_id/follow", privatehandler.DeleteFollow(s.followsService), s.authorized(tokens.ScopeFollowsWrite), userId)
+				profilesg.GET(":user_id/followers", privatehandler.GetFollowers(s.followsService), s.authorized(tokens.ScopeProfileRead), userId, mymiddleware.Pagination(100))
+				profilesg.GET(":user_id/following", privatehandler.GetFollowings(s.followsService), s.authorized(tokens.ScopeProfileRead), userId, mymiddleware.Pagination(100))
 			}
 		}
+		{
+			// roles are carried only by access tokens of sessions, so personal access tokens are never let in
+			adming := g.Group("admin/")
+			moderator, admin := mymiddleware.Role(models.RoleModerator), mymiddleware.Role(models.RoleAdmin)
+			userId := mymiddleware.IdParam("user_id")
+
+			adming.GET("users", adminhandler.GetUsers(s.usersService), s.authorized(tokens.ScopeAccount), moderator, mymiddleware.Pagination(100))
+			adming.POST("users/:user_id/suspension", adminhandler.PostSuspension(s.usersService), s.authorized(tokens.ScopeAccount), moderator, userId)
+			adming.DELETE("users/:user_id/suspension", adminhandler.DeleteSuspension(s.usersService), s.authorized(tokens.ScopeAccount), moderator, userId)
+			adming.PUT("users/:user_id/role", adminhandler.PutRole(s.usersService), s.authorized(tokens.ScopeAccount), admin, userId)
+			adming.DELETE("posts/:post_id", adminhandler.DeletePost(s.feedService), s.authorized(tokens.ScopeAccount), moderator, mymiddleware.IdParam("post_id"))
+		}
 	}
 
 	s.logger.Info("server has been started", "address", s.address)
@@ -0,0 +1,87 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+)
+
+var ErrInvalid = errors.New("invalid cursor")
+
+type payload struct {
+	Time     int64  `json:"t"`
+	Id       uint64 `json:"i"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// Encode makes an opaque string out of the keyset
+func Encode(keyset storage.Keyset) string {
+	b, _ := json.Marshal(payload{
+		Time:     keyset.Time.UnixMicro(),
+		Id:       keyset.Id,
+		Backward: keyset.Backward,
+	})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode parses a cursor made by Encode. Empty cursor is decoded to nil
+func Decode(cursor string) (*storage.Keyset, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	var p payload
+	if err = json.Unmarshal(b, &p); err != nil {
+		return nil, ErrInvalid
+	}
+
+	return &storage.Keyset{
+		Time:     time.UnixMicro(p.Time).UTC(),
+		Id:       p.Id,
+		Backward: p.Backward,
+	}, nil
+}
+
+// Page trims items selected for the page and returns cursors to the next and previous pages.
+// Items must be ordered by (time DESC, id DESC) and selected with limit pageSize+1,
+// so that the extra item tells whether there is one more page.
+// requested is the keyset the page was selected by, nil if it was selected by offset
+func Page[T any](items []T, pageSize int, requested *storage.Keyset, isFirst bool, key func(T) (time.Time, uint64)) (page []T, next, prev *string) {
+	hasMore := len(items) > pageSize
+	backward := requested != nil && requested.Backward
+
+	switch {
+	case hasMore && backward:
+		items = items[len(items)-pageSize:]
+	case hasMore:
+		items = items[:pageSize]
+	}
+
+	if len(items) == 0 {
+		return items, nil, nil
+	}
+
+	if hasMore || backward {
+		t, id := key(items[len(items)-1])
+		next = encodePtr(storage.Keyset{Time: t, Id: id})
+	}
+	if (hasMore && backward) || (!backward && !isFirst) {
+		t, id := key(items[0])
+		prev = encodePtr(storage.Keyset{Time: t, Id: id, Backward: true})
+	}
+
+	return items, next, prev
+}
+
+func encodePtr(keyset storage.Keyset) *string {
+	s := Encode(keyset)
+	return &s
+}
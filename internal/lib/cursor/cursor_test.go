@@ -0,0 +1,122 @@
+package cursor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/cursor"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	keyset := storage.Keyset{
+		Time:     time.Date(2024, time.July, 20, 12, 30, 15, 123456000, time.UTC),
+		Id:       42,
+		Backward: true,
+	}
+
+	got, err := cursor.Decode(cursor.Encode(keyset))
+	require.NoError(t, err)
+	assert.Equal(t, keyset, *got)
+
+	got, err = cursor.Decode("")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	_, err = cursor.Decode("not a cursor")
+	assert.ErrorIs(t, err, cursor.ErrInvalid)
+}
+
+type item struct {
+	t  time.Time
+	id uint64
+}
+
+func key(i item) (time.Time, uint64) {
+	return i.t, i.id
+}
+
+func items(ids ...uint64) []item {
+	res := make([]item, len(ids))
+	for i, id := range ids {
+		res[i] = item{t: time.Unix(int64(id), 0).UTC(), id: id}
+	}
+	return res
+}
+
+func TestPage(t *testing.T) {
+	tests := []struct {
+		name      string
+		items     []item
+		requested *storage.Keyset
+		isFirst   bool
+		want      []item
+		next      *storage.Keyset
+		prev      *storage.Keyset
+	}{
+		{
+			name:    "first page",
+			items:   items(9, 8, 7),
+			isFirst: true,
+			want:    items(9, 8),
+			next:    &storage.Keyset{Time: time.Unix(8, 0).UTC(), Id: 8},
+		},
+		{
+			name:    "last page by offset",
+			items:   items(3, 2),
+			isFirst: false,
+			want:    items(3, 2),
+			prev:    &storage.Keyset{Time: time.Unix(3, 0).UTC(), Id: 3, Backward: true},
+		},
+		{
+			name:      "forward",
+			items:     items(7, 6, 5),
+			requested: &storage.Keyset{Time: time.Unix(8, 0).UTC(), Id: 8},
+			want:      items(7, 6),
+			next:      &storage.Keyset{Time: time.Unix(6, 0).UTC(), Id: 6},
+			prev:      &storage.Keyset{Time: time.Unix(7, 0).UTC(), Id: 7, Backward: true},
+		},
+		{
+			name:      "backward",
+			items:     items(9, 8, 7),
+			requested: &storage.Keyset{Time: time.Unix(6, 0).UTC(), Id: 6, Backward: true},
+			want:      items(8, 7),
+			next:      &storage.Keyset{Time: time.Unix(7, 0).UTC(), Id: 7},
+			prev:      &storage.Keyset{Time: time.Unix(8, 0).UTC(), Id: 8, Backward: true},
+		},
+		{
+			name:      "backward to the first page",
+			items:     items(9, 8),
+			requested: &storage.Keyset{Time: time.Unix(7, 0).UTC(), Id: 7, Backward: true},
+			want:      items(9, 8),
+			next:      &storage.Keyset{Time: time.Unix(8, 0).UTC(), Id: 8},
+		},
+		{
+			name:      "empty",
+			items:     items(),
+			requested: &storage.Keyset{Time: time.Unix(1, 0).UTC(), Id: 1},
+			want:      items(),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			got, next, prev := cursor.Page(tc.items, 2, tc.requested, tc.isFirst, key)
+			assert.Equal(tt, tc.want, got)
+			assertCursor(tt, tc.next, next)
+			assertCursor(tt, tc.prev, prev)
+		})
+	}
+}
+
+func assertCursor(t *testing.T, want *storage.Keyset, got *string) {
+	if want == nil {
+		assert.Nil(t, got)
+		return
+	}
+	require.NotNil(t, got)
+	keyset, err := cursor.Decode(*got)
+	require.NoError(t, err)
+	assert.Equal(t, *want, *keyset)
+}
@@ -0,0 +1,97 @@
+// Package fulltext is a simple full-text search which imitates
+// postgres 'simple' text search configuration.
+// It is used where postgres is not available, e.g. by memory.MemStorage
+package fulltext
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Tokenize splits s into lowercased words, like to_tsvector('simple', s) does
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), isSeparator)
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// Match reports whether document contains every word of query,
+// like plainto_tsquery('simple', query) does. Empty query matches nothing
+func Match(document, query []string) bool {
+	if len(query) == 0 {
+		return false
+	}
+
+	words := make(map[string]struct{}, len(document))
+	for _, w := range document {
+		words[w] = struct{}{}
+	}
+	for _, w := range query {
+		if _, ok := words[w]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Rank is a share of document words that are in query.
+// The more often query words occur, the higher document is ranked
+func Rank(document, query []string) float64 {
+	if len(document) == 0 {
+		return 0
+	}
+
+	queryWords := make(map[string]struct{}, len(query))
+	for _, w := range query {
+		queryWords[w] = struct{}{}
+	}
+
+	var found int
+	for _, w := range document {
+		if _, ok := queryWords[w]; ok {
+			found++
+		}
+	}
+	return float64(found) / float64(len(document))
+}
+
+// Headline wraps every word of text found in query with startSel and stopSel.
+// Text is HTML-escaped, so the headline is safe to render, the selectors are written as they are
+func Headline(text string, query []string, startSel, stopSel string) string {
+	queryWords := make(map[string]struct{}, len(query))
+	for _, w := range query {
+		queryWords[w] = struct{}{}
+	}
+
+	var b strings.Builder
+	word := make([]rune, 0, 16)
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		_, found := queryWords[strings.ToLower(string(word))]
+		if found {
+			b.WriteString(startSel)
+		}
+		b.WriteString(html.EscapeString(string(word)))
+		if found {
+			b.WriteString(stopSel)
+		}
+		word = word[:0]
+	}
+
+	for _, r := range text {
+		if isSeparator(r) {
+			flush()
+			b.WriteString(html.EscapeString(string(r)))
+			continue
+		}
+		word = append(word, r)
+	}
+	flush()
+
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package fulltext_test
+
+import (
+	"testing"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/fulltext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		query    string
+		match    bool
+	}{
+		{
+			name:     "all words",
+			document: "Hello, World! Привет, мир",
+			query:    "world HELLO",
+			match:    true,
+		},
+		{
+			name:     "cyrillic",
+			document: "Hello, World! Привет, мир",
+			query:    "мир",
+			match:    true,
+		},
+		{
+			name:     "not all words",
+			document: "Hello, World!",
+			query:    "hello there",
+			match:    false,
+		},
+		{
+			name:     "part of word",
+			document: "Hello, World!",
+			query:    "hell",
+			match:    false,
+		},
+		{
+			name:     "empty query",
+			document: "Hello, World!",
+			query:    " !? ",
+			match:    false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			got := fulltext.Match(fulltext.Tokenize(tc.document), fulltext.Tokenize(tc.query))
+			assert.Equal(tt, tc.match, got)
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	query := fulltext.Tokenize("go")
+
+	often := fulltext.Rank(fulltext.Tokenize("go go go, write go"), query)
+	rarely := fulltext.Rank(fulltext.Tokenize("go and write some code"), query)
+
+	assert.Greater(t, often, rarely)
+	assert.Zero(t, fulltext.Rank(nil, query))
+}
+
+func TestHeadline(t *testing.T) {
+	got := fulltext.Headline("Hello, World! Hello-world", fulltext.Tokenize("world"), "<b>", "</b>")
+	assert.Equal(t, "Hello, <b>World</b>! Hello-<b>world</b>", got)
+
+	got = fulltext.Headline(`<img src=x onerror="alert('world')">`, fulltext.Tokenize("world"), "<b>", "</b>")
+	assert.Equal(t, "&lt;img src=x onerror=&#34;alert(&#39;<b>world</b>&#39;)&#34;&gt;", got)
+}
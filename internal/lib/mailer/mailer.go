@@ -0,0 +1,153 @@
+// Package mailer sends plain text emails
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type SMTPOptions struct {
+	Host string
+	Port uint16
+	// Username and Password are optional, PLAIN auth is used if set
+	Username string
+	Password string
+	From     string
+}
+
+// SMTP sends every message in its own connection, STARTTLS is used when the server supports it
+type SMTP struct {
+	opts SMTPOptions
+}
+
+var _ Mailer = (*SMTP)(nil)
+
+func NewSMTP(opts SMTPOptions) *SMTP {
+	return &SMTP{opts: opts}
+}
+
+// Send gives up when ctx is done, the connection is closed then even if the server does not respond
+func (m *SMTP) Send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(m.opts.Host, strconv.Itoa(int(m.opts.Port)))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	if err = m.send(conn, msg); err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// send does what smtp.SendMail does over conn
+func (m *SMTP) send(conn net.Conn, msg Message) error {
+	c, err := smtp.NewClient(conn, m.opts.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err = c.Hello("localhost"); err != nil {
+		return err
+	}
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: m.opts.Host}); err != nil {
+			return err
+		}
+	}
+	if m.opts.Username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("mailer: server does not support AUTH")
+		}
+		if err = c.Auth(smtp.PlainAuth("", m.opts.Username, m.opts.Password, m.opts.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err = c.Mail(m.opts.From); err != nil {
+		return err
+	}
+	if err = c.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(format(m.opts.From, msg)); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// Writer writes messages instead of sending them, to stdout or a file during development
+type Writer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+var _ Mailer = (*Writer)(nil)
+
+func NewWriter(w io.Writer, from string) *Writer {
+	return &Writer{w: w, from: from}
+}
+
+func (m *Writer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.w, "%s\r\n.\r\n", format(m.from, msg))
+	return err
+}
+
+// format makes an RFC 5322 message, header values are stripped of line breaks
+func format(from string, msg Message) []byte {
+	clean := strings.NewReplacer("\r", "", "\n", "")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", clean.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", clean.Replace(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", clean.Replace(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
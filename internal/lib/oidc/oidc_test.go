@@ -0,0 +1,165 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/oidc"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubIdP issues an id token for the code "code" if the PKCE verifier matches the last challenge
+type stubIdP struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	nonce     string
+	claims    func(jwt.MapClaims)
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := &stubIdP{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "stub",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if id != "client" || secret != "secret" || r.PostFormValue("code") != "code" ||
+			base64.RawURLEncoding.EncodeToString(verifier[:]) != idp.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		claims := jwt.MapClaims{
+			"iss":            idp.URL,
+			"aud":            "client",
+			"sub":            "subject",
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          idp.nonce,
+			"email":          "user@example.com",
+			"email_verified": true,
+			"given_name":     "John",
+		}
+		if idp.claims != nil {
+			idp.claims(claims)
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "stub"
+		signed, _ := token.SignedString(key)
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "access_token": "access"})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+
+	return idp
+}
+
+// authorize imitates the user signing in at the provider
+func (idp *stubIdP) authorize(t *testing.T, authUrl string) (state string) {
+	u, err := url.Parse(authUrl)
+	require.NoError(t, err)
+	require.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+
+	idp.challenge, idp.nonce = u.Query().Get("code_challenge"), u.Query().Get("nonce")
+	return u.Query().Get("state")
+}
+
+func TestProviders(t *testing.T) {
+	tests := []struct {
+		name    string
+		claims  func(jwt.MapClaims)
+		state   func(state string) string
+		binding func(binding string) string
+		pkce    func(idp *stubIdP)
+		err     error
+	}{
+		{name: "success"},
+		{name: "unknown state", state: func(string) string { return "unknown" }, err: oidc.ErrInvalidState},
+		{name: "another client", binding: func(string) string { return "attacker's" }, err: oidc.ErrInvalidState},
+		{name: "wrong verifier", pkce: func(idp *stubIdP) { idp.challenge = "tampered" }, err: oidc.ErrExchange},
+		{name: "wrong audience", claims: func(c jwt.MapClaims) { c["aud"] = "another" }, err: oidc.ErrInvalidIdToken},
+		{name: "wrong nonce", claims: func(c jwt.MapClaims) { c["nonce"] = "replayed" }, err: oidc.ErrInvalidIdToken},
+		{name: "expired", claims: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, err: oidc.ErrInvalidIdToken},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			idp := newStubIdP(tt)
+			idp.claims = tc.claims
+			providers := oidc.NewProviders([]oidc.ProviderConfig{{
+				Name:         "stub",
+				Issuer:       idp.URL,
+				ClientId:     "client",
+				ClientSecret: "secret",
+				RedirectUrl:  "https://app.example.com/callback",
+				Scopes:       []string{"email", "profile"},
+			}}, time.Minute, idp.Client())
+
+			authUrl, binding, err := providers.AuthCodeURL(context.Background(), "stub")
+			require.NoError(tt, err)
+			state := idp.authorize(tt, authUrl)
+			if tc.state != nil {
+				state = tc.state(state)
+			}
+			if tc.binding != nil {
+				binding = tc.binding(binding)
+			}
+			if tc.pkce != nil {
+				tc.pkce(idp)
+			}
+
+			identity, err := providers.Exchange(context.Background(), "stub", "code", state, binding)
+			if tc.err != nil {
+				assert.ErrorIs(tt, err, tc.err)
+				return
+			}
+			require.NoError(tt, err)
+			assert.Equal(tt, oidc.Identity{
+				Issuer:        idp.URL,
+				Subject:       "subject",
+				Email:         "user@example.com",
+				EmailVerified: true,
+				GivenName:     "John",
+			}, *identity)
+
+			_, err = providers.Exchange(context.Background(), "stub", "code", state, binding)
+			assert.ErrorIs(tt, err, oidc.ErrInvalidState)
+		})
+	}
+
+	t.Run("unknown provider", func(tt *testing.T) {
+		_, _, err := oidc.NewProviders(nil, time.Minute, nil).AuthCodeURL(context.Background(), "stub")
+		assert.ErrorIs(tt, err, oidc.ErrUnknownProvider)
+	})
+}
@@ -0,0 +1,322 @@
+// Package oidc signs users in with OpenID Connect providers
+// using the authorization code flow with PKCE
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	ErrInvalidIdToken = errors.New("invalid id token")
+	ErrExchange       = errors.New("authorization code exchange failed")
+	ErrUnknownKey     = errors.New("unknown key id")
+)
+
+const (
+	// leeway tolerates clock skew between us and the provider
+	leeway = time.Minute
+	// keysRefresh limits fetching of the provider's keys when a token has an unknown kid
+	keysRefresh = time.Minute
+)
+
+type ProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	// RedirectUrl must be registered at the provider, the code and state are sent there
+	RedirectUrl string
+	// Scopes are requested in addition to openid
+	Scopes []string
+}
+
+// Identity is what the provider tells about the user in the id token
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+	Picture       string
+	// Birthdate is YYYY-MM-DD if the provider knows it
+	Birthdate string
+}
+
+// Provider discovers its endpoints and keys on first use, so the app starts even if it is down
+type Provider struct {
+	cfg    ProviderConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	discovery     *discovery
+	keys          map[string]any
+	keysFetchedAt time.Time
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+func NewProvider(cfg ProviderConfig, client *http.Client) *Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Provider{
+		cfg:    cfg,
+		client: client,
+		keys:   make(map[string]any),
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthCodeURL is where the user is sent to sign in, challenge is S256 of the PKCE verifier
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, challenge string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientId)
+	query.Set("redirect_uri", p.cfg.RedirectUrl)
+	query.Set("scope", strings.Join(append([]string{"openid"}, p.cfg.Scopes...), " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", challenge)
+	query.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + query.Encode(), nil
+}
+
+// Exchange redeems the code and verifies the id token it is exchanged for
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectUrl)
+	form.Set("client_id", p.cfg.ClientId)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientId), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IdToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%w: %d response", ErrExchange, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrExchange, body.Error, body.ErrorDescription)
+	}
+
+	return p.verify(ctx, body.IdToken, nonce)
+}
+
+func (p *Provider) verify(ctx context.Context, idToken, nonce string) (*Identity, error) {
+	parser := jwt.Parser{
+		SkipClaimsValidation: true,
+		ValidMethods:         []string{"RS256", "ES256"},
+	}
+	token, err := parser.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIdToken, err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidIdToken
+	}
+
+	now := time.Now()
+	switch {
+	case claims["iss"] != p.cfg.Issuer:
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidIdToken)
+	case !hasAudience(claims["aud"], p.cfg.ClientId):
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidIdToken)
+	case !claims.VerifyExpiresAt(now.Add(-leeway).Unix(), true):
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIdToken)
+	case !claims.VerifyIssuedAt(now.Add(leeway).Unix(), false):
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidIdToken)
+	case claims["nonce"] != nonce:
+		return nil, fmt.Errorf("%w: unexpected nonce", ErrInvalidIdToken)
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIdToken)
+	}
+
+	identity := &Identity{
+		Issuer:  p.cfg.Issuer,
+		Subject: subject,
+	}
+	identity.Email, _ = claims["email"].(string)
+	identity.GivenName, _ = claims["given_name"].(string)
+	identity.FamilyName, _ = claims["family_name"].(string)
+	identity.Picture, _ = claims["picture"].(string)
+	identity.Birthdate, _ = claims["birthdate"].(string)
+	// some providers send it as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+
+	return identity, nil
+}
+
+func hasAudience(aud any, clientId string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientId
+	case []any:
+		return slices.Contains(aud, any(clientId))
+	}
+	return false
+}
+
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var d discovery
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.cfg.Issuer, "/")+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, fmt.Errorf("oidc discovery of %s: %w", p.cfg.Name, err)
+	}
+	if d.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery of %s: issuer '%s' does not match", p.cfg.Name, d.Issuer)
+	}
+
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+// key fetches keys again if kid is unknown, because the provider may have rotated them
+func (p *Provider) key(ctx context.Context, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < keysRefresh {
+		return nil, ErrUnknownKey
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.discovery.JwksUri, &set); err != nil {
+		return nil, err
+	}
+	p.keysFetchedAt = time.Now()
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		switch jwk.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN == nil && errE == nil {
+				keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+			}
+		case "EC":
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if jwk.Crv == "P-256" && errX == nil && errY == nil {
+				keys[jwk.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+			}
+		}
+	}
+	p.keys = keys
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,137 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrInvalidState    = errors.New("unknown or expired state")
+)
+
+// Providers starts sign ins and remembers their PKCE verifiers and nonces by state until they are finished.
+// A state is bound to the client that has started the sign in with a binding only the client gets,
+// so nobody can make a victim finish a sign in with someone else's code and state.
+// States are kept in memory, so the callback must reach the same instance
+type Providers struct {
+	providers map[string]*Provider
+	ttl       time.Duration
+
+	mu        sync.Mutex
+	pending   map[string]pending
+	lastSweep time.Time
+}
+
+type pending struct {
+	provider  string
+	binding   string
+	verifier  string
+	nonce     string
+	expiresAt time.Time
+}
+
+// NewProviders makes providers of cfgs, ttl is how long a user may take to sign in at a provider
+func NewProviders(cfgs []ProviderConfig, ttl time.Duration, client *http.Client) *Providers {
+	providers := make(map[string]*Provider, len(cfgs))
+	for _, cfg := range cfgs {
+		providers[cfg.Name] = NewProvider(cfg, client)
+	}
+
+	return &Providers{
+		providers: providers,
+		ttl:       ttl,
+		pending:   make(map[string]pending),
+		lastSweep: time.Now(),
+	}
+}
+
+// AuthCodeURL starts a sign in with the provider. The client keeps binding to itself
+// and presents it to Exchange along with the code and state
+func (ps *Providers) AuthCodeURL(ctx context.Context, provider string) (url string, binding string, err error) {
+	p, ok := ps.providers[provider]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	var values [4]string
+	for i := range values {
+		random, err := randomString()
+		if err != nil {
+			return "", "", err
+		}
+		values[i] = random
+	}
+	state, nonce, verifier, binding := values[0], values[1], values[2], values[3]
+
+	challenge := sha256.Sum256([]byte(verifier))
+	url, err = p.AuthCodeURL(ctx, state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return "", "", err
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.sweep()
+	ps.pending[state] = pending{
+		provider:  provider,
+		binding:   binding,
+		verifier:  verifier,
+		nonce:     nonce,
+		expiresAt: time.Now().Add(ps.ttl),
+	}
+
+	return url, binding, nil
+}
+
+// Exchange finishes the sign in started with state by the client holding binding, a state can be used once
+func (ps *Providers) Exchange(ctx context.Context, provider, code, state, binding string) (*Identity, error) {
+	p, ok := ps.providers[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	ps.mu.Lock()
+	started, ok := ps.pending[state]
+	delete(ps.pending, state)
+	ps.mu.Unlock()
+
+	if !ok || started.provider != provider || time.Now().After(started.expiresAt) ||
+		subtle.ConstantTimeCompare([]byte(started.binding), []byte(binding)) != 1 {
+		return nil, ErrInvalidState
+	}
+
+	return p.Exchange(ctx, code, started.verifier, started.nonce)
+}
+
+// sweep forgets abandoned sign ins, ps.mu must be locked
+func (ps *Providers) sweep() {
+	now := time.Now()
+	if now.Sub(ps.lastSweep) < ps.ttl {
+		return
+	}
+
+	for state, p := range ps.pending {
+		if now.After(p.expiresAt) {
+			delete(ps.pending, state)
+		}
+	}
+	ps.lastSweep = now
+}
+
+// randomString is 256 bits in base64url, it is a valid PKCE verifier as well
+func randomString() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
@@ -0,0 +1,240 @@
+// Package passhash hashes passwords with argon2id or bcrypt.
+// Hashes describe their algorithm and parameters, so a hash of any supported one is verified,
+// and NeedsRehash tells the ones that differ from the current Options
+package passhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+var (
+	ErrMismatch       = errors.New("password does not match the hash")
+	ErrUnknownHash    = errors.New("unknown hash algorithm")
+	ErrInvalidHash    = errors.New("invalid hash")
+	ErrTooLong        = errors.New("password is too long for the algorithm")
+	ErrInvalidOptions = errors.New("invalid password hashing options")
+)
+
+// Options of new hashes
+type Options struct {
+	// Algorithm is either Argon2id or Bcrypt
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Options
+}
+
+type Argon2Options struct {
+	// Time is the number of passes over Memory, which is in KiB
+	Time       uint32
+	Memory     uint32
+	Threads    uint8
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+// DefaultOptions follow the second recommended option of RFC 9106 with fewer threads
+var DefaultOptions = Options{
+	Algorithm:  Argon2id,
+	BcryptCost: bcrypt.DefaultCost,
+	Argon2: Argon2Options{
+		Time:       3,
+		Memory:     64 * 1024,
+		Threads:    2,
+		SaltLength: 16,
+		KeyLength:  32,
+	},
+}
+
+// bcryptMaxLength is the number of bytes bcrypt hashes, it would ignore the rest
+const bcryptMaxLength = 72
+
+type Hasher struct {
+	mu   sync.RWMutex
+	opts Options
+}
+
+func New(opts Options) (*Hasher, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return &Hasher{opts: opts}, nil
+}
+
+// SetOptions applies to the next hashes, invalid opts are not applied
+func (h *Hasher) SetOptions(opts Options) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.opts = opts
+	return nil
+}
+
+func (h *Hasher) Options() Options {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.opts
+}
+
+// Hash returns ErrTooLong if the password is longer than 72 bytes and the algorithm is bcrypt
+func (h *Hasher) Hash(password string) (string, error) {
+	opts := h.Options()
+
+	if opts.Algorithm == Bcrypt {
+		if len(password) > bcryptMaxLength {
+			return "", ErrTooLong
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), opts.BcryptCost)
+		return string(hash), err
+	}
+
+	salt := make([]byte, opts.Argon2.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	params := argon2Params{
+		version: argon2.Version,
+		memory:  opts.Argon2.Memory,
+		time:    opts.Argon2.Time,
+		threads: opts.Argon2.Threads,
+		salt:    salt,
+	}
+	params.key = params.derive(password, opts.Argon2.KeyLength)
+
+	return params.String(), nil
+}
+
+// Verify returns ErrMismatch if the password is wrong, it is nil if the password matches
+func (h *Hasher) Verify(hash, password string) error {
+	switch {
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatch
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidHash, err)
+		}
+		return nil
+	case strings.HasPrefix(hash, "$"+Argon2id+"$"):
+		params, err := parseArgon2(hash)
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare(params.key, params.derive(password, uint32(len(params.key)))) != 1 {
+			return ErrMismatch
+		}
+		return nil
+	}
+
+	return ErrUnknownHash
+}
+
+// NeedsRehash reports whether the hash has been made with other algorithm or parameters than the current ones
+func (h *Hasher) NeedsRehash(hash string) bool {
+	opts := h.Options()
+
+	if opts.Algorithm == Bcrypt {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return !isBcrypt(hash) || err != nil || cost != opts.BcryptCost
+	}
+
+	params, err := parseArgon2(hash)
+	return err != nil ||
+		params.version != argon2.Version ||
+		params.memory != opts.Argon2.Memory ||
+		params.time != opts.Argon2.Time ||
+		params.threads != opts.Argon2.Threads ||
+		uint32(len(params.salt)) != opts.Argon2.SaltLength ||
+		uint32(len(params.key)) != opts.Argon2.KeyLength
+}
+
+func (opts Options) validate() error {
+	switch opts.Algorithm {
+	case Bcrypt:
+		if opts.BcryptCost < bcrypt.MinCost || opts.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("%w: bcrypt cost must be from %d to %d", ErrInvalidOptions, bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case Argon2id:
+		a := opts.Argon2
+		if a.Time == 0 || a.Threads == 0 || a.Memory < 8*uint32(a.Threads) {
+			return fmt.Errorf("%w: argon2id needs at least one pass and thread and 8 KiB of memory per thread", ErrInvalidOptions)
+		}
+		if a.SaltLength < 8 || a.KeyLength < 16 {
+			return fmt.Errorf("%w: argon2id salt must be at least 8 bytes and key 16 bytes", ErrInvalidOptions)
+		}
+	default:
+		return fmt.Errorf("%w: unknown algorithm '%s'", ErrInvalidOptions, opts.Algorithm)
+	}
+	return nil
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// argon2Params is a hash in the PHC string format, $argon2id$v=19$m=65536,t=3,p=2$salt$key
+type argon2Params struct {
+	version int
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func (p argon2Params) derive(password string, keyLength uint32) []byte {
+	return argon2.IDKey([]byte(password), p.salt, p.time, p.memory, p.threads, keyLength)
+}
+
+func (p argon2Params) String() string {
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", Argon2id, p.version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(p.salt), base64.RawStdEncoding.EncodeToString(p.key))
+}
+
+func parseArgon2(hash string) (argon2Params, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != Argon2id {
+		return argon2Params{}, ErrInvalidHash
+	}
+
+	var p argon2Params
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &p.version); err != nil {
+		return argon2Params{}, fmt.Errorf("%w: %s", ErrInvalidHash, err)
+	}
+	if p.version != argon2.Version {
+		return argon2Params{}, fmt.Errorf("%w: unsupported version %d", ErrInvalidHash, p.version)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return argon2Params{}, fmt.Errorf("%w: %s", ErrInvalidHash, err)
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return argon2Params{}, fmt.Errorf("%w: %s", ErrInvalidHash, err)
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(p.key) == 0 {
+		return argon2Params{}, fmt.Errorf("%w: invalid key", ErrInvalidHash)
+	}
+	if p.time == 0 || p.threads == 0 {
+		return argon2Params{}, fmt.Errorf("%w: invalid parameters", ErrInvalidHash)
+	}
+
+	return p, nil
+}
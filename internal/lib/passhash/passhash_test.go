@@ -0,0 +1,89 @@
+package passhash_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/passhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fast keeps tests quick, they are far below what production needs
+var fast = passhash.Options{
+	Algorithm:  passhash.Argon2id,
+	BcryptCost: 4,
+	Argon2: passhash.Argon2Options{
+		Time:       1,
+		Memory:     64,
+		Threads:    1,
+		SaltLength: 16,
+		KeyLength:  32,
+	},
+}
+
+func TestHasher(t *testing.T) {
+	bcryptOpts := fast
+	bcryptOpts.Algorithm = passhash.Bcrypt
+	costlier := fast
+	costlier.Argon2.Time = 2
+
+	tests := []struct {
+		name   string
+		hashed passhash.Options
+		// current are options of the hasher when the hash is verified
+		current passhash.Options
+		prefix  string
+		rehash  bool
+	}{
+		{name: "argon2id", hashed: fast, current: fast, prefix: "$argon2id$v=19$m=64,t=1,p=1$", rehash: false},
+		{name: "bcrypt", hashed: bcryptOpts, current: bcryptOpts, prefix: "$2a$04$", rehash: false},
+		{name: "bcrypt to argon2id", hashed: bcryptOpts, current: fast, prefix: "$2a$", rehash: true},
+		{name: "argon2id to bcrypt", hashed: fast, current: bcryptOpts, prefix: "$argon2id$", rehash: true},
+		{name: "more passes", hashed: fast, current: costlier, prefix: "$argon2id$", rehash: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			h, err := passhash.New(tc.hashed)
+			require.NoError(tt, err)
+
+			hash, err := h.Hash("password")
+			require.NoError(tt, err)
+			assert.True(tt, strings.HasPrefix(hash, tc.prefix), hash)
+
+			require.NoError(tt, h.SetOptions(tc.current))
+			assert.NoError(tt, h.Verify(hash, "password"))
+			assert.ErrorIs(tt, h.Verify(hash, "wrong password"), passhash.ErrMismatch)
+			assert.Equal(tt, tc.rehash, h.NeedsRehash(hash))
+		})
+	}
+
+	t.Run("salted", func(tt *testing.T) {
+		h, _ := passhash.New(fast)
+		first, _ := h.Hash("password")
+		second, _ := h.Hash("password")
+		assert.NotEqual(tt, first, second)
+	})
+
+	t.Run("too long for bcrypt", func(tt *testing.T) {
+		h, _ := passhash.New(bcryptOpts)
+		_, err := h.Hash(strings.Repeat("a", 73))
+		assert.ErrorIs(tt, err, passhash.ErrTooLong)
+	})
+
+	t.Run("invalid hash", func(tt *testing.T) {
+		h, _ := passhash.New(fast)
+		assert.ErrorIs(tt, h.Verify("$argon2id$v=19$m=64$salt$key", "password"), passhash.ErrInvalidHash)
+		assert.ErrorIs(tt, h.Verify("plain", "password"), passhash.ErrUnknownHash)
+		assert.True(tt, h.NeedsRehash("plain"))
+	})
+
+	t.Run("invalid options", func(tt *testing.T) {
+		_, err := passhash.New(passhash.Options{Algorithm: "md5"})
+		assert.ErrorIs(tt, err, passhash.ErrInvalidOptions)
+
+		h, _ := passhash.New(fast)
+		assert.ErrorIs(tt, h.SetOptions(passhash.Options{Algorithm: passhash.Bcrypt, BcryptCost: 100}), passhash.ErrInvalidOptions)
+		assert.Equal(tt, fast, h.Options(), "invalid options are not applied")
+	})
+}
@@ -0,0 +1,108 @@
+package passpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidList = errors.New("invalid breached passwords list")
+
+// prefixLength is the number of hex digits of SHA-1 hashes a range is named after
+const prefixLength = 5
+
+// Breached is a list of SHA-1 hashes of breached passwords in the k-anonymity layout of the Pwned Passwords
+// range api. It is either a directory with a file of every range, named after the first 5 hex digits with or without .txt,
+// whose lines are the other 35 digits and the count, e.g. 0018A45C4D1DEF81644B54AB7F969B88D65:3,
+// or a single file of full hashes and counts, which is loaded in memory, so it should be small
+type Breached struct {
+	dir    string
+	hashes map[string]int
+}
+
+// LoadBreached reads the file at path or checks that the directory exists, ranges of a directory are read on every Count
+func LoadBreached(path string) (*Breached, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &Breached{dir: path}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hashes := make(map[string]int)
+	err = scanHashes(f, func(hash string, count int) {
+		hashes[hash] = count
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Breached{hashes: hashes}, nil
+}
+
+// Count is how many times the password has been seen in breaches, 0 if it is not in the list
+func (b *Breached) Count(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	if b.hashes != nil {
+		return b.hashes[hash], nil
+	}
+
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+	f, err := os.Open(filepath.Join(b.dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		f, err = os.Open(filepath.Join(b.dir, prefix))
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// a partial list has no files of some ranges
+		return 0, nil
+	case err != nil:
+		return 0, err
+	}
+	defer f.Close()
+
+	count := 0
+	err = scanHashes(f, func(hash string, c int) {
+		if hash == suffix {
+			count = c
+		}
+	})
+	return count, err
+}
+
+// scanHashes calls fn with every hash in upper case, a line without a count is counted once
+func scanHashes(r io.Reader, fn func(hash string, count int)) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		hash, countStr, found := strings.Cut(line, ":")
+		count := 1
+		if found {
+			var err error
+			if count, err = strconv.Atoi(countStr); err != nil {
+				return fmt.Errorf("%w: line '%s'", ErrInvalidList, line)
+			}
+		}
+		fn(strings.ToUpper(hash), count)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,134 @@
+// Package passpolicy tells what is wrong with new passwords: they may be short,
+// easy to guess (see Estimate), contain the name or the email of the user or be in a list of breached ones
+package passpolicy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var ErrInvalidOptions = errors.New("invalid password policy options")
+
+type Options struct {
+	// MinLength is in characters
+	MinLength int
+	// MinEntropy is in bits, see Estimate
+	MinEntropy float64
+	// RejectPersonal rejects passwords that contain the name, the lastname or the email of the user
+	RejectPersonal bool
+	// BreachedPath is a file or a directory of breached passwords, see Breached. The check is off if it is empty
+	BreachedPath string
+	// BreachedMinCount is how many times a password must have been seen in breaches to be rejected
+	BreachedMinCount int
+}
+
+// DefaultOptions reject about what 10^10 guesses would find
+var DefaultOptions = Options{
+	MinLength:        8,
+	MinEntropy:       32,
+	RejectPersonal:   true,
+	BreachedMinCount: 1,
+}
+
+// UserInfo is what an attacker may know about the user
+type UserInfo struct {
+	Name     string
+	Lastname string
+	Email    string
+}
+
+type Policy struct {
+	mu       sync.RWMutex
+	opts     Options
+	breached *Breached
+}
+
+func New(opts Options) (*Policy, error) {
+	p := &Policy{}
+	if err := p.SetOptions(opts); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SetOptions loads the breached list if its path has changed, invalid opts or a list that cannot be loaded are not applied
+func (p *Policy) SetOptions(opts Options) error {
+	if opts.MinLength < 0 || opts.MinEntropy < 0 || opts.BreachedMinCount < 1 {
+		return fmt.Errorf("%w: lengths must not be negative and a password is breached after at least 1 breach", ErrInvalidOptions)
+	}
+
+	p.mu.RLock()
+	current, breached := p.opts, p.breached
+	p.mu.RUnlock()
+
+	switch {
+	case opts.BreachedPath == "":
+		breached = nil
+	case opts.BreachedPath != current.BreachedPath || breached == nil:
+		var err error
+		if breached, err = LoadBreached(opts.BreachedPath); err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.opts, p.breached = opts, breached
+	return nil
+}
+
+func (p *Policy) Options() Options {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.opts
+}
+
+// Check returns messages of what is wrong with the password, none if it is accepted.
+// If the breached list cannot be read, the error is returned along with the messages of the other checks
+func (p *Policy) Check(password string, user UserInfo) ([]string, error) {
+	p.mu.RLock()
+	opts, breached := p.opts, p.breached
+	p.mu.RUnlock()
+
+	var msgs []string
+	if utf8.RuneCountInString(password) < opts.MinLength {
+		msgs = append(msgs, fmt.Sprintf("too short, must be at least %d characters", opts.MinLength))
+	}
+
+	local, _, _ := strings.Cut(user.Email, "@")
+	if opts.RejectPersonal {
+		normalized := normalize(password)
+		if containsAny(normalized, user.Name, user.Lastname) {
+			msgs = append(msgs, "must not contain your name")
+		}
+		if containsAny(normalized, local) {
+			msgs = append(msgs, "must not contain your email")
+		}
+	}
+
+	if Estimate(password, user.Name, user.Lastname, local, user.Email).Entropy < opts.MinEntropy {
+		msgs = append(msgs, "too easy to guess, avoid common words, names, dates, sequences and repeated characters")
+	}
+
+	if breached == nil {
+		return msgs, nil
+	}
+	count, err := breached.Count(password)
+	if err == nil && count >= opts.BreachedMinCount {
+		msgs = append(msgs, "has appeared in a data breach, choose another one")
+	}
+	return msgs, err
+}
+
+// containsAny reports whether normalized contains one of inputs, too short ones are ignored
+func containsAny(normalized string, inputs ...string) bool {
+	for _, input := range inputs {
+		if input = normalize(input); utf8.RuneCountInString(input) >= minMatchLength && strings.Contains(normalized, input) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package passpolicy_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/passpolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		pattern  string
+		weak     bool
+	}{
+		{name: "common word", password: "password1", pattern: passpolicy.PatternDictionary, weak: true},
+		{name: "substitutions", password: "P@ssw0rd!", pattern: passpolicy.PatternDictionary, weak: true},
+		{name: "sequence", password: "abcdefgh", pattern: passpolicy.PatternSequence, weak: true},
+		{name: "keyboard", password: "zxcvbnm!", pattern: passpolicy.PatternKeyboard, weak: true},
+		{name: "repeat", password: "x7Kx7Kx7K", pattern: passpolicy.PatternRepeat, weak: true},
+		{name: "year", password: "summer2024", pattern: passpolicy.PatternYear, weak: true},
+		{name: "personal", password: "Smirnov1990", pattern: passpolicy.PatternPersonal, weak: true},
+		{name: "random", password: "kq8ZmvT2", pattern: passpolicy.PatternBruteforce},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			strength := passpolicy.Estimate(tc.password, "Alexander", "Smirnov")
+
+			assert.Equal(tt, tc.weak, strength.Entropy < passpolicy.DefaultOptions.MinEntropy, "entropy %f", strength.Entropy)
+			tokens := make([]string, 0, len(strength.Matches))
+			patterns := make([]string, 0, len(strength.Matches))
+			for _, m := range strength.Matches {
+				tokens, patterns = append(tokens, m.Token), append(patterns, m.Pattern)
+			}
+			assert.Equal(tt, tc.password, strings.Join(tokens, ""))
+			assert.Contains(tt, patterns, tc.pattern)
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	dir := t.TempDir()
+	hash := sha1Hex("kq8ZmvT2")
+	lines := "0018A45C4D1DEF81644B54AB7F969B88D65:3\n" + hash[5:] + ":12\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(lines), 0o600))
+
+	user := passpolicy.UserInfo{Name: "Alexander", Lastname: "Smirnov", Email: "sasha.s@example.com"}
+
+	tests := []struct {
+		name     string
+		opts     func(*passpolicy.Options)
+		password string
+		msgs     int
+	}{
+		{name: "accepted", password: "hjfkqpwn3V"},
+		{name: "short", opts: func(o *passpolicy.Options) { o.MinLength = 12 }, password: "hjfkqpwn3V", msgs: 1},
+		{name: "weak", password: "qwerty123", msgs: 1},
+		{name: "name", opts: func(o *passpolicy.Options) { o.MinEntropy = 0 }, password: "Alex4nder-hjkq", msgs: 1},
+		{name: "email", opts: func(o *passpolicy.Options) { o.MinEntropy = 0 }, password: "my-sasha.s-hjkq", msgs: 1},
+		{name: "personal allowed", opts: func(o *passpolicy.Options) { o.MinEntropy, o.RejectPersonal = 0, false }, password: "Alex4nder-hjkq"},
+		{name: "breached", password: "kq8ZmvT2", msgs: 1},
+		{name: "breached less than min count", opts: func(o *passpolicy.Options) { o.BreachedMinCount = 13 }, password: "kq8ZmvT2"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			opts := passpolicy.DefaultOptions
+			opts.BreachedPath = dir
+			if tc.opts != nil {
+				tc.opts(&opts)
+			}
+			policy, err := passpolicy.New(opts)
+			require.NoError(tt, err)
+
+			msgs, err := policy.Check(tc.password, user)
+			require.NoError(tt, err)
+			assert.Len(tt, msgs, tc.msgs, msgs)
+		})
+	}
+
+	t.Run("single file", func(tt *testing.T) {
+		path := filepath.Join(tt.TempDir(), "breached.txt")
+		require.NoError(tt, os.WriteFile(path, []byte(strings.ToLower(hash)+":1\n"), 0o600))
+
+		breached, err := passpolicy.LoadBreached(path)
+		require.NoError(tt, err)
+		count, err := breached.Count("kq8ZmvT2")
+		require.NoError(tt, err)
+		assert.Equal(tt, 1, count)
+	})
+
+	t.Run("invalid options", func(tt *testing.T) {
+		_, err := passpolicy.New(passpolicy.Options{BreachedMinCount: 0})
+		assert.ErrorIs(tt, err, passpolicy.ErrInvalidOptions)
+		_, err = passpolicy.New(passpolicy.Options{BreachedMinCount: 1, BreachedPath: filepath.Join(dir, "missing")})
+		assert.Error(tt, err)
+	})
+}
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,354 @@
+package passpolicy
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	PatternDictionary = "dictionary"
+	PatternPersonal   = "personal"
+	PatternSequence   = "sequence"
+	PatternKeyboard   = "keyboard"
+	PatternRepeat     = "repeat"
+	PatternYear       = "year"
+	PatternBruteforce = "bruteforce"
+)
+
+// Match is a part of a password that is guessed as a whole
+type Match struct {
+	Pattern string
+	// Token is the part as it is in the password
+	Token string
+	Bits  float64
+}
+
+// Strength is what Estimate knows about a password
+type Strength struct {
+	// Entropy is log2 of the guesses needed by an attacker who tries the patterns first
+	Entropy float64
+	// Matches cover the password in order
+	Matches []Match
+}
+
+const (
+	// minMatchLength is the shortest word, sequence or repeat that is matched
+	minMatchLength = 3
+	// maxMatched runes are matched with patterns, the rest of a longer password is bruteforce
+	maxMatched = 100
+)
+
+//go:embed words.txt
+var wordsList string
+
+// words are common passwords and words by their rank, the first one is the most common
+var words = func() map[string]int {
+	words := make(map[string]int)
+	for i, word := range strings.Fields(wordsList) {
+		if _, ok := words[word]; !ok {
+			words[word] = i + 1
+		}
+	}
+	return words
+}()
+
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// leet are substitutions people make in words, they are undone before matching
+var leet = map[rune]rune{
+	'4': 'a', '@': 'a', '3': 'e', '1': 'i', '!': 'i', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't',
+}
+
+type candidate struct {
+	start, end int
+	pattern    string
+	bits       float64
+}
+
+// Estimate finds words, sequences, keyboard rows, repeats and years in the password
+// and picks the ones that make it the easiest to guess, the other characters are bruteforced.
+// userInputs are what an attacker may know about the user, e.g. the name, they are cheaper than any word
+func Estimate(password string, userInputs ...string) Strength {
+	runes := []rune(password)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	byEnd := make(map[int][]candidate)
+	for _, c := range findAll(runes, lower, personal(userInputs)) {
+		byEnd[c.end] = append(byEnd[c.end], c)
+	}
+
+	charBits := math.Log2(float64(charsetSize(runes)))
+
+	// best[i] is the fewest bits to guess runes[:i], from[i] is the match that ends there
+	best := make([]float64, len(runes)+1)
+	from := make([]*candidate, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		best[i] = best[i-1] + charBits
+		for j := range byEnd[i] {
+			c := &byEnd[i][j]
+			if bits := best[c.start] + c.bits; bits < best[i] {
+				best[i], from[i] = bits, c
+			}
+		}
+	}
+
+	var matches []Match
+	for i := len(runes); i > 0; {
+		c := from[i]
+		if c == nil {
+			start := i - 1
+			for start > 0 && from[start] == nil {
+				start--
+			}
+			c = &candidate{start: start, end: i, pattern: PatternBruteforce, bits: float64(i-start) * charBits}
+		}
+		matches = append(matches, Match{Pattern: c.pattern, Token: string(runes[c.start:c.end]), Bits: c.bits})
+		i = c.start
+	}
+	for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+		matches[i], matches[j] = matches[j], matches[i]
+	}
+
+	return Strength{Entropy: best[len(runes)], Matches: matches}
+}
+
+func findAll(runes, lower []rune, inputs map[string]bool) []candidate {
+	if len(runes) > maxMatched {
+		runes, lower = runes[:maxMatched], lower[:maxMatched]
+	}
+	unleeted := make([]rune, len(lower))
+	for i, r := range lower {
+		unleeted[i] = unleet(r)
+	}
+
+	var found []candidate
+	found = append(found, findWords(runes, lower, unleeted, inputs)...)
+	found = append(found, findSequences(lower)...)
+	found = append(found, findKeyboard(lower)...)
+	found = append(found, findRepeats(lower)...)
+	found = append(found, findYears(lower)...)
+	return found
+}
+
+func findWords(runes, lower, unleeted []rune, inputs map[string]bool) []candidate {
+	inputBits := math.Log2(float64(len(inputs) + 1))
+
+	var found []candidate
+	for i := range lower {
+		for j := i + minMatchLength; j <= len(lower); j++ {
+			bits, pattern := math.Inf(1), ""
+			for k, word := range []string{string(lower[i:j]), string(unleeted[i:j])} {
+				// a substitution doubles the guesses
+				substituted := float64(k)
+				if inputs[word] && inputBits+substituted < bits {
+					bits, pattern = inputBits+substituted, PatternPersonal
+				}
+				if rank, ok := words[word]; ok && math.Log2(float64(rank+1))+substituted < bits {
+					bits, pattern = math.Log2(float64(rank+1))+substituted, PatternDictionary
+				}
+			}
+			if pattern != "" {
+				found = append(found, candidate{start: i, end: j, pattern: pattern, bits: bits + caseBits(runes[i:j])})
+			}
+		}
+	}
+	return found
+}
+
+// findSequences finds runs of letters or digits with the step of 1 or -1, e.g. abcd or 9876
+func findSequences(lower []rune) []candidate {
+	var found []candidate
+	for i := 0; i < len(lower)-1; {
+		step := lower[i+1] - lower[i]
+		j := i + 1
+		for (step == 1 || step == -1) && j < len(lower) && lower[j]-lower[j-1] == step && sameClass(lower[i], lower[j]) {
+			j++
+		}
+		if j-i >= minMatchLength {
+			start := math.Log2(float64(classSize(lower[i])))
+			// the obvious starts are tried first
+			if strings.ContainsRune("az019", lower[i]) {
+				start = 1
+			}
+			bits := start + math.Log2(float64(j-i))
+			if step == -1 {
+				bits++
+			}
+			found = append(found, candidate{start: i, end: j, pattern: PatternSequence, bits: bits})
+			i = j - 1
+			continue
+		}
+		i++
+	}
+	return found
+}
+
+// findKeyboard finds adjacent keys of a row of the qwerty layout in both directions, e.g. qwer or lkjh
+func findKeyboard(lower []rune) []candidate {
+	rowBits := math.Log2(float64(2 * len(keyboardRows) * 10))
+
+	var found []candidate
+	for _, row := range keyboardRows {
+		reversed := []rune(row)
+		for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+			reversed[i], reversed[j] = reversed[j], reversed[i]
+		}
+		for _, keys := range []string{row, string(reversed)} {
+			for i := 0; i < len(lower); {
+				j := i + 1
+				for j < len(lower) && strings.Contains(keys, string(lower[i:j+1])) {
+					j++
+				}
+				if j-i >= minMatchLength {
+					found = append(found, candidate{start: i, end: j, pattern: PatternKeyboard, bits: rowBits + math.Log2(float64(j-i))})
+					i = j
+					continue
+				}
+				i++
+			}
+		}
+	}
+	return found
+}
+
+// findRepeats finds a character or a part repeated one after another, e.g. aaaa or x7kx7k.
+// Repeated words are cheap anyway, so the repeated part is bruteforced
+func findRepeats(lower []rune) []candidate {
+	var found []candidate
+	for i := range lower {
+		for period := 1; i+2*period <= len(lower); period++ {
+			times := 1
+			for end := i + (times+1)*period; end <= len(lower) && string(lower[end-period:end]) == string(lower[i:i+period]); end += period {
+				times++
+			}
+			if times < 2 || times*period < minMatchLength {
+				continue
+			}
+			bits := float64(period)*math.Log2(float64(charsetSize(lower[i:i+period]))) + math.Log2(float64(times))
+			found = append(found, candidate{start: i, end: i + times*period, pattern: PatternRepeat, bits: bits})
+		}
+	}
+	return found
+}
+
+// findYears finds years from 1900 to 2099
+func findYears(lower []rune) []candidate {
+	yearBits := math.Log2(200)
+
+	var found []candidate
+	for i := 0; i+4 <= len(lower); i++ {
+		year := string(lower[i : i+4])
+		if (strings.HasPrefix(year, "19") || strings.HasPrefix(year, "20")) && isDigit(lower[i+2]) && isDigit(lower[i+3]) {
+			found = append(found, candidate{start: i, end: i + 4, pattern: PatternYear, bits: yearBits})
+		}
+	}
+	return found
+}
+
+// personal are the inputs as they are matched, too short ones would match anything
+func personal(userInputs []string) map[string]bool {
+	inputs := make(map[string]bool, len(userInputs))
+	for _, input := range userInputs {
+		if input = normalize(input); len([]rune(input)) >= minMatchLength {
+			inputs[input] = true
+		}
+	}
+	return inputs
+}
+
+// caseBits are the guesses of which letters are capitalized, the first one or all of them are tried first
+func caseBits(token []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 0
+	case lower == 0 || upper == 1 && unicode.IsUpper(token[0]):
+		return 1
+	}
+
+	variants := 0.0
+	for k := 1; k <= min(upper, lower); k++ {
+		variants += binomial(upper+lower, k)
+	}
+	return math.Log2(variants)
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+// charsetSize is the number of characters of the classes that are in runes
+func charsetSize(runes []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case isDigit(r):
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	size := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.present {
+			size += class.size
+		}
+	}
+	return max(size, 1)
+}
+
+func classSize(r rune) int {
+	if isDigit(r) {
+		return 10
+	}
+	return 26
+}
+
+func sameClass(a, b rune) bool {
+	return isDigit(a) && isDigit(b) || a >= 'a' && a <= 'z' && b >= 'a' && b <= 'z'
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func unleet(r rune) rune {
+	if l, ok := leet[r]; ok {
+		return l
+	}
+	return r
+}
+
+// normalize lowers s and undoes substitutions, so p@ssw0rd contains password
+func normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		return unleet(unicode.ToLower(r))
+	}, s)
+}
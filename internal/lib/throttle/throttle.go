@@ -0,0 +1,189 @@
+// Package throttle slows down repeated failures of the same key,
+// e.g. sign in attempts of an email or from an IP
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// Options of a Limiter. Zero delays never block, so zero Options disable it
+type Options struct {
+	// FreeAttempts are failures allowed before delays start
+	FreeAttempts int
+	// BaseDelay follows the first failure over FreeAttempts, each next one doubles it up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutAttempts failures in a row lock the key for Lockout, 0 disables lockout
+	LockoutAttempts int
+	Lockout         time.Duration
+	// Window is how long failures are remembered after the last one
+	Window time.Duration
+}
+
+// sweepInterval is how often Limiter forgets idle keys
+const sweepInterval = time.Minute
+
+// Limiter keeps failures in memory, so it suits a single instance
+type Limiter struct {
+	mu        sync.Mutex
+	opts      Options
+	entries   map[string]entry
+	lastSweep time.Time
+}
+
+type entry struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+	// inFlight are attempts started with Begin and not ended yet
+	inFlight int
+}
+
+func New(opts Options) *Limiter {
+	return &Limiter{
+		opts:      opts,
+		entries:   make(map[string]entry),
+		lastSweep: time.Now(),
+	}
+}
+
+// SetOptions applies to the next failures, current blocks are kept
+func (l *Limiter) SetOptions(opts Options) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.opts = opts
+}
+
+func (l *Limiter) Options() Options {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.opts
+}
+
+// Blocked returns how long the key has to wait before the next attempt, 0 if it may try now
+func (l *Limiter) Blocked(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		return 0
+	}
+	return max(time.Until(e.blockedUntil), 0)
+}
+
+// Begin starts an attempt of the key, it returns how long the key has to wait if the attempt is not allowed.
+// Attempts in progress are counted as failures, so parallel ones cannot all start before the first one fails.
+// An allowed attempt must be ended with End
+func (l *Limiter) Begin(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	e := l.entries[key]
+	if wait := e.blockedUntil.Sub(now); wait > 0 {
+		return wait
+	}
+	failures := e.failures
+	if now.Sub(e.lastFailure) > l.opts.Window {
+		failures = 0
+	}
+	if e.inFlight > 0 {
+		if delay := l.delay(failures + e.inFlight); delay > 0 {
+			return delay
+		}
+	}
+
+	e.inFlight++
+	l.entries[key] = e
+	return 0
+}
+
+// End finishes an attempt started with Begin, a failed one is recorded as Fail does
+func (l *Limiter) End(key string, failed bool) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if e.inFlight > 0 {
+		e.inFlight--
+	}
+	switch {
+	case failed:
+		return l.fail(key, e)
+	case !ok:
+		// the key has been reset during the attempt
+	case e.failures == 0 && e.inFlight == 0:
+		delete(l.entries, key)
+	default:
+		l.entries[key] = e
+	}
+	return 0
+}
+
+// Fail records a failure and returns how long the key is blocked after it
+func (l *Limiter) Fail(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fail(key, l.entries[key])
+}
+
+// fail records a failure of e, l.mu must be held
+func (l *Limiter) fail(key string, e entry) time.Duration {
+	now := time.Now()
+	l.sweep(now)
+
+	if now.Sub(e.lastFailure) > l.opts.Window {
+		e = entry{inFlight: e.inFlight}
+	}
+	e.failures++
+	e.lastFailure = now
+
+	delay := l.delay(e.failures)
+	e.blockedUntil = now.Add(delay)
+	l.entries[key] = e
+
+	return delay
+}
+
+// Reset forgets failures of the key
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
+
+// delay after the failure, l.mu must be held
+func (l *Limiter) delay(failures int) time.Duration {
+	var delay time.Duration
+	if over := failures - l.opts.FreeAttempts; over > 0 && l.opts.BaseDelay > 0 {
+		delay = l.opts.BaseDelay
+		for i := 1; i < over && (l.opts.MaxDelay == 0 || delay < l.opts.MaxDelay); i++ {
+			delay *= 2
+		}
+		if l.opts.MaxDelay > 0 {
+			delay = min(delay, l.opts.MaxDelay)
+		}
+	}
+
+	if l.opts.LockoutAttempts > 0 && failures >= l.opts.LockoutAttempts {
+		delay = max(delay, l.opts.Lockout)
+	}
+	return delay
+}
+
+// sweep forgets keys which are neither blocked, remembered nor attempted, l.mu must be held
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	for key, e := range l.entries {
+		if e.inFlight == 0 && now.After(e.blockedUntil) && now.Sub(e.lastFailure) > l.opts.Window {
+			delete(l.entries, key)
+		}
+	}
+	l.lastSweep = now
+}
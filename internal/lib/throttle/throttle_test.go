@@ -0,0 +1,83 @@
+package throttle_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/throttle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	opts := throttle.Options{
+		FreeAttempts:    2,
+		BaseDelay:       time.Second,
+		MaxDelay:        4 * time.Second,
+		LockoutAttempts: 7,
+		Lockout:         time.Hour,
+		Window:          time.Hour,
+	}
+
+	tests := []struct {
+		name     string
+		opts     throttle.Options
+		failures int
+		delay    time.Duration
+	}{
+		{name: "free", opts: opts, failures: 2, delay: 0},
+		{name: "first delay", opts: opts, failures: 3, delay: time.Second},
+		{name: "doubled", opts: opts, failures: 4, delay: 2 * time.Second},
+		{name: "max delay", opts: opts, failures: 6, delay: 4 * time.Second},
+		{name: "lockout", opts: opts, failures: 7, delay: time.Hour},
+		{name: "disabled", opts: throttle.Options{}, failures: 100, delay: 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			l := throttle.New(tc.opts)
+
+			var delay time.Duration
+			for range tc.failures {
+				delay = l.Fail("key")
+			}
+			assert.Equal(tt, tc.delay, delay)
+
+			blocked := l.Blocked("key")
+			assert.LessOrEqual(tt, blocked, tc.delay)
+			assert.Equal(tt, tc.delay > 0, blocked > 0)
+			assert.Zero(tt, l.Blocked("another key"))
+
+			l.Reset("key")
+			assert.Zero(tt, l.Blocked("key"))
+		})
+	}
+}
+
+func TestLimiterAttempts(t *testing.T) {
+	l := throttle.New(throttle.Options{
+		FreeAttempts: 2,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		Window:       time.Hour,
+	})
+
+	// parallel attempts are allowed while their failures would not block the key
+	assert.Zero(t, l.Begin("key"))
+	assert.Zero(t, l.Begin("key"))
+	assert.Zero(t, l.Begin("key"))
+	assert.Positive(t, l.Begin("key"))
+	assert.Zero(t, l.Begin("another key"))
+
+	assert.Zero(t, l.End("key", false))
+	assert.Zero(t, l.End("key", true))
+	assert.Zero(t, l.Begin("key"))
+	assert.Positive(t, l.Begin("key"))
+
+	assert.Zero(t, l.End("key", true))
+	assert.Positive(t, l.End("key", true))
+	assert.Positive(t, l.Begin("key"))
+
+	l.Reset("key")
+	assert.Zero(t, l.Begin("key"))
+	assert.Zero(t, l.End("key", false))
+	assert.Zero(t, l.Blocked("key"))
+}
@@ -0,0 +1,101 @@
+package tokens
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Purpose of an action token is its typ header,
+// so it is never accepted as a token of another purpose or kind
+type Purpose string
+
+const (
+	PurposeVerifyEmail   Purpose = "verify-email+jwt"
+	PurposeResetPassword Purpose = "reset-password+jwt"
+	// PurposeTwoFactor is a sign in challenge, exchanged for a Pair with a second factor
+	PurposeTwoFactor Purpose = "2fa-challenge+jwt"
+)
+
+// Action tokens are sent by email or returned as challenges and signed with access keys.
+// They become invalid once used, because Email or Rotation no longer match the user
+type Action struct {
+	Purpose Purpose
+	Id      uint64
+	Email   string
+	// Rotation is the token generation of the user, it is bumped when the password changes
+	Rotation uint64
+	Jti      string
+	IssuedAt int64
+	Exp      int64
+}
+
+type ActionString string
+
+func (a *Action) Sign(ttl time.Duration) (ActionString, error) {
+	return a.SignWith(AccessKeys, ttl)
+}
+
+func (a *Action) SignWith(keys *KeySet, ttl time.Duration) (ActionString, error) {
+	jti, err := NewJti()
+	if err != nil {
+		return "", err
+	}
+	a.Jti = jti
+	a.IssuedAt = time.Now().Unix()
+	a.Exp = time.Now().Add(ttl).Unix()
+
+	tknstr, err := keys.signedString(string(a.Purpose), AccessRegistered.claims(jwt.MapClaims{
+		"email": a.Email,
+		"rtr":   a.Rotation,
+	}, standard{Subject: a.Id, Jti: a.Jti, IssuedAt: a.IssuedAt, Exp: a.Exp}))
+	if err != nil {
+		return "", err
+	}
+
+	return ActionString(tknstr), nil
+}
+
+func (a ActionString) ParseVerify(purpose Purpose) (*Action, error) {
+	return a.ParseVerifyWith(AccessKeys, purpose)
+}
+
+func (a ActionString) ParseVerifyWith(keys *KeySet, purpose Purpose) (*Action, error) {
+	token, err := keys.parse(string(a), string(purpose))
+	if errors.Is(err, ErrWrongType) {
+		return nil, ErrWrongType
+	}
+	if err != nil {
+		return nil, ErrUnexpectedSigningMethod
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		std, err := AccessRegistered.verify(claims)
+		if err != nil {
+			return nil, err
+		}
+
+		email, ok := claims["email"].(string)
+		if !ok {
+			return nil, ErrInvalidPayload
+		}
+
+		rotation, ok := claims["rtr"].(float64)
+		if !ok {
+			return nil, ErrInvalidPayload
+		}
+
+		return &Action{
+			Purpose:  purpose,
+			Id:       std.Subject,
+			Email:    email,
+			Rotation: uint64(rotation),
+			Jti:      std.Jti,
+			IssuedAt: std.IssuedAt,
+			Exp:      std.Exp,
+		}, nil
+	}
+
+	return nil, ErrUnknown
+}
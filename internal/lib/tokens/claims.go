@@ -0,0 +1,115 @@
+package tokens
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	ErrNotYetValid = errors.New("token is not valid yet")
+	ErrWrongType   = errors.New("wrong token type")
+)
+
+// Registered claims are set for every token of a kind and required when it is verified.
+// Empty ones are neither set nor checked
+type Registered struct {
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew between instances when exp, nbf and iat are checked
+	Leeway time.Duration
+}
+
+var (
+	AccessRegistered  Registered
+	RefreshRegistered Registered
+)
+
+// AcceptedUntil is when a token that expires at exp is no longer accepted, so a denied token must stay denied until then.
+// exp is checked in whole seconds, hence the extra second
+func (reg Registered) AcceptedUntil(exp time.Time) time.Time {
+	return exp.Add(reg.Leeway + time.Second)
+}
+
+// typ headers keep a token of one kind from being accepted as another one,
+// even if both are signed with the same key. See RFC 9068
+const (
+	accessType  = "at+jwt"
+	refreshType = "rt+jwt"
+)
+
+// standard holds claims of every token, sub is the user id
+type standard struct {
+	Subject  uint64
+	Jti      string
+	IssuedAt int64
+	Exp      int64
+}
+
+// claims adds registered and standard claims to private ones, the token is valid since it is issued
+func (reg Registered) claims(private jwt.MapClaims, std standard) jwt.MapClaims {
+	private["sub"] = strconv.FormatUint(std.Subject, 10)
+	private["jti"] = std.Jti
+	private["iat"] = std.IssuedAt
+	private["nbf"] = std.IssuedAt
+	private["exp"] = std.Exp
+	if reg.Issuer != "" {
+		private["iss"] = reg.Issuer
+	}
+	if reg.Audience != "" {
+		private["aud"] = reg.Audience
+	}
+	return private
+}
+
+func (reg Registered) verify(claims jwt.MapClaims) (standard, error) {
+	now := time.Now().Unix()
+	leeway := int64(reg.Leeway / time.Second)
+
+	exp, ok := claims["exp"].(float64)
+	if !ok || int64(exp)+leeway < now {
+		return standard{}, ErrExpired
+	}
+
+	nbf, ok := claims["nbf"].(float64)
+	if !ok {
+		return standard{}, ErrInvalidPayload
+	}
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return standard{}, ErrInvalidPayload
+	}
+	if int64(nbf)-leeway > now || int64(iat)-leeway > now {
+		return standard{}, ErrNotYetValid
+	}
+
+	if reg.Issuer != "" && !claims.VerifyIssuer(reg.Issuer, true) {
+		return standard{}, ErrInvalidPayload
+	}
+	if reg.Audience != "" && !claims.VerifyAudience(reg.Audience, true) {
+		return standard{}, ErrInvalidPayload
+	}
+
+	sub, ok := claims["sub"].(string)
+	if !ok {
+		return standard{}, ErrInvalidPayload
+	}
+	id, err := strconv.ParseUint(sub, 10, 64)
+	if err != nil {
+		return standard{}, ErrInvalidPayload
+	}
+
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return standard{}, ErrInvalidPayload
+	}
+
+	return standard{
+		Subject:  id,
+		Jti:      jti,
+		IssuedAt: int64(iat),
+		Exp:      int64(exp),
+	}, nil
+}
@@ -0,0 +1,116 @@
+package tokens
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Denylist keeps revoked access tokens by jti until they would expire anyway.
+// All tokens of a user issued up to some moment can be denied at once, e.g. when the user is suspended.
+// MemoryDenylist suits a single instance, a shared store is needed for several ones
+type Denylist interface {
+	Deny(ctx context.Context, jti string, until time.Time) error
+	IsDenied(ctx context.Context, jti string) (bool, error)
+	// DenyUser denies tokens of the user issued not later than issuedBefore
+	DenyUser(ctx context.Context, userId uint64, issuedBefore, until time.Time) error
+	IsUserDenied(ctx context.Context, userId uint64, issuedAt int64) (bool, error)
+}
+
+// sweepInterval is how often MemoryDenylist forgets expired entries
+const sweepInterval = time.Minute
+
+type MemoryDenylist struct {
+	mu        sync.Mutex
+	entries   map[string]time.Time
+	users     map[uint64]userDenial
+	lastSweep time.Time
+}
+
+type userDenial struct {
+	// issuedBefore is unix seconds as iat of tokens
+	issuedBefore int64
+	until        time.Time
+}
+
+var _ Denylist = (*MemoryDenylist)(nil)
+
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{
+		entries:   make(map[string]time.Time),
+		users:     make(map[uint64]userDenial),
+		lastSweep: time.Now(),
+	}
+}
+
+func (d *MemoryDenylist) Deny(ctx context.Context, jti string, until time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.sweep(now)
+
+	if until.After(now) {
+		d.entries[jti] = until
+	}
+
+	return nil
+}
+
+func (d *MemoryDenylist) IsDenied(ctx context.Context, jti string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	until, ok := d.entries[jti]
+	return ok && time.Now().Before(until), nil
+}
+
+func (d *MemoryDenylist) DenyUser(ctx context.Context, userId uint64, issuedBefore, until time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.sweep(now)
+
+	if !until.After(now) {
+		return nil
+	}
+	// a later denial covers an earlier one
+	denial := d.users[userId]
+	if issuedBefore.Unix() > denial.issuedBefore {
+		denial.issuedBefore = issuedBefore.Unix()
+	}
+	if until.After(denial.until) {
+		denial.until = until
+	}
+	d.users[userId] = denial
+
+	return nil
+}
+
+func (d *MemoryDenylist) IsUserDenied(ctx context.Context, userId uint64, issuedAt int64) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	denial, ok := d.users[userId]
+	return ok && issuedAt <= denial.issuedBefore && time.Now().Before(denial.until), nil
+}
+
+// sweep forgets expired entries once in sweepInterval, d.mu must be locked
+func (d *MemoryDenylist) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < sweepInterval {
+		return
+	}
+
+	for key, expiresAt := range d.entries {
+		if !now.Before(expiresAt) {
+			delete(d.entries, key)
+		}
+	}
+	for userId, denial := range d.users {
+		if !now.Before(denial.until) {
+			delete(d.users, userId)
+		}
+	}
+	d.lastSweep = now
+}
@@ -0,0 +1,185 @@
+package tokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"sync"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	ErrUnsupportedKey = errors.New("only RSA and Ed25519 private keys are supported")
+	ErrNoKey          = errors.New("no signing key")
+	ErrUnknownKey     = errors.New("unknown key id")
+)
+
+// validMethods are accepted by parsers, the key found by kid must use the same one anyway
+var validMethods = []string{
+	jwt.SigningMethodHS256.Alg(),
+	jwt.SigningMethodRS256.Alg(),
+	jwt.SigningMethodEdDSA.Alg(),
+}
+
+// Key signs tokens, Id is put into the kid header
+type Key struct {
+	Id     string
+	Method jwt.SigningMethod
+
+	sign   any
+	verify any
+}
+
+// ParseKey makes an RS256 or EdDSA key of a PEM encoded private key
+// (PKCS #8 or PKCS #1 for RSA). Anything that is not PEM is an HS256 secret
+func ParseKey(secret []byte) (*Key, error) {
+	block, _ := pem.Decode(secret)
+	if block == nil {
+		return &Key{
+			Id:     keyId(secret),
+			Method: jwt.SigningMethodHS256,
+			sign:   secret,
+			verify: secret,
+		}, nil
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if private, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, ErrUnsupportedKey
+		}
+	}
+
+	key := &Key{sign: private}
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.verify = jwt.SigningMethodRS256, &private.PublicKey
+	case ed25519.PrivateKey:
+		key.Method, key.verify = jwt.SigningMethodEdDSA, private.Public()
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key.verify)
+	if err != nil {
+		return nil, err
+	}
+	key.Id = keyId(der)
+
+	return key, nil
+}
+
+// keyId is derived from the key, so it is the same on every instance
+// and after restarts. Secrets are hashed and cannot be recovered from it
+func keyId(material []byte) string {
+	sum := sha256.Sum256(material)
+	return hex.EncodeToString(sum[:8])
+}
+
+// KeySet signs with the current key and verifies with both the current and the previous one,
+// so tokens signed before a rotation stay valid until they expire.
+// The zero value is ready to use, but signs nothing until Set
+type KeySet struct {
+	mu       sync.RWMutex
+	current  *Key
+	previous *Key
+}
+
+var (
+	AccessKeys  = &KeySet{}
+	RefreshKeys = &KeySet{}
+)
+
+// Set replaces the keys, previous may be nil or the same as current
+func (ks *KeySet) Set(current, previous *Key) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if previous != nil && previous.Id == current.Id {
+		previous = nil
+	}
+	ks.current, ks.previous = current, previous
+}
+
+// Rotate makes the key current and keeps the current one for verification
+func (ks *KeySet) Rotate(key *Key) {
+	ks.Set(key, ks.Current())
+}
+
+func (ks *KeySet) Current() *Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.current
+}
+
+func (ks *KeySet) Key(kid string) (*Key, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	for _, key := range []*Key{ks.current, ks.previous} {
+		if key != nil && key.Id == kid {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+func (ks *KeySet) keys() []*Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	keys := make([]*Key, 0, 2)
+	for _, key := range []*Key{ks.current, ks.previous} {
+		if key != nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (ks *KeySet) signedString(typ string, claims jwt.MapClaims) (string, error) {
+	key := ks.Current()
+	if key == nil {
+		return "", ErrNoKey
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.Id
+	token.Header["typ"] = typ
+
+	return token.SignedString(key.sign)
+}
+
+func (ks *KeySet) parse(tokenString, typ string) (*jwt.Token, error) {
+	parser := jwt.Parser{
+		SkipClaimsValidation: true,
+		ValidMethods:         validMethods,
+	}
+	token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if token.Header["typ"] != typ {
+			return nil, ErrWrongType
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, ok := ks.Key(kid)
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, ErrUnexpectedSigningMethod
+		}
+		return key.verify, nil
+	})
+
+	// errors of the key func are wrapped without Unwrap
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Inner != nil {
+		return token, validationErr.Inner
+	}
+	return token, err
+}
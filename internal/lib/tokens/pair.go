@@ -1,20 +1,36 @@
 package tokens
 
-import "github.com/Onnywrite/tinkoff-prod/internal/models"
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+)
 
 type Pair struct {
-	Access  AccessString  `json:"access"`
-	Refresh RefreshString `json:"refresh"`
+	Access  AccessString  `json:"access,omitempty"`
+	Refresh RefreshString `json:"refresh,omitempty"`
 }
 
-func NewPair(usr *models.User, rotation uint64) (Pair, error) {
+// NewPair signs tokens for the user, sessionId becomes the jti of the refresh token
+// and the access token gets its own one, see NewJti
+func NewPair(usr *models.User, rotation uint64, sessionId string) (Pair, error) {
+	accessJti, err := NewJti()
+	if err != nil {
+		return Pair{}, err
+	}
+
 	access := Access{
-		Id:    usr.Id,
-		Email: usr.Email,
+		Id:        usr.Id,
+		Email:     usr.Email,
+		Jti:       accessJti,
+		SessionId: sessionId,
+		Role:      usr.Role,
 	}
 	refresh := Refresh{
 		Id:       usr.Id,
 		Rotation: rotation,
+		Jti:      sessionId,
 	}
 
 	accessStr, err := access.Sign()
@@ -32,3 +48,12 @@ func NewPair(usr *models.User, rotation uint64) (Pair, error) {
 		Refresh: refreshStr,
 	}, nil
 }
+
+// NewJti generates a random id of a token
+func NewJti() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,32 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// PersonalPrefix tells personal access tokens from JWTs in the Authorization header
+const PersonalPrefix = "tpat_"
+
+// NewPersonal generates a personal access token and its hash.
+// Only the hash is stored, the token is shown to the user once
+func NewPersonal() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = PersonalPrefix + hex.EncodeToString(b)
+	return token, HashPersonal(token), nil
+}
+
+// HashPersonal is SHA-256, tokens are random enough not to need a slow hash
+func HashPersonal(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func IsPersonal(token string) bool {
+	return strings.HasPrefix(token, PersonalPrefix)
+}
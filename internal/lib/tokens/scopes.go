@@ -0,0 +1,34 @@
+package tokens
+
+import "slices"
+
+// Scope limits what a personal access token can do.
+// Access tokens of sessions have every scope
+type Scope string
+
+const (
+	ScopeProfileRead   Scope = "profile:read"
+	ScopeProfileWrite  Scope = "profile:write"
+	ScopeFeedRead      Scope = "feed:read"
+	ScopeFeedWrite     Scope = "feed:write"
+	ScopeLikesWrite    Scope = "likes:write"
+	ScopeCommentsWrite Scope = "comments:write"
+	ScopeFollowsWrite  Scope = "follows:write"
+	// ScopeAccount guards credentials and sessions, so it is never granted to personal tokens
+	ScopeAccount Scope = "account"
+)
+
+// PersonalScopes can be granted to personal access tokens
+var PersonalScopes = []Scope{
+	ScopeProfileRead,
+	ScopeProfileWrite,
+	ScopeFeedRead,
+	ScopeFeedWrite,
+	ScopeLikesWrite,
+	ScopeCommentsWrite,
+	ScopeFollowsWrite,
+}
+
+func IsPersonalScope(scope string) bool {
+	return slices.Contains(PersonalScopes, Scope(scope))
+}
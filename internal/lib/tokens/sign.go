@@ -3,43 +3,51 @@ package tokens
 import (
 	"time"
 
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/golang-jwt/jwt"
 )
 
 var (
-	AccessSecret  []byte
-	RefreshSecret []byte
-	AccessTTL     time.Duration = 0
-	RefreshTTL    time.Duration = 0
+	AccessTTL  time.Duration = 0
+	RefreshTTL time.Duration = 0
 )
 
 type Access struct {
 	Id    uint64
 	Email string
-	Exp   int64
+	// Jti is unique for every access token, so it can be denied alone
+	Jti string
+	// SessionId is the jti of the refresh token of the pair
+	SessionId string
+	// Role is the role of the user when the token was issued, it changes on refresh
+	Role     models.Role
+	IssuedAt int64
+	Exp      int64
 }
 
 type Refresh struct {
 	Id       uint64
 	Rotation uint64
+	// Jti identifies the token in sessions, every refresh issues a new one
+	Jti      string
+	IssuedAt int64
 	Exp      int64
 }
 
 func (a *Access) Sign() (AccessString, error) {
-	return a.SignSecret(AccessSecret)
+	return a.SignWith(AccessKeys)
 }
 
-func (a *Access) SignSecret(secret []byte) (AccessString, error) {
+func (a *Access) SignWith(keys *KeySet) (AccessString, error) {
+	a.IssuedAt = time.Now().Unix()
 	if AccessTTL != 0 {
 		a.Exp = time.Now().Add(AccessTTL).Unix()
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":    a.Id,
+	tknstr, err := keys.signedString(accessType, AccessRegistered.claims(jwt.MapClaims{
 		"email": a.Email,
-		"exp":   a.Exp,
-	})
-
-	tknstr, err := token.SignedString(secret)
+		"sid":   a.SessionId,
+		"role":  a.Role,
+	}, standard{Subject: a.Id, Jti: a.Jti, IssuedAt: a.IssuedAt, Exp: a.Exp}))
 	if err != nil {
 		return "", err
 	}
@@ -48,20 +56,17 @@ func (a *Access) SignSecret(secret []byte) (AccessString, error) {
 }
 
 func (r *Refresh) Sign() (RefreshString, error) {
-	return r.SignSecret(RefreshSecret)
+	return r.SignWith(RefreshKeys)
 }
 
-func (r *Refresh) SignSecret(secret []byte) (RefreshString, error) {
+func (r *Refresh) SignWith(keys *KeySet) (RefreshString, error) {
+	r.IssuedAt = time.Now().Unix()
 	if AccessTTL != 0 {
 		r.Exp = time.Now().Add(RefreshTTL).Unix()
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":  r.Id,
-		"exp": r.Exp,
+	tknstr, err := keys.signedString(refreshType, RefreshRegistered.claims(jwt.MapClaims{
 		"rtr": r.Rotation,
-	})
-
-	tknstr, err := token.SignedString(secret)
+	}, standard{Subject: r.Id, Jti: r.Jti, IssuedAt: r.IssuedAt, Exp: r.Exp}))
 	if err != nil {
 		return "", err
 	}
@@ -1,11 +1,19 @@
 package tokens_test
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"testing"
 	"time"
 
 	"github.com/Onnywrite/tinkoff-prod/internal/lib/tokens"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAccess(t *testing.T) {
@@ -13,17 +21,19 @@ func TestAccess(t *testing.T) {
 		name   string
 		access tokens.Access
 		err    error
-		secret []byte
+		keys   *tokens.KeySet
 	}{
 		{
 			name: "success",
 			access: tokens.Access{
 				Id:    1,
 				Email: "email@email.com",
+				Jti:   "0123456789abcdef",
+				Role:  models.RoleModerator,
 				Exp:   time.Now().Add(time.Hour).Unix(),
 			},
-			err:    nil,
-			secret: []byte("secret"),
+			err:  nil,
+			keys: hsKeys(),
 		},
 		{
 			name: "expired",
@@ -32,15 +42,15 @@ func TestAccess(t *testing.T) {
 				Email: "email@email.com",
 				Exp:   0,
 			},
-			err:    tokens.ErrExpired,
-			secret: []byte("secret"),
+			err:  tokens.ErrExpired,
+			keys: hsKeys(),
 		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(tt *testing.T) {
-			got, _ := tc.access.SignSecret(tc.secret)
+			got, _ := tc.access.SignWith(tc.keys)
 
-			access, err := got.ParseVerifySecret(tc.secret)
+			access, err := got.ParseVerifyWith(tc.keys)
 			if tc.err != nil {
 				assert.EqualError(tt, err, tc.err.Error())
 				return
@@ -57,16 +67,17 @@ func TestRefresh(t *testing.T) {
 		name    string
 		refresh tokens.Refresh
 		err     error
-		secret  []byte
+		keys    *tokens.KeySet
 	}{
 		{
 			name: "success",
 			refresh: tokens.Refresh{
 				Id:  1,
+				Jti: "0123456789abcdef",
 				Exp: time.Now().Add(time.Hour).Unix(),
 			},
-			err:    nil,
-			secret: []byte("secret"),
+			err:  nil,
+			keys: hsKeys(),
 		},
 		{
 			name: "expired",
@@ -74,15 +85,15 @@ func TestRefresh(t *testing.T) {
 				Id:  1,
 				Exp: 0,
 			},
-			err:    tokens.ErrExpired,
-			secret: []byte("secret"),
+			err:  tokens.ErrExpired,
+			keys: hsKeys(),
 		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(tt *testing.T) {
-			got, _ := tc.refresh.SignSecret(tc.secret)
+			got, _ := tc.refresh.SignWith(tc.keys)
 
-			access, err := got.ParseVerifySecret(tc.secret)
+			access, err := got.ParseVerifyWith(tc.keys)
 			if tc.err != nil {
 				assert.EqualError(tt, err, tc.err.Error())
 				return
@@ -93,3 +104,241 @@ func TestRefresh(t *testing.T) {
 		})
 	}
 }
+
+func TestAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  tokens.Action
+		ttl     time.Duration
+		purpose tokens.Purpose
+		err     error
+	}{
+		{
+			name:    "success",
+			action:  tokens.Action{Purpose: tokens.PurposeVerifyEmail, Id: 1, Email: "email@email.com", Rotation: 2},
+			ttl:     time.Hour,
+			purpose: tokens.PurposeVerifyEmail,
+		},
+		{
+			name:    "another purpose",
+			action:  tokens.Action{Purpose: tokens.PurposeVerifyEmail, Id: 1, Email: "email@email.com"},
+			ttl:     time.Hour,
+			purpose: tokens.PurposeResetPassword,
+			err:     tokens.ErrWrongType,
+		},
+		{
+			name:    "expired",
+			action:  tokens.Action{Purpose: tokens.PurposeResetPassword, Id: 1, Email: "email@email.com"},
+			ttl:     -time.Hour,
+			purpose: tokens.PurposeResetPassword,
+			err:     tokens.ErrExpired,
+		},
+	}
+	keys := hsKeys()
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			got, err := tc.action.SignWith(keys, tc.ttl)
+			require.NoError(tt, err)
+
+			action, err := got.ParseVerifyWith(keys, tc.purpose)
+			if tc.err != nil {
+				assert.ErrorIs(tt, err, tc.err)
+				return
+			}
+			assert.NoError(tt, err)
+			assert.Equal(tt, tc.action, *action)
+		})
+	}
+
+	t.Run("access as action", func(tt *testing.T) {
+		access := tokens.Access{Id: 1, Email: "email@email.com", Jti: "fedcba9876543210", Exp: time.Now().Add(time.Hour).Unix()}
+		accessStr, err := access.SignWith(keys)
+		require.NoError(tt, err)
+
+		_, err = tokens.ActionString(accessStr).ParseVerifyWith(keys, tokens.PurposeVerifyEmail)
+		assert.ErrorIs(tt, err, tokens.ErrWrongType)
+	})
+}
+
+func TestMemoryDenylist(t *testing.T) {
+	ctx := context.Background()
+	d := tokens.NewMemoryDenylist()
+
+	assert.NoError(t, d.Deny(ctx, "denied", time.Now().Add(time.Hour)))
+	assert.NoError(t, d.Deny(ctx, "expired", time.Now().Add(-time.Second)))
+
+	tests := []struct {
+		name   string
+		jti    string
+		denied bool
+	}{
+		{name: "denied", jti: "denied", denied: true},
+		{name: "expired", jti: "expired", denied: false},
+		{name: "unknown", jti: "unknown", denied: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			denied, err := d.IsDenied(ctx, tc.jti)
+			assert.NoError(tt, err)
+			assert.Equal(tt, tc.denied, denied)
+		})
+	}
+
+	suspendedAt := time.Now()
+	assert.NoError(t, d.DenyUser(ctx, 1, suspendedAt, suspendedAt.Add(time.Hour)))
+	assert.NoError(t, d.DenyUser(ctx, 2, suspendedAt, suspendedAt.Add(-time.Second)))
+
+	users := []struct {
+		name     string
+		userId   uint64
+		issuedAt int64
+		denied   bool
+	}{
+		{name: "issued before", userId: 1, issuedAt: suspendedAt.Add(-time.Minute).Unix(), denied: true},
+		{name: "issued after", userId: 1, issuedAt: suspendedAt.Add(time.Minute).Unix(), denied: false},
+		{name: "expired", userId: 2, issuedAt: suspendedAt.Add(-time.Minute).Unix(), denied: false},
+		{name: "unknown", userId: 3, issuedAt: suspendedAt.Unix(), denied: false},
+	}
+	for _, tc := range users {
+		t.Run("user "+tc.name, func(tt *testing.T) {
+			denied, err := d.IsUserDenied(ctx, tc.userId, tc.issuedAt)
+			assert.NoError(tt, err)
+			assert.Equal(tt, tc.denied, denied)
+		})
+	}
+}
+
+func hsKeys() *tokens.KeySet {
+	key, _ := tokens.ParseKey([]byte("secret"))
+	keys := &tokens.KeySet{}
+	keys.Set(key, nil)
+	return keys
+}
+
+func pemKey(t *testing.T, private any) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestKeySet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		secret []byte
+		alg    string
+		jwks   int
+	}{
+		{name: "hs256", secret: []byte("secret"), alg: "HS256", jwks: 0},
+		{name: "rs256", secret: pemKey(t, rsaKey), alg: "RS256", jwks: 1},
+		{name: "eddsa", secret: pemKey(t, edKey), alg: "EdDSA", jwks: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			key, err := tokens.ParseKey(tc.secret)
+			require.NoError(tt, err)
+			assert.Equal(tt, tc.alg, key.Method.Alg())
+
+			keys := &tokens.KeySet{}
+			keys.Set(key, nil)
+			assert.Len(tt, keys.JWKS().Keys, tc.jwks)
+
+			access := tokens.Access{Id: 1, Email: "email@email.com", Jti: "0123456789abcdef", Exp: time.Now().Add(time.Hour).Unix()}
+			signed, err := access.SignWith(keys)
+			require.NoError(tt, err)
+
+			// the previous key still verifies
+			next, _ := tokens.ParseKey([]byte("next secret"))
+			keys.Rotate(next)
+			got, err := signed.ParseVerifyWith(keys)
+			assert.NoError(tt, err)
+			assert.Equal(tt, access, *got)
+
+			// but not after one more rotation
+			last, _ := tokens.ParseKey([]byte("last secret"))
+			keys.Rotate(last)
+			_, err = signed.ParseVerifyWith(keys)
+			assert.Error(tt, err)
+		})
+	}
+}
+
+func TestClaims(t *testing.T) {
+	defer func(access, refresh tokens.Registered) {
+		tokens.AccessRegistered, tokens.RefreshRegistered = access, refresh
+	}(tokens.AccessRegistered, tokens.RefreshRegistered)
+
+	keys := hsKeys()
+	registered := tokens.Registered{Issuer: "issuer", Audience: "audience", Leeway: time.Minute}
+	tokens.RefreshRegistered = registered
+
+	refresh := tokens.Refresh{Id: 1, Jti: "0123456789abcdef", Exp: time.Now().Add(time.Hour).Unix()}
+	refreshStr, err := refresh.SignWith(keys)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		exp      time.Duration
+		signed   tokens.Registered
+		verified tokens.Registered
+		err      error
+	}{
+		{name: "success", exp: time.Hour, signed: registered, verified: registered},
+		{name: "expired within leeway", exp: -30 * time.Second, signed: registered, verified: registered},
+		{name: "expired", exp: -2 * time.Minute, signed: registered, verified: registered, err: tokens.ErrExpired},
+		{name: "wrong issuer", exp: time.Hour, signed: registered,
+			verified: tokens.Registered{Issuer: "another", Audience: "audience"}, err: tokens.ErrInvalidPayload},
+		{name: "wrong audience", exp: time.Hour, signed: registered,
+			verified: tokens.Registered{Issuer: "issuer", Audience: "another"}, err: tokens.ErrInvalidPayload},
+		{name: "missing audience", exp: time.Hour, signed: tokens.Registered{Issuer: "issuer"},
+			verified: registered, err: tokens.ErrInvalidPayload},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			tokens.AccessRegistered = tc.signed
+			access := tokens.Access{
+				Id:        1,
+				Email:     "email@email.com",
+				Jti:       "fedcba9876543210",
+				SessionId: refresh.Jti,
+				Exp:       time.Now().Add(tc.exp).Unix(),
+			}
+			accessStr, err := access.SignWith(keys)
+			require.NoError(tt, err)
+
+			tokens.AccessRegistered = tc.verified
+			got, err := accessStr.ParseVerifyWith(keys)
+			if tc.err != nil {
+				assert.ErrorIs(tt, err, tc.err)
+				return
+			}
+			assert.NoError(tt, err)
+			assert.Equal(tt, access, *got)
+		})
+	}
+
+	t.Run("accepted until", func(tt *testing.T) {
+		exp := time.Now().Add(-30 * time.Second)
+		assert.True(tt, registered.AcceptedUntil(exp).After(time.Now()))
+		assert.True(tt, registered.AcceptedUntil(exp).Before(time.Now().Add(time.Minute)))
+	})
+	t.Run("refresh as access", func(tt *testing.T) {
+		asAccess := tokens.AccessString(refreshStr)
+		_, err := asAccess.ParseVerifyWith(keys)
+		assert.ErrorIs(tt, err, tokens.ErrWrongType)
+	})
+	t.Run("access as refresh", func(tt *testing.T) {
+		tokens.AccessRegistered = registered
+		access := tokens.Access{Id: 1, Email: "email@email.com", Jti: "fedcba9876543210", Exp: time.Now().Add(time.Hour).Unix()}
+		accessStr, err := access.SignWith(keys)
+		require.NoError(tt, err)
+
+		asRefresh := tokens.RefreshString(accessStr)
+		_, err = asRefresh.ParseVerifyWith(keys)
+		assert.ErrorIs(tt, err, tokens.ErrWrongType)
+	})
+}
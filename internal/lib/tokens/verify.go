@@ -3,8 +3,8 @@ package tokens
 import (
 	"bytes"
 	"errors"
-	"time"
 
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/golang-jwt/jwt"
 )
 
@@ -20,42 +20,48 @@ var (
 )
 
 func (a *AccessString) ParseVerify() (*Access, error) {
-	return a.ParseVerifySecret(AccessSecret)
+	return a.ParseVerifyWith(AccessKeys)
 }
 
-func (a *AccessString) ParseVerifySecret(secret []byte) (*Access, error) {
-	parser := jwt.Parser{
-		SkipClaimsValidation: true,
-		ValidMethods:         []string{"HS256"},
+func (a *AccessString) ParseVerifyWith(keys *KeySet) (*Access, error) {
+	token, err := keys.parse(string(*a), accessType)
+	if errors.Is(err, ErrWrongType) {
+		return nil, ErrWrongType
 	}
-	token, err := parser.Parse(string(*a), func(token *jwt.Token) (interface{}, error) {
-		return secret, nil
-	})
-
 	if err != nil {
 		return nil, ErrUnexpectedSigningMethod
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		exp, ok := claims["exp"].(float64)
-		if !ok || exp < float64(time.Now().Unix()) {
-			return nil, ErrExpired
+		std, err := AccessRegistered.verify(claims)
+		if err != nil {
+			return nil, err
 		}
 
-		id, ok := claims["id"].(float64)
+		email, ok := claims["email"].(string)
 		if !ok {
 			return nil, ErrInvalidPayload
 		}
 
-		email, ok := claims["email"].(string)
+		sid, ok := claims["sid"].(string)
 		if !ok {
 			return nil, ErrInvalidPayload
 		}
 
+		// tokens issued before roles were introduced have no role
+		role := models.RoleUser
+		if claim, ok := claims["role"].(string); ok {
+			role = models.Role(claim)
+		}
+
 		return &Access{
-			Id:    uint64(id),
-			Email: email,
-			Exp:   int64(exp),
+			Id:        std.Subject,
+			Email:     email,
+			Jti:       std.Jti,
+			SessionId: sid,
+			Role:      role,
+			IssuedAt:  std.IssuedAt,
+			Exp:       std.Exp,
 		}, nil
 	}
 
@@ -63,31 +69,22 @@ func (a *AccessString) ParseVerifySecret(secret []byte) (*Access, error) {
 }
 
 func (a *RefreshString) ParseVerify() (*Refresh, error) {
-	return a.ParseVerifySecret(RefreshSecret)
+	return a.ParseVerifyWith(RefreshKeys)
 }
 
-func (a *RefreshString) ParseVerifySecret(secret []byte) (*Refresh, error) {
-	parser := jwt.Parser{
-		SkipClaimsValidation: true,
-		ValidMethods:         []string{"HS256"},
+func (a *RefreshString) ParseVerifyWith(keys *KeySet) (*Refresh, error) {
+	token, err := keys.parse(string(*a), refreshType)
+	if errors.Is(err, ErrWrongType) {
+		return nil, ErrWrongType
 	}
-	token, err := parser.Parse(string(*a), func(token *jwt.Token) (interface{}, error) {
-		return secret, nil
-	})
-
 	if err != nil {
 		return nil, ErrUnexpectedSigningMethod
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		exp, ok := claims["exp"].(float64)
-		if !ok || exp < float64(time.Now().Unix()) {
-			return nil, ErrExpired
-		}
-
-		id, ok := claims["id"].(float64)
-		if !ok {
-			return nil, ErrInvalidPayload
+		std, err := RefreshRegistered.verify(claims)
+		if err != nil {
+			return nil, err
 		}
 
 		rotation, ok := claims["rtr"].(float64)
@@ -96,9 +93,11 @@ func (a *RefreshString) ParseVerifySecret(secret []byte) (*Refresh, error) {
 		}
 
 		return &Refresh{
-			Id:       uint64(id),
-			Exp:      int64(exp),
+			Id:       std.Subject,
 			Rotation: uint64(rotation),
+			Jti:      std.Jti,
+			IssuedAt: std.IssuedAt,
+			Exp:      std.Exp,
 		}, nil
 	}
 
@@ -0,0 +1,51 @@
+package totp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// RecoveryCodes is how many codes are generated at once, each can be used instead of a password once
+const RecoveryCodes = 10
+
+var recoveryEncoding = strings.ToLower("ABCDEFGHIJKLMNOPQRSTUVWXYZ234567")
+
+// NewRecoveryCodes generates codes like "abcde-fghij" and their hashes, only hashes are stored
+func NewRecoveryCodes() (codes, hashes []string, err error) {
+	codes = make([]string, RecoveryCodes)
+	hashes = make([]string, RecoveryCodes)
+
+	for i := range codes {
+		random := make([]byte, 10)
+		if _, err = rand.Read(random); err != nil {
+			return nil, nil, err
+		}
+
+		code := make([]byte, 0, 11)
+		for j, b := range random {
+			if j == 5 {
+				code = append(code, '-')
+			}
+			code = append(code, recoveryEncoding[b&0x1f])
+		}
+
+		codes[i] = string(code)
+		hashes[i] = HashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode ignores case, dashes and spaces, so codes may be typed as the user likes
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsRecoveryCode tells recovery codes from TOTP codes, which are digits only
+func IsRecoveryCode(code string) bool {
+	return len(strings.NewReplacer("-", "", " ", "").Replace(code)) > Digits
+}
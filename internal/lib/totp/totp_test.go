@@ -0,0 +1,76 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCode(t *testing.T) {
+	// test vectors of RFC 6238 for SHA1, the last 6 of their 8 digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		name string
+		unix int64
+		code string
+	}{
+		{name: "59", unix: 59, code: "287082"},
+		{name: "1111111109", unix: 1111111109, code: "081804"},
+		{name: "1234567890", unix: 1234567890, code: "005924"},
+		{name: "2000000000", unix: 2000000000, code: "279037"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			code, err := totp.Code(secret, totp.Step(time.Unix(tc.unix, 0)))
+			require.NoError(tt, err)
+			assert.Equal(tt, tc.code, code)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := totp.NewSecret()
+	require.NoError(t, err)
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		at    time.Time
+		valid bool
+	}{
+		{name: "current", at: now, valid: true},
+		{name: "previous", at: now.Add(-totp.Period), valid: true},
+		{name: "next", at: now.Add(totp.Period), valid: true},
+		{name: "too old", at: now.Add(-3 * totp.Period), valid: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			code, err := totp.Code(secret, totp.Step(tc.at))
+			require.NoError(tt, err)
+
+			step, ok := totp.Validate(secret, code, now)
+			assert.Equal(tt, tc.valid, ok)
+			if ok {
+				assert.Equal(tt, totp.Step(tc.at), step)
+			}
+		})
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := totp.NewRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, totp.RecoveryCodes)
+
+	for i, code := range codes {
+		assert.Len(t, code, 11)
+		assert.True(t, totp.IsRecoveryCode(code))
+		assert.Equal(t, hashes[i], totp.HashRecoveryCode(code))
+	}
+	assert.False(t, totp.IsRecoveryCode("123 456"))
+}
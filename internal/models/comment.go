@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type Comment struct {
+	Id     uint64
+	PostId uint64
+	// ParentId is nil for comments to the post itself
+	ParentId  *uint64
+	Author    User
+	Content   string
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+	// DeletedAt is not nil if the comment has been deleted.
+	// Deleted comments are kept, so that their replies stay in the tree
+	DeletedAt *time.Time
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Follow means that posts of Followee are shown in Follower's timeline
+type Follow struct {
+	Follower   User
+	Followee   User
+	FollowedAt time.Time
+	// ApprovedAt is nil while the follow is a pending request to a private user
+	ApprovedAt *time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Identity links a user to an account at an OpenID Connect provider
+type Identity struct {
+	Id     uint64
+	UserId uint64
+	// Issuer and Subject identify the account, emails at providers may change
+	Issuer    string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
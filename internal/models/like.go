@@ -7,3 +7,13 @@ type Like struct {
 	Post    Post      `json:"post"`
 	LikedAt time.Time `json:"liked_at"`
 }
+
+// PostLikes is a summary of a post's likes
+type PostLikes struct {
+	PostId uint64
+	Count  uint64
+	// IsLiked tells whether the post has been liked by a specific user
+	IsLiked bool
+	// Top are the most recent likes
+	Top []Like
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// PersonalToken is a long-lived token of an integration, only its hash is stored
+type PersonalToken struct {
+	Id        uint64
+	UserId    uint64
+	Name      string
+	Hash      string
+	Scopes    []string
+	CreatedAt time.Time
+	// ExpiresAt is nil for tokens which never expire
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
@@ -15,6 +15,26 @@ type Post struct {
 	UpdatedAt   *time.Time  `json:"updated_at"`
 }
 
+// PostRevision is a previous version of an edited post
+type PostRevision struct {
+	Id         uint64
+	PostId     uint64
+	Content    string
+	ImagesUrls StringSlice
+	// CreatedAt is when this version was published or made by an edit
+	CreatedAt time.Time
+	// ReplacedAt is when this version was replaced by a newer one
+	ReplacedAt time.Time
+}
+
+// FoundPost is a post matched by a search query
+type FoundPost struct {
+	Post
+	Rank float64
+	// Headline is the HTML-escaped content with matched words wrapped in <b></b>
+	Headline string
+}
+
 type StringSlice []string
 
 func (s *StringSlice) Scan(src interface{}) error {
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// DeviceInfo describes the client a session has been started from
+type DeviceInfo struct {
+	UserAgent string
+	Ip        string
+}
+
+// Session is an issued refresh token. Tokens rotated from one sign in share FamilyId
+type Session struct {
+	Jti      string
+	FamilyId uint64
+	UserId   uint64
+	Device   DeviceInfo
+	// StartedAt is when the first token of the family has been issued
+	StartedAt time.Time
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	// UsedAt is set when the token has been exchanged for the next one
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Totp is the second factor of a user, Secret is sealed, see secretbox.Box
+type Totp struct {
+	UserId    uint64
+	Secret    string
+	CreatedAt time.Time
+	// EnabledAt is nil until the first code is confirmed
+	EnabledAt *time.Time
+	// LastStep is the step of the last accepted code, it cannot be used again
+	LastStep int64
+}
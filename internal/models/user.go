@@ -12,4 +12,37 @@ type User struct {
 	Image        string  `json:"image"`
 	PasswordHash string  `json:"password"`
 	Birthday     time.Time
+	// TokenGeneration must match rotation of refresh tokens, see tokens.Refresh
+	TokenGeneration uint64 `json:"token_generation"`
+	// EmailVerifiedAt is nil until the user follows the link sent to the email
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	Role            Role       `json:"role"`
+	// SuspendedAt is set by moderators, a suspended user cannot sign in
+	SuspendedAt *time.Time `json:"suspended_at"`
+}
+
+// Role grants access to the admin api, every role can do what lower ones can
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// Rank orders roles, unknown ones are below RoleUser
+func (r Role) Rank() int {
+	switch r {
+	case RoleUser:
+		return 1
+	case RoleModerator:
+		return 2
+	case RoleAdmin:
+		return 3
+	}
+	return 0
+}
+
+func (r Role) Valid() bool {
+	return r.Rank() != 0
 }
@@ -0,0 +1,159 @@
+package comments
+
+import (
+	"context"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+type CommentsOptions struct {
+	PostId   uint64
+	Page     uint64
+	PageSize uint64
+	// Tree pages root comments with all their replies nested.
+	// Otherwise all comments are paged in order of creation
+	Tree       bool
+	FormatDate func(time.Time) string
+}
+
+func (s *Service) Comments(ctx context.Context, opts CommentsOptions) (*Page, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "comments.Service.Comments").With("post_id", opts.PostId).With("page", opts.Page).With("page_size", opts.PageSize)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if eroErr := s.checkPost(ctx, logCtx, opts.PostId); eroErr != nil {
+		return nil, eroErr
+	}
+
+	offset, count := int(opts.Page-1)*int(opts.PageSize), int(opts.PageSize)
+
+	var (
+		commentsCh <-chan models.Comment
+		errCh      <-chan ero.Error
+		total      uint64
+		eroErr     ero.Error
+	)
+	if opts.Tree {
+		commentsCh, errCh = s.d.ListProvider.RootComments(ctx, offset, count, opts.PostId)
+		total, eroErr = s.d.Counter.RootCommentsNum(ctx, opts.PostId)
+	} else {
+		commentsCh, errCh = s.d.ListProvider.Comments(ctx, offset, count, opts.PostId)
+		total, eroErr = s.d.Counter.CommentsNum(ctx, opts.PostId)
+	}
+	if eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting comments count")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	comments, eroErr := s.collect(commentsCh, errCh, opts.FormatDate)
+	if eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting comments")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	if len(comments) == 0 {
+		s.log.DebugContext(logCtx.BuildContext(), "no comments")
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, ErrNoComments)
+	}
+
+	if opts.Tree {
+		if eroErr = s.attachReplies(ctx, comments, opts.FormatDate); eroErr != nil {
+			s.log.ErrorContext(eroErr.Context(ctx), "error while getting replies")
+			return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+		}
+	}
+
+	return &Page{
+		First:    1,
+		Current:  opts.Page,
+		Last:     (total + opts.PageSize - 1) / opts.PageSize,
+		Count:    total,
+		Comments: comments,
+	}, nil
+}
+
+// attachReplies gets all replies to roots and nests them into their parents
+func (s *Service) attachReplies(ctx context.Context, roots []*Comment, formatDate func(time.Time) string) ero.Error {
+	byId := make(map[uint64]*Comment, len(roots))
+	ids := make([]uint64, len(roots))
+	for i, c := range roots {
+		byId[c.Id] = c
+		ids[i] = c.Id
+	}
+
+	repliesCh, errCh := s.d.ListProvider.Replies(ctx, ids)
+	replies, eroErr := s.collect(repliesCh, errCh, formatDate)
+	if eroErr != nil {
+		return eroErr
+	}
+
+	// replies are ordered by creation, so parents always go before their replies
+	for _, r := range replies {
+		byId[r.Id] = r
+		if parent, ok := byId[*r.ParentId]; ok {
+			parent.Replies = append(parent.Replies, r)
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) collect(commentsCh <-chan models.Comment, errCh <-chan ero.Error, formatDate func(time.Time) string) ([]*Comment, ero.Error) {
+	comments := make([]*Comment, 0)
+	for c := range commentsCh {
+		comments = append(comments, newComment(c, formatDate))
+	}
+
+	if eroErr := <-errCh; eroErr != nil {
+		return nil, eroErr
+	}
+	return comments, nil
+}
+
+func newComment(c models.Comment, formatDate func(time.Time) string) *Comment {
+	comment := &Comment{
+		Id:        c.Id,
+		ParentId:  c.ParentId,
+		CreatedAt: formatDate(c.CreatedAt),
+	}
+
+	if c.DeletedAt != nil {
+		comment.Deleted = true
+		return comment
+	}
+
+	comment.Content = c.Content
+	comment.Author = &Author{
+		Id:       c.Author.Id,
+		Name:     c.Author.Name,
+		Lastname: c.Author.Lastname,
+		Image:    c.Author.Image,
+	}
+	if c.UpdatedAt != nil {
+		updatedAt := formatDate(*c.UpdatedAt)
+		comment.UpdatedAt = &updatedAt
+	}
+
+	return comment
+}
+
+// PostsCommentsNum counts not deleted comments of several posts at once.
+// Posts without comments are missing in the result
+func (s *Service) PostsCommentsNum(ctx context.Context, postIds []uint64) (map[uint64]uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "comments.Service.PostsCommentsNum").With("posts_count", len(postIds))
+
+	if len(postIds) == 0 {
+		return map[uint64]uint64{}, nil
+	}
+
+	counts, eroErr := s.d.PostsCounter.PostsCommentsNum(ctx, postIds)
+	if eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while counting comments of posts")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return counts, nil
+}
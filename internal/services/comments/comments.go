@@ -0,0 +1,68 @@
+package comments
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+)
+
+type Service struct {
+	log *slog.Logger
+
+	d Dependencies
+}
+
+type CommentSaver interface {
+	SaveComment(ctx context.Context, comment *models.Comment) (uint64, ero.Error)
+}
+
+type CommentProvider interface {
+	Comment(ctx context.Context, id uint64) (*models.Comment, ero.Error)
+}
+
+type CommentUpdater interface {
+	UpdateComment(ctx context.Context, comment *models.Comment) ero.Error
+}
+
+type CommentDeleter interface {
+	DeleteComment(ctx context.Context, id uint64) ero.Error
+}
+
+type CommentsProvider interface {
+	Comments(ctx context.Context, offset, count int, postId uint64) (<-chan models.Comment, <-chan ero.Error)
+	RootComments(ctx context.Context, offset, count int, postId uint64) (<-chan models.Comment, <-chan ero.Error)
+	Replies(ctx context.Context, rootIds []uint64) (<-chan models.Comment, <-chan ero.Error)
+}
+
+type CommentsCountProvider interface {
+	CommentsNum(ctx context.Context, postId uint64) (uint64, ero.Error)
+	RootCommentsNum(ctx context.Context, postId uint64) (uint64, ero.Error)
+}
+
+type PostsCommentsCountProvider interface {
+	PostsCommentsNum(ctx context.Context, postIds []uint64) (map[uint64]uint64, ero.Error)
+}
+
+type PostProvider interface {
+	Post(ctx context.Context, id uint64) (*models.Post, ero.Error)
+}
+
+type Dependencies struct {
+	Saver        CommentSaver
+	Provider     CommentProvider
+	Updater      CommentUpdater
+	Deleter      CommentDeleter
+	ListProvider CommentsProvider
+	Counter      CommentsCountProvider
+	PostsCounter PostsCommentsCountProvider
+	PostProvider PostProvider
+}
+
+func New(log *slog.Logger, deps Dependencies) *Service {
+	return &Service{
+		log: log,
+		d:   deps,
+	}
+}
@@ -0,0 +1,67 @@
+package comments
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (s *Service) Create(ctx context.Context, comment NewComment) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "comments.Service.Create").With("post_id", comment.PostId).With("author_id", comment.AuthorId)
+
+	if err := comment.Validate(); err != nil {
+		s.log.DebugContext(logCtx.BuildContext(), "comment hasn't passed validation")
+		return 0, err
+	}
+
+	if eroErr := s.checkPost(ctx, logCtx, comment.PostId); eroErr != nil {
+		return 0, eroErr
+	}
+
+	if comment.ParentId != nil {
+		parent, eroErr := s.d.Provider.Comment(ctx, *comment.ParentId)
+		switch {
+		case errors.Is(eroErr, storage.ErrNoRows) || eroErr == nil && parent.PostId != comment.PostId:
+			s.log.DebugContext(logCtx.BuildContext(), "parent comment not found")
+			return 0, ero.New(logCtx.Build(), ero.CodeNotFound, ErrParentNotFound)
+		case eroErr != nil:
+			s.log.ErrorContext(eroErr.Context(ctx), "error while getting parent comment")
+			return 0, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+		}
+	}
+
+	id, eroErr := s.d.Saver.SaveComment(ctx, &models.Comment{
+		PostId:   comment.PostId,
+		ParentId: comment.ParentId,
+		Author:   models.User{Id: comment.AuthorId},
+		Content:  *comment.Content,
+	})
+	switch {
+	case errors.Is(eroErr, storage.ErrForeignKeyConstraint):
+		s.log.DebugContext(logCtx.BuildContext(), "post or author not found")
+		return 0, ero.New(logCtx.WithParent(eroErr.Context(ctx)).Build(), ero.CodeNotFound, ErrPostNotFound)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while saving comment")
+		return 0, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return id, nil
+}
+
+// checkPost makes sure the post exists and is not deleted
+func (s *Service) checkPost(ctx context.Context, logCtx *erolog.ContextBuilder, postId uint64) ero.Error {
+	_, eroErr := s.d.PostProvider.Post(ctx, postId)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "post not found")
+		return ero.New(logCtx.WithParent(eroErr.Context(ctx)).Build(), ero.CodeNotFound, ErrPostNotFound)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting post")
+		return ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package comments
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+type EditOptions struct {
+	PostId    uint64
+	CommentId uint64
+	// UserId must be the author of the comment
+	UserId  uint64
+	Content *string
+}
+
+func (s *Service) Edit(ctx context.Context, opts EditOptions) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "comments.Service.Edit").With("comment_id", opts.CommentId).With("user_id", opts.UserId)
+
+	if err := validateContent(opts.Content); err != nil {
+		s.log.DebugContext(logCtx.BuildContext(), "comment hasn't passed validation")
+		return err
+	}
+
+	if eroErr := s.checkAuthor(ctx, logCtx, opts.PostId, opts.CommentId, opts.UserId); eroErr != nil {
+		return eroErr
+	}
+
+	eroErr := s.d.Updater.UpdateComment(ctx, &models.Comment{
+		Id:      opts.CommentId,
+		Content: *opts.Content,
+	})
+	return s.changeError(ctx, logCtx, eroErr)
+}
+
+// Delete hides content and author of the comment, its replies are kept
+func (s *Service) Delete(ctx context.Context, postId, commentId, userId uint64) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "comments.Service.Delete").With("comment_id", commentId).With("user_id", userId)
+
+	if eroErr := s.checkAuthor(ctx, logCtx, postId, commentId, userId); eroErr != nil {
+		return eroErr
+	}
+
+	return s.changeError(ctx, logCtx, s.d.Deleter.DeleteComment(ctx, commentId))
+}
+
+// checkAuthor makes sure the comment is under the post, is not deleted and userId is its author
+func (s *Service) checkAuthor(ctx context.Context, logCtx *erolog.ContextBuilder, postId, commentId, userId uint64) ero.Error {
+	comment, eroErr := s.d.Provider.Comment(ctx, commentId)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows) || eroErr == nil && (comment.PostId != postId || comment.DeletedAt != nil):
+		s.log.DebugContext(logCtx.BuildContext(), "comment not found")
+		return ero.New(logCtx.Build(), ero.CodeNotFound, ErrCommentNotFound)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting comment")
+		return ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	if comment.Author.Id != userId {
+		s.log.DebugContext(logCtx.BuildContext(), "user is not the author")
+		return ero.New(logCtx.Build(), ero.CodePermissionDenied, ErrNotAuthor)
+	}
+
+	return nil
+}
+
+func (s *Service) changeError(ctx context.Context, logCtx *erolog.ContextBuilder, eroErr ero.Error) ero.Error {
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "comment not found")
+		return ero.New(logCtx.WithParent(eroErr.Context(ctx)).Build(), ero.CodeNotFound, ErrCommentNotFound)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while changing comment")
+		return ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package comments
+
+import "errors"
+
+var (
+	ErrPostNotFound    = errors.New("post not found")
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrParentNotFound  = errors.New("parent comment not found")
+	ErrNotAuthor       = errors.New("only the author can change the comment")
+	ErrNoComments      = errors.New("post has no comments")
+	ErrInternal        = errors.New("internal error")
+)
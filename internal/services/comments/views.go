@@ -0,0 +1,86 @@
+package comments
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+type Author struct {
+	Id       uint64 `json:"id"`
+	Name     string `json:"name"`
+	Lastname string `json:"surname"`
+	Image    string `json:"image"`
+}
+
+type Comment struct {
+	Id       uint64  `json:"id"`
+	ParentId *uint64 `json:"parent_id"`
+	// Author is nil and Content is empty if the comment has been deleted
+	Author    *Author `json:"author"`
+	Content   string  `json:"content"`
+	Deleted   bool    `json:"deleted"`
+	CreatedAt string  `json:"created_at"`
+	UpdatedAt *string `json:"updated_at"`
+	// Replies are set only if comments are requested as a tree
+	Replies []*Comment `json:"replies,omitempty"`
+}
+
+type Page struct {
+	First   uint64 `json:"first"`
+	Current uint64 `json:"current"`
+	Last    uint64 `json:"last"`
+	// Count is the number of comments if they are flat or the number of root comments if they are a tree
+	Count    uint64     `json:"count"`
+	Comments []*Comment `json:"comments"`
+}
+
+type NewComment struct {
+	PostId   uint64
+	AuthorId uint64
+	// ParentId is the comment being replied, nil if the comment is to the post itself
+	ParentId *uint64
+	Content  *string
+}
+
+func (c NewComment) Validate() ero.Error {
+	return validateContent(c.Content)
+}
+
+func validateContent(content *string) ero.Error {
+	type fieldFault struct {
+		Field   string
+		Message string
+	}
+
+	spaceRegex := regexp.MustCompile(`^\s*$`)
+
+	faults := make([]fieldFault, 0, 1)
+
+	if content == nil || spaceRegex.MatchString(*content) {
+		faults = append(faults, fieldFault{
+			Field:   "content",
+			Message: "cannot be empty",
+		})
+	} else {
+		*content = strings.TrimSpace(*content)
+		if utf8.RuneCountInString(*content) > 1000 {
+			faults = append(faults, fieldFault{
+				Field:   "content",
+				Message: "too long, must be less than 1000 characters",
+			})
+		}
+	}
+
+	if len(faults) > 0 {
+		fields := make([]string, len(faults))
+		for i := range faults {
+			fields[i] = faults[i].Field
+		}
+		return ero.NewValidation(erolog.NewContextBuilder().With("fields", fields).Build(), faults)
+	}
+	return nil
+}
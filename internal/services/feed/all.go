@@ -2,109 +2,119 @@ package feed
 
 import (
 	"context"
-	"errors"
 	"time"
 
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/cursor"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/Onnywrite/tinkoff-prod/internal/services/likes"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
 )
 
 type AllFeedOptions struct {
-	Page       uint64
-	PageSize   uint64
+	Page     uint64
+	PageSize uint64
+	// Cursor is an opaque cursor from previous page. If set, Page is ignored
+	Cursor     string
 	UserId     uint64
 	LikesCount uint64
 	FormatDate func(time.Time) string
 }
 
-type postLikes struct {
-	likes   []likes.Like
-	count   uint64
-	isLiked bool
-}
+// getLikesForPosts gets likes of all posts of a page at once.
+// Posts without likes are missing in the map
+func (s *Service) getLikesForPosts(ctx context.Context, posts []models.Post, userId, maxCount uint64, formatDate func(time.Time) string) (map[uint64]likes.PostLikes, ero.Error) {
+	ids := make([]uint64, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].Id
+	}
 
-func (s *Service) getLikesForPost(ctx context.Context, postId, userId, maxCount uint64, formatDate func(time.Time) string) (info postLikes, err ero.Error) {
-	likesPage, eroErr := s.d.LikesProvider.Likes(ctx, likes.LikesOptions{
-		Page:       1,
-		PageSize:   maxCount,
-		PostId:     postId,
+	return s.d.LikesProvider.PostsLikes(ctx, likes.PostsLikesOptions{
+		PostIds:    ids,
+		UserId:     userId,
+		LikesCount: maxCount,
 		FormatDate: formatDate,
 	})
-	switch {
-	case errors.Is(eroErr, likes.ErrNoLikes):
-		return postLikes{
-			likes:   []likes.Like{},
-			count:   0,
-			isLiked: false,
-		}, nil
-	case eroErr != nil:
-		return postLikes{}, eroErr
-	}
-
-	isLiked := s.d.IsLikedProvider.IsLiked(ctx, userId, postId)
-	return postLikes{
-		likes:   likesPage.Likes,
-		count:   likesPage.Count,
-		isLiked: isLiked,
-	}, nil
+}
+
+// getCommentsForPosts counts comments of all posts of a page at once.
+// Posts without comments are missing in the map
+func (s *Service) getCommentsForPosts(ctx context.Context, posts []models.Post) (map[uint64]uint64, ero.Error) {
+	ids := make([]uint64, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].Id
+	}
+
+	return s.d.CommentsCounter.PostsCommentsNum(ctx, ids)
+}
+
+// likesOf is like likesMap[postId], but with non-nil Likes
+func likesOf(likesMap map[uint64]likes.PostLikes, postId uint64) likes.PostLikes {
+	info, ok := likesMap[postId]
+	if !ok || info.Likes == nil {
+		info.Likes = []likes.Like{}
+	}
+	return info
+}
+
+func postKey(p models.Post) (time.Time, uint64) {
+	return p.PublishedAt, p.Id
+}
+
+// currentPage is 0 when the page has been selected by cursor, because its number is unknown
+func currentPage(keyset *storage.Keyset, page uint64) uint64 {
+	if keyset != nil {
+		return 0
+	}
+	return page
+}
+
+// postsSource selects posts of a feed either by page number or by cursor
+type postsSource struct {
+	byOffset func(ctx context.Context, offset, count int) (<-chan models.Post, <-chan ero.Error)
+	byKeyset func(ctx context.Context, keyset storage.Keyset, count int) (<-chan models.Post, <-chan ero.Error)
+	count    func(ctx context.Context) (uint64, ero.Error)
 }
 
 // refactor: use dynamic schema (map[string]any) and decorator pattern
 func (s *Service) AllFeed(ctx context.Context, opts AllFeedOptions) (*PagedFeed, ero.Error) {
 	logCtx := erolog.NewContextBuilder().With("op", "feed.Service.AllFeed").With("page", opts.Page).With("page_size", opts.PageSize)
-	ctx, cancel := context.WithCancel(ctx)
 
+	return s.pagedFeed(ctx, logCtx, opts, postsSource{
+		byOffset: s.d.Provider.Posts,
+		byKeyset: s.d.Provider.PostsByKeyset,
+		count:    s.d.Counter.PostsNum,
+	})
+}
+
+func (s *Service) pagedFeed(ctx context.Context, logCtx *erolog.ContextBuilder, opts AllFeedOptions, src postsSource) (*PagedFeed, ero.Error) {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	postsCh, errCh := s.d.Provider.Posts(ctx, int(opts.Page-1)*int(opts.PageSize), int(opts.PageSize))
 
-	postsCount, eroErr := s.d.Counter.PostsNum(ctx)
+	keyset, err := cursor.Decode(opts.Cursor)
+	if err != nil {
+		s.log.DebugContext(logCtx.BuildContext(), "invalid cursor")
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeBadRequest, ErrInvalidCursor)
+	}
+
+	var postsCh <-chan models.Post
+	var errCh <-chan ero.Error
+	if keyset != nil {
+		postsCh, errCh = src.byKeyset(ctx, *keyset, int(opts.PageSize)+1)
+	} else {
+		postsCh, errCh = src.byOffset(ctx, int(opts.Page-1)*int(opts.PageSize), int(opts.PageSize)+1)
+	}
+
+	postsCount, eroErr := src.count(ctx)
 	if eroErr != nil {
 		s.log.ErrorContext(eroErr.Context(ctx), "internal error")
 		return nil, eroErr
 	}
 
-	posts := make([]LikedPost, 0, opts.PageSize)
+	selected := make([]models.Post, 0, opts.PageSize+1)
 	for p := range postsCh {
-		likesInfo, eroErr := s.getLikesForPost(ctx, p.Id, opts.UserId, opts.LikesCount, opts.FormatDate)
-		if eroErr != nil {
-			s.log.ErrorContext(eroErr.Context(ctx), "error while getting likes for post in feed")
-			continue
-		}
-
-		var url *string
-		if p.ImagesUrls == nil || len(p.ImagesUrls) == 0 {
-			url = nil
-		} else {
-			url = &p.ImagesUrls[0]
-		}
-
-		var updatedAt *string
-		if p.UpdatedAt != nil {
-			formatted := opts.FormatDate(*p.UpdatedAt)
-			updatedAt = &formatted
-		} else {
-			updatedAt = nil
-		}
-
-		posts = append(posts, LikedPost{
-			Liked:      likesInfo.isLiked,
-			LikesCount: likesInfo.count,
-			Likes:      likesInfo.likes,
-			Post: Post{
-				Id:          p.Id,
-				Content:     p.Content,
-				ImageUrl:    url,
-				PublishedAt: opts.FormatDate(p.PublishedAt),
-				UpdatedAt:   updatedAt,
-				Author: Author{
-					Id:       p.Author.Id,
-					Name:     p.Author.Name,
-					Lastname: p.Author.Lastname,
-					Image:    p.Author.Image,
-				},
-			},
-		})
+		selected = append(selected, p)
 	}
 
 	if eroErr = <-errCh; eroErr != nil {
@@ -112,14 +122,72 @@ func (s *Service) AllFeed(ctx context.Context, opts AllFeedOptions) (*PagedFeed,
 		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
 	}
 
+	selected, next, prev := cursor.Page(selected, int(opts.PageSize), keyset, keyset == nil && opts.Page == 1, postKey)
+
+	likesMap, eroErr := s.getLikesForPosts(ctx, selected, opts.UserId, opts.LikesCount, opts.FormatDate)
+	if eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting likes for posts in feed")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	commentsMap, eroErr := s.getCommentsForPosts(ctx, selected)
+	if eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting comments count for posts in feed")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	posts := make([]LikedPost, 0, opts.PageSize)
+	for _, p := range selected {
+		posts = append(posts, newLikedPost(p, likesOf(likesMap, p.Id), commentsMap[p.Id], opts.FormatDate))
+	}
+
 	if len(posts) == 0 {
 		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, ErrNoPosts)
 	}
 
 	return &PagedFeed{
 		First:   1,
-		Current: uint64(opts.Page),
+		Current: currentPage(keyset, opts.Page),
 		Last:    (postsCount + opts.PageSize - 1) / opts.PageSize,
+		Next:    next,
+		Prev:    prev,
 		Posts:   posts,
 	}, nil
 }
+
+func newLikedPost(p models.Post, likesInfo likes.PostLikes, commentsCount uint64, formatDate func(time.Time) string) LikedPost {
+	var url *string
+	if p.ImagesUrls == nil || len(p.ImagesUrls) == 0 {
+		url = nil
+	} else {
+		url = &p.ImagesUrls[0]
+	}
+
+	var updatedAt *string
+	if p.UpdatedAt != nil {
+		formatted := formatDate(*p.UpdatedAt)
+		updatedAt = &formatted
+	} else {
+		updatedAt = nil
+	}
+
+	return LikedPost{
+		Liked:         likesInfo.Liked,
+		LikesCount:    likesInfo.Count,
+		Likes:         likesInfo.Likes,
+		CommentsCount: commentsCount,
+		Post: Post{
+			Id:          p.Id,
+			Content:     p.Content,
+			ImageUrl:    url,
+			PublishedAt: formatDate(p.PublishedAt),
+			UpdatedAt:   updatedAt,
+			Author: Author{
+				Id:       p.Author.Id,
+				Name:     p.Author.Name,
+				Lastname: p.Author.Lastname,
+				Image:    p.Author.Image,
+			},
+		},
+	}
+}
@@ -2,15 +2,21 @@ package feed
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/cursor"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
 )
 
 type AuthorFeedOptions struct {
-	Page       uint64
-	PageSize   uint64
+	Page     uint64
+	PageSize uint64
+	// Cursor is an opaque cursor from previous page. If set, Page is ignored
+	Cursor     string
 	AuthorId   uint64
 	UserId     uint64
 	LikesCount uint64
@@ -26,7 +32,33 @@ func (s *Service) AuthorFeed(ctx context.Context, opts AuthorFeedOptions) (*Page
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	postsCh, errCh := s.d.AuthorProvider.UsersPosts(ctx, int(opts.Page-1)*int(opts.PageSize), int(opts.PageSize), opts.AuthorId)
+
+	hasAccess, eroErr := s.d.Access.HasFullAccess(ctx, opts.UserId, opts.AuthorId)
+	switch {
+	case errors.Is(eroErr, users.ErrUserNotFound):
+		s.log.DebugContext(logCtx.BuildContext(), "author not found")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).Build(), ero.CodeNotFound, ErrAuthorNotFound)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while checking access to author")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	case !hasAccess:
+		s.log.DebugContext(logCtx.BuildContext(), "author is private")
+		return nil, ero.New(logCtx.Build(), ero.CodePermissionDenied, ErrPrivateProfile)
+	}
+
+	keyset, err := cursor.Decode(opts.Cursor)
+	if err != nil {
+		s.log.DebugContext(logCtx.BuildContext(), "invalid cursor")
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeBadRequest, ErrInvalidCursor)
+	}
+
+	var postsCh <-chan models.Post
+	var errCh <-chan ero.Error
+	if keyset != nil {
+		postsCh, errCh = s.d.AuthorProvider.UsersPostsByKeyset(ctx, *keyset, int(opts.PageSize)+1, opts.AuthorId)
+	} else {
+		postsCh, errCh = s.d.AuthorProvider.UsersPosts(ctx, int(opts.Page-1)*int(opts.PageSize), int(opts.PageSize)+1, opts.AuthorId)
+	}
 
 	postsCount, eroErr := s.d.AuthorCounter.UsersPostsNum(ctx, opts.AuthorId)
 	if eroErr != nil {
@@ -34,13 +66,33 @@ func (s *Service) AuthorFeed(ctx context.Context, opts AuthorFeedOptions) (*Page
 		return nil, eroErr
 	}
 
-	posts := make([]LikedAuthorlessPost, 0, opts.PageSize)
+	selected := make([]models.Post, 0, opts.PageSize+1)
 	for p := range postsCh {
-		likesInfo, eroErr := s.getLikesForPost(ctx, p.Id, opts.UserId, opts.LikesCount, opts.FormatDate)
-		if eroErr != nil {
-			s.log.ErrorContext(eroErr.Context(ctx), "error while getting likes for post in profile")
-			continue
-		}
+		selected = append(selected, p)
+	}
+
+	if eroErr = <-errCh; eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting posts")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	selected, next, prev := cursor.Page(selected, int(opts.PageSize), keyset, keyset == nil && opts.Page == 1, postKey)
+
+	likesMap, eroErr := s.getLikesForPosts(ctx, selected, opts.UserId, opts.LikesCount, opts.FormatDate)
+	if eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting likes for posts in profile")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	commentsMap, eroErr := s.getCommentsForPosts(ctx, selected)
+	if eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting comments count for posts in profile")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	posts := make([]LikedAuthorlessPost, 0, opts.PageSize)
+	for _, p := range selected {
+		likesInfo := likesOf(likesMap, p.Id)
 
 		var url *string
 		if p.ImagesUrls == nil || len(p.ImagesUrls) == 0 {
@@ -58,9 +110,10 @@ func (s *Service) AuthorFeed(ctx context.Context, opts AuthorFeedOptions) (*Page
 		}
 
 		posts = append(posts, LikedAuthorlessPost{
-			Liked:      likesInfo.isLiked,
-			LikesCount: likesInfo.count,
-			Likes:      likesInfo.likes,
+			Liked:         likesInfo.Liked,
+			LikesCount:    likesInfo.Count,
+			Likes:         likesInfo.Likes,
+			CommentsCount: commentsMap[p.Id],
 			AuthorlessPost: AuthorlessPost{
 				Id:          p.Id,
 				Content:     p.Content,
@@ -71,19 +124,16 @@ func (s *Service) AuthorFeed(ctx context.Context, opts AuthorFeedOptions) (*Page
 		})
 	}
 
-	if eroErr = <-errCh; eroErr != nil {
-		s.log.ErrorContext(eroErr.Context(ctx), "error while getting posts")
-		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
-	}
-
 	if len(posts) == 0 {
 		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, ErrNoPosts)
 	}
 
 	return &PagedProfileFeed{
 		First:   1,
-		Current: uint64(opts.Page),
+		Current: currentPage(keyset, opts.Page),
 		Last:    (postsCount + opts.PageSize - 1) / opts.PageSize,
+		Next:    next,
+		Prev:    prev,
 		Posts:   posts,
 	}, nil
 }
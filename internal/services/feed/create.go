@@ -23,7 +23,7 @@ func (s *Service) CreatePost(ctx context.Context, post NewPost) (uint64, ero.Err
 			Id: post.AuthorId,
 		},
 		Content:    *post.Content,
-		ImagesUrls: models.StringSlice(post.ImagesUrls),
+		ImagesUrls: post.imagesOr(nil),
 	})
 	switch {
 	case errors.Is(err, storage.ErrForeignKeyConstraint):
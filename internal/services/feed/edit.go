@@ -0,0 +1,185 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+// EditPost replaces content and images of the post, keeping the previous version as a revision.
+// Images are kept if post.ImagesUrls is nil.
+// post.AuthorId must be the author of the post
+func (s *Service) EditPost(ctx context.Context, postId uint64, post NewPost) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "feed.Service.EditPost").With("post_id", postId).With("user_id", post.AuthorId)
+
+	if err := post.Validate(); err != nil {
+		s.log.DebugContext(logCtx.BuildContext(), "post hasn't passed validation")
+		return err
+	}
+
+	return s.d.Transactor.WithTx(ctx, func(tx storage.Storage) ero.Error {
+		old, eroErr := s.authorsPost(ctx, tx, logCtx, postId, post.AuthorId)
+		if eroErr != nil {
+			return eroErr
+		}
+
+		createdAt := old.PublishedAt
+		if old.UpdatedAt != nil {
+			createdAt = *old.UpdatedAt
+		}
+		eroErr = tx.SavePostRevision(ctx, models.PostRevision{
+			PostId:     postId,
+			Content:    old.Content,
+			ImagesUrls: old.ImagesUrls,
+			CreatedAt:  createdAt,
+		})
+		if eroErr != nil {
+			s.log.ErrorContext(eroErr.Context(ctx), "error while saving post revision")
+			return ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+		}
+
+		eroErr = tx.UpdatePost(ctx, &models.Post{
+			Id:         postId,
+			Content:    *post.Content,
+			ImagesUrls: post.imagesOr(old.ImagesUrls),
+		})
+		if eroErr != nil {
+			s.log.ErrorContext(eroErr.Context(ctx), "error while updating post")
+			return ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+		}
+
+		return nil
+	})
+}
+
+// DeletePost hides the post and its likes. Only the author can delete the post
+func (s *Service) DeletePost(ctx context.Context, userId, postId uint64) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "feed.Service.DeletePost").With("post_id", postId).With("user_id", userId)
+
+	return s.d.Transactor.WithTx(ctx, func(tx storage.Storage) ero.Error {
+		if _, eroErr := s.authorsPost(ctx, tx, logCtx, postId, userId); eroErr != nil {
+			return eroErr
+		}
+
+		if eroErr := tx.DeletePost(ctx, postId); eroErr != nil {
+			s.log.ErrorContext(eroErr.Context(ctx), "error while deleting post")
+			return ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+		}
+
+		return nil
+	})
+}
+
+// RemovePost hides a post of any author, it is done by moderators, see models.RoleModerator
+func (s *Service) RemovePost(ctx context.Context, moderatorId, postId uint64) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "feed.Service.RemovePost").With("post_id", postId).With("moderator_id", moderatorId)
+
+	return s.d.Transactor.WithTx(ctx, func(tx storage.Storage) ero.Error {
+		eroErr := tx.DeletePost(ctx, postId)
+		switch {
+		case errors.Is(eroErr, storage.ErrNoRows):
+			s.log.DebugContext(logCtx.BuildContext(), "post not found")
+			return ero.New(logCtx.WithParent(eroErr.Context(ctx)).Build(), ero.CodeNotFound, ErrPostNotFound)
+		case eroErr != nil:
+			s.log.ErrorContext(eroErr.Context(ctx), "error while deleting post")
+			return ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+		}
+
+		s.log.InfoContext(logCtx.BuildContext(), "post removed by moderator")
+		return nil
+	})
+}
+
+// authorsPost gets the post if userId is its author
+func (s *Service) authorsPost(ctx context.Context, tx storage.Storage, logCtx *erolog.ContextBuilder, postId, userId uint64) (*models.Post, ero.Error) {
+	post, eroErr := tx.Post(ctx, postId)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "post not found")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).Build(), ero.CodeNotFound, ErrPostNotFound)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting post")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	if post.Author.Id != userId {
+		s.log.DebugContext(logCtx.BuildContext(), "user is not the author")
+		return nil, ero.New(logCtx.Build(), ero.CodePermissionDenied, ErrNotAuthor)
+	}
+
+	return post, nil
+}
+
+type RevisionsOptions struct {
+	PostId uint64
+	// UserId is the viewer, revisions of private authors are shown to their followers only
+	UserId     uint64
+	FormatDate func(time.Time) string
+}
+
+// PostRevisions returns previous versions of the post, the latest first
+func (s *Service) PostRevisions(ctx context.Context, opts RevisionsOptions) ([]Revision, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "feed.Service.PostRevisions").
+		With("post_id", opts.PostId).
+		With("user_id", opts.UserId)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	post, eroErr := s.d.PostProvider.Post(ctx, opts.PostId)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "post not found")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).Build(), ero.CodeNotFound, ErrPostNotFound)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting post")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	hasAccess, eroErr := s.d.Access.HasFullAccess(ctx, opts.UserId, post.Author.Id)
+	switch {
+	case errors.Is(eroErr, users.ErrUserNotFound):
+		s.log.DebugContext(logCtx.BuildContext(), "author not found")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).Build(), ero.CodeNotFound, ErrAuthorNotFound)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while checking access to author")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	case !hasAccess:
+		s.log.DebugContext(logCtx.BuildContext(), "author is private")
+		return nil, ero.New(logCtx.Build(), ero.CodePermissionDenied, ErrPrivateProfile)
+	}
+
+	revisionsCh, errCh := s.d.Revisions.PostRevisions(ctx, opts.PostId)
+
+	revisions := make([]Revision, 0)
+	for r := range revisionsCh {
+		var url *string
+		if len(r.ImagesUrls) > 0 {
+			url = &r.ImagesUrls[0]
+		}
+
+		revisions = append(revisions, Revision{
+			Id:         r.Id,
+			Content:    r.Content,
+			ImageUrl:   url,
+			CreatedAt:  opts.FormatDate(r.CreatedAt),
+			ReplacedAt: opts.FormatDate(r.ReplacedAt),
+		})
+	}
+
+	if eroErr = <-errCh; eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting post revisions")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	if len(revisions) == 0 {
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, ErrNoRevisions)
+	}
+
+	return revisions, nil
+}
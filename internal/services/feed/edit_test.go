@@ -0,0 +1,68 @@
+package feed_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/feed"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditPost(t *testing.T) {
+	images := []string{"https://example.com/a.png", "https://example.com/b.png"}
+
+	tests := []struct {
+		name   string
+		images *[]string
+		want   models.StringSlice
+	}{
+		{name: "content only keeps images", want: images},
+		{name: "new images", images: &[]string{"https://example.com/c.png"}, want: models.StringSlice{"https://example.com/c.png"}},
+		{name: "empty images remove them", images: &[]string{}, want: models.StringSlice{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			db := memory.New()
+			s := feed.New(slog.New(slog.NewTextHandler(io.Discard, nil)), feed.Dependencies{
+				Saver:      db,
+				Transactor: db,
+			})
+
+			author, eroErr := db.SaveUser(context.Background(), &models.User{
+				Name:     "Name",
+				Lastname: "Lastname",
+				Email:    "user@example.com",
+				Country:  models.Country{Id: 1},
+				Birthday: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+			})
+			require.Nil(tt, eroErr)
+
+			content := "published"
+			postId, eroErr := s.CreatePost(context.Background(), feed.NewPost{
+				AuthorId:   author.Id,
+				Content:    &content,
+				ImagesUrls: &images,
+			})
+			require.Nil(tt, eroErr)
+
+			edited := "edited"
+			eroErr = s.EditPost(context.Background(), postId, feed.NewPost{
+				AuthorId:   author.Id,
+				Content:    &edited,
+				ImagesUrls: tc.images,
+			})
+			require.Nil(tt, eroErr)
+
+			post, eroErr := db.Post(context.Background(), postId)
+			require.Nil(tt, eroErr)
+			assert.Equal(tt, edited, post.Content)
+			assert.Equal(tt, tc.want, post.ImagesUrls)
+		})
+	}
+}
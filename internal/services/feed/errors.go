@@ -5,5 +5,11 @@ import "errors"
 var (
 	ErrInternal       = errors.New("internal error")
 	ErrAuthorNotFound = errors.New("author not found")
+	ErrPrivateProfile = errors.New("author's profile is private")
 	ErrNoPosts        = errors.New("no posts found")
+	ErrInvalidCursor  = errors.New("invalid cursor")
+	ErrEmptyQuery     = errors.New("search query is empty")
+	ErrPostNotFound   = errors.New("post not found")
+	ErrNotAuthor      = errors.New("only the author can change the post")
+	ErrNoRevisions    = errors.New("post has never been edited")
 )
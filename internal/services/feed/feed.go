@@ -6,6 +6,7 @@ import (
 
 	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/Onnywrite/tinkoff-prod/internal/services/likes"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 )
 
@@ -17,6 +18,7 @@ type Service struct {
 
 type PostsProvider interface {
 	Posts(ctx context.Context, offset, count int) (<-chan models.Post, <-chan ero.Error)
+	PostsByKeyset(ctx context.Context, keyset storage.Keyset, count int) (<-chan models.Post, <-chan ero.Error)
 }
 
 type PostsCountProvider interface {
@@ -31,26 +33,59 @@ type AuthorPostsCountProvider interface {
 	UsersPostsNum(ctx context.Context, userId uint64) (uint64, ero.Error)
 }
 
-type IsLikedProvider interface {
-	IsLiked(ctx context.Context, userId, postId uint64) bool
-}
-
 type AuthorPostsProvider interface {
 	UsersPosts(ctx context.Context, offset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error)
+	UsersPostsByKeyset(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error)
+}
+
+type TimelineProvider interface {
+	TimelinePosts(ctx context.Context, offset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error)
+	TimelinePostsByKeyset(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error)
+	TimelinePostsNum(ctx context.Context, userId uint64) (uint64, ero.Error)
+}
+
+type AccessChecker interface {
+	HasFullAccess(ctx context.Context, viewerId, userId uint64) (bool, ero.Error)
+}
+
+type CommentsCounter interface {
+	PostsCommentsNum(ctx context.Context, postIds []uint64) (map[uint64]uint64, ero.Error)
+}
+
+type PostProvider interface {
+	Post(ctx context.Context, id uint64) (*models.Post, ero.Error)
+}
+
+type PostRevisionsProvider interface {
+	PostRevisions(ctx context.Context, postId uint64) (<-chan models.PostRevision, <-chan ero.Error)
+}
+
+type PostsSearcher interface {
+	SearchPosts(ctx context.Context, query string, offset, count int) (<-chan models.FoundPost, <-chan ero.Error)
+	SearchPostsNum(ctx context.Context, query string) (uint64, ero.Error)
 }
 
 type LikesProvider interface {
-	Likes(ctx context.Context, opts likes.LikesOptions) (*likes.PagedLikes, ero.Error)
+	PostsLikes(ctx context.Context, opts likes.PostsLikesOptions) (map[uint64]likes.PostLikes, ero.Error)
 }
 
 type Dependencies struct {
-	Provider        PostsProvider
-	Counter         PostsCountProvider
-	Saver           PostSaver
-	AuthorCounter   AuthorPostsCountProvider
-	AuthorProvider  AuthorPostsProvider
-	IsLikedProvider IsLikedProvider
-	LikesProvider   LikesProvider
+	Provider       PostsProvider
+	Counter        PostsCountProvider
+	Saver          PostSaver
+	AuthorCounter  AuthorPostsCountProvider
+	AuthorProvider AuthorPostsProvider
+	Timeline       TimelineProvider
+	LikesProvider  LikesProvider
+	// CommentsCounter is usually comments.Service
+	CommentsCounter CommentsCounter
+	Searcher        PostsSearcher
+	PostProvider    PostProvider
+	Revisions       PostRevisionsProvider
+	// Transactor runs edits and deletes of posts
+	Transactor storage.Transactor
+	// Access is usually users.Service, it hides posts of private authors
+	Access AccessChecker
 }
 
 func New(logger *slog.Logger, deps Dependencies) *Service {
@@ -0,0 +1,87 @@
+package feed
+
+import (
+	"context"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/fulltext"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+type SearchOptions struct {
+	Query      string
+	Page       uint64
+	PageSize   uint64
+	UserId     uint64
+	LikesCount uint64
+	FormatDate func(time.Time) string
+}
+
+// Search finds public posts containing all words of the query, the most relevant first.
+// Results are paged only by number, because cursors do not fit ranking
+func (s *Service) Search(ctx context.Context, opts SearchOptions) (*PagedSearch, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "feed.Service.Search").With("page", opts.Page).With("page_size", opts.PageSize)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if len(fulltext.Tokenize(opts.Query)) == 0 {
+		s.log.DebugContext(logCtx.BuildContext(), "empty search query")
+		return nil, ero.New(logCtx.Build(), ero.CodeBadRequest, ErrEmptyQuery)
+	}
+
+	postsCh, errCh := s.d.Searcher.SearchPosts(ctx, opts.Query, int(opts.Page-1)*int(opts.PageSize), int(opts.PageSize))
+
+	postsCount, eroErr := s.d.Searcher.SearchPostsNum(ctx, opts.Query)
+	if eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "internal error")
+		return nil, eroErr
+	}
+
+	selected := make([]models.FoundPost, 0, opts.PageSize)
+	for p := range postsCh {
+		selected = append(selected, p)
+	}
+
+	if eroErr = <-errCh; eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while searching posts")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	if len(selected) == 0 {
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, ErrNoPosts)
+	}
+
+	plain := make([]models.Post, len(selected))
+	for i := range selected {
+		plain[i] = selected[i].Post
+	}
+	likesMap, eroErr := s.getLikesForPosts(ctx, plain, opts.UserId, opts.LikesCount, opts.FormatDate)
+	if eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting likes for found posts")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	commentsMap, eroErr := s.getCommentsForPosts(ctx, plain)
+	if eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting comments count for found posts")
+		return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	posts := make([]FoundPost, 0, len(selected))
+	for _, p := range selected {
+		posts = append(posts, FoundPost{
+			LikedPost: newLikedPost(p.Post, likesOf(likesMap, p.Id), commentsMap[p.Id], opts.FormatDate),
+			Rank:      p.Rank,
+			Highlight: p.Headline,
+		})
+	}
+
+	return &PagedSearch{
+		First:   1,
+		Current: opts.Page,
+		Last:    (postsCount + opts.PageSize - 1) / opts.PageSize,
+		Posts:   posts,
+	}, nil
+}
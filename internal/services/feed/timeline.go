@@ -0,0 +1,27 @@
+package feed
+
+import (
+	"context"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+// Timeline is a home feed of opts.UserId: posts of followed users and their own posts, the latest first
+func (s *Service) Timeline(ctx context.Context, opts AllFeedOptions) (*PagedFeed, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "feed.Service.Timeline").With("user_id", opts.UserId).With("page", opts.Page).With("page_size", opts.PageSize)
+
+	return s.pagedFeed(ctx, logCtx, opts, postsSource{
+		byOffset: func(ctx context.Context, offset, count int) (<-chan models.Post, <-chan ero.Error) {
+			return s.d.Timeline.TimelinePosts(ctx, offset, count, opts.UserId)
+		},
+		byKeyset: func(ctx context.Context, keyset storage.Keyset, count int) (<-chan models.Post, <-chan ero.Error) {
+			return s.d.Timeline.TimelinePostsByKeyset(ctx, keyset, count, opts.UserId)
+		},
+		count: func(ctx context.Context) (uint64, ero.Error) {
+			return s.d.Timeline.TimelinePostsNum(ctx, opts.UserId)
+		},
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"unicode/utf8"
 
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/Onnywrite/tinkoff-prod/internal/services/likes"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
@@ -29,9 +30,10 @@ type Post struct {
 
 type LikedPost struct {
 	Post
-	Liked      bool         `json:"is_liked"`
-	LikesCount uint64       `json:"likes_count"`
-	Likes      []likes.Like `json:"likes"`
+	Liked         bool         `json:"is_liked"`
+	LikesCount    uint64       `json:"likes_count"`
+	Likes         []likes.Like `json:"likes"`
+	CommentsCount uint64       `json:"comments_count"`
 }
 
 // TODO: getting profile feed with and without likes if it makes sence
@@ -45,25 +47,56 @@ type AuthorlessPost struct {
 
 type LikedAuthorlessPost struct {
 	AuthorlessPost
-	Liked      bool         `json:"is_liked"`
-	LikesCount uint64       `json:"likes_count"`
-	Likes      []likes.Like `json:"likes"`
+	Liked         bool         `json:"is_liked"`
+	LikesCount    uint64       `json:"likes_count"`
+	Likes         []likes.Like `json:"likes"`
+	CommentsCount uint64       `json:"comments_count"`
 }
 
 type Page[T any] struct {
-	First   uint64 `json:"first"`
+	First uint64 `json:"first"`
+	// Current is 0 if the page has been requested by cursor
 	Current uint64 `json:"current"`
 	Last    uint64 `json:"last"`
-	Posts   []T    `json:"posts"`
+	// Next and Prev are cursors to neighbouring pages, nil if there is no such page
+	Next  *string `json:"next"`
+	Prev  *string `json:"prev"`
+	Posts []T     `json:"posts"`
+}
+
+// FoundPost is a LikedPost matched by a search query
+type FoundPost struct {
+	LikedPost
+	Rank float64 `json:"rank"`
+	// Highlight is the HTML-escaped content with matched words wrapped in <b></b>
+	Highlight string `json:"highlight"`
+}
+
+type Revision struct {
+	Id         uint64  `json:"id"`
+	Content    string  `json:"content"`
+	ImageUrl   *string `json:"image_url"`
+	CreatedAt  string  `json:"created_at"`
+	ReplacedAt string  `json:"replaced_at"`
 }
 
 type PagedFeed Page[LikedPost]
 type PagedProfileFeed Page[LikedAuthorlessPost]
+type PagedSearch Page[FoundPost]
 
 type NewPost struct {
-	AuthorId   uint64   `json:"author_id"`
-	Content    *string  `json:"content"`
-	ImagesUrls []string `json:"images_urls"`
+	AuthorId uint64  `json:"author_id"`
+	Content  *string `json:"content"`
+	// ImagesUrls are kept as they are by an edit if nil, an empty slice removes them
+	ImagesUrls *[]string `json:"images_urls"`
+}
+
+// imagesOr are the images of the post, or old ones if they have not been sent
+func (p NewPost) imagesOr(old models.StringSlice) models.StringSlice {
+	if p.ImagesUrls == nil {
+		return old
+	}
+	return models.StringSlice(*p.ImagesUrls)
 }
 
 func (p NewPost) Validate() ero.Error {
@@ -84,21 +117,19 @@ func (p NewPost) Validate() ero.Error {
 		})
 	} else {
 		*p.Content = strings.TrimSpace(*p.Content)
-	}
-	if utf8.RuneCountInString(*p.Content) > 1000 {
-		faults = append(faults, fieldFault{
-			Field:   "content",
-			Message: "too long, must be less than 1000 characters",
-		})
+		if utf8.RuneCountInString(*p.Content) > 1000 {
+			faults = append(faults, fieldFault{
+				Field:   "content",
+				Message: "too long, must be less than 1000 characters",
+			})
+		}
 	}
 
 	if p.ImagesUrls != nil {
-		if len(p.ImagesUrls) == 0 {
-			p.ImagesUrls = nil
-		}
-		for i := range p.ImagesUrls {
-			p.ImagesUrls[i] = strings.Trim(p.ImagesUrls[i], " ")
-			if !urlRegex.MatchString(p.ImagesUrls[i]) {
+		images := *p.ImagesUrls
+		for i := range images {
+			images[i] = strings.Trim(images[i], " ")
+			if !urlRegex.MatchString(images[i]) {
 				faults = append(faults, fieldFault{
 					Field:   "images_urls",
 					Message: "not all URLs are valid",
@@ -0,0 +1,154 @@
+package follows
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/cursor"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+type FollowsOptions struct {
+	Page     uint64
+	PageSize uint64
+	// Cursor is an opaque cursor from previous page. If set, Page is ignored
+	Cursor string
+	UserId uint64
+	// ViewerId is the user who lists the follows
+	ViewerId   uint64
+	FormatDate func(time.Time) string
+}
+
+// currentPage is 0 when the page has been selected by cursor, because its number is unknown
+func currentPage(keyset *storage.Keyset, page uint64) uint64 {
+	if keyset != nil {
+		return 0
+	}
+	return page
+}
+
+// direction is either followers or followings of a user
+type direction struct {
+	op       string
+	byOffset func(ctx context.Context, offset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error)
+	byKeyset func(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error)
+	count    func(ctx context.Context, userId uint64) (uint64, ero.Error)
+	// other is the user on the other side of a follow
+	other func(models.Follow) models.User
+	// restricted follows are seen only by viewers with full access to the user, see users.Service.HasFullAccess
+	restricted bool
+}
+
+// Followers are users who follow opts.UserId, the latest first
+func (s *Service) Followers(ctx context.Context, opts FollowsOptions) (*PagedFollows, ero.Error) {
+	return s.follows(ctx, opts, direction{
+		op:         "follows.Service.Followers",
+		byOffset:   s.d.Provider.Followers,
+		byKeyset:   s.d.Provider.FollowersByKeyset,
+		count:      s.d.Counter.FollowersNum,
+		other:      func(f models.Follow) models.User { return f.Follower },
+		restricted: true,
+	})
+}
+
+// Followings are users whom opts.UserId follows, the latest first
+func (s *Service) Followings(ctx context.Context, opts FollowsOptions) (*PagedFollows, ero.Error) {
+	return s.follows(ctx, opts, direction{
+		op:         "follows.Service.Followings",
+		byOffset:   s.d.Provider.Followings,
+		byKeyset:   s.d.Provider.FollowingsByKeyset,
+		count:      s.d.Counter.FollowingsNum,
+		other:      func(f models.Follow) models.User { return f.Followee },
+		restricted: true,
+	})
+}
+
+func (s *Service) follows(ctx context.Context, opts FollowsOptions, dir direction) (*PagedFollows, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", dir.op).With("user_id", opts.UserId).With("viewer_id", opts.ViewerId).With("page", opts.Page).With("page_size", opts.PageSize)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if dir.restricted {
+		hasAccess, eroErr := s.d.Access.HasFullAccess(ctx, opts.ViewerId, opts.UserId)
+		switch {
+		case errors.Is(eroErr, users.ErrUserNotFound):
+			s.log.DebugContext(logCtx.BuildContext(), "user not found")
+			return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).Build(), ero.CodeNotFound, ErrUserNotFound)
+		case eroErr != nil:
+			s.log.ErrorContext(eroErr.Context(ctx), "error while checking access to user")
+			return nil, ero.New(logCtx.WithParent(eroErr.Context(ctx)).With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+		case !hasAccess:
+			s.log.DebugContext(logCtx.BuildContext(), "user is private")
+			return nil, ero.New(logCtx.Build(), ero.CodePermissionDenied, ErrPrivateProfile)
+		}
+	}
+
+	keyset, err := cursor.Decode(opts.Cursor)
+	if err != nil {
+		s.log.DebugContext(logCtx.BuildContext(), "invalid cursor")
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeBadRequest, ErrInvalidCursor)
+	}
+
+	var followsCh <-chan models.Follow
+	var eroCh <-chan ero.Error
+	if keyset != nil {
+		followsCh, eroCh = dir.byKeyset(ctx, *keyset, int(opts.PageSize)+1, opts.UserId)
+	} else {
+		followsCh, eroCh = dir.byOffset(ctx, int((opts.Page-1)*opts.PageSize), int(opts.PageSize)+1, opts.UserId)
+	}
+
+	followsCount, eroErr := dir.count(ctx, opts.UserId)
+	if eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting follows count")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	selected := make([]models.Follow, 0, opts.PageSize+1)
+	for follow := range followsCh {
+		selected = append(selected, follow)
+	}
+
+	if err := <-eroCh; err != nil {
+		s.log.ErrorContext(err.Context(ctx), "error while getting follows")
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	key := func(f models.Follow) (time.Time, uint64) {
+		return f.FollowedAt, dir.other(f).Id
+	}
+	selected, next, prev := cursor.Page(selected, int(opts.PageSize), keyset, keyset == nil && opts.Page == 1, key)
+
+	follows := make([]Follow, 0, opts.PageSize)
+	for _, follow := range selected {
+		user := dir.other(follow)
+		follows = append(follows, Follow{
+			User: User{
+				Id:       user.Id,
+				Name:     user.Name,
+				Lastname: user.Lastname,
+				Image:    user.Image,
+			},
+			FollowedAt: opts.FormatDate(follow.FollowedAt),
+		})
+	}
+
+	if len(follows) == 0 {
+		s.log.DebugContext(logCtx.BuildContext(), "no follows")
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, ErrNoFollows)
+	}
+
+	return &PagedFollows{
+		First:   1,
+		Current: currentPage(keyset, opts.Page),
+		Last:    (followsCount + opts.PageSize - 1) / opts.PageSize,
+		Count:   followsCount,
+		Next:    next,
+		Prev:    prev,
+		Follows: follows,
+	}, nil
+}
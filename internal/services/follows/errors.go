@@ -0,0 +1,15 @@
+package follows
+
+import "errors"
+
+var (
+	ErrSelfFollow        = errors.New("cannot follow yourself")
+	ErrAlreadyFollowed   = errors.New("user has already been followed")
+	ErrAlreadyUnfollowed = errors.New("user has not been followed yet")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrPrivateProfile    = errors.New("user's profile is private")
+	ErrNoRequest         = errors.New("no pending follow request")
+	ErrNoFollows         = errors.New("no follows found")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrInternal          = errors.New("internal error")
+)
@@ -0,0 +1,78 @@
+package follows
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+// Follow follows a public user at once or sends a request to a private one
+func (s *Service) Follow(ctx context.Context, followerId, followeeId uint64) (*FollowStatus, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "follows.Service.Follow").With("follower_id", followerId).With("followee_id", followeeId)
+
+	if followerId == followeeId {
+		s.log.DebugContext(logCtx.BuildContext(), "self follow")
+		return nil, ero.New(logCtx.Build(), ero.CodeBadRequest, ErrSelfFollow)
+	}
+
+	followee, err := s.d.UserProvider.UserById(ctx, followeeId)
+	switch {
+	case errors.Is(err, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "followee does not exist")
+		return nil, ero.New(logCtx.WithParent(err.Context(ctx)).Build(), ero.CodeNotFound, ErrUserNotFound)
+	case err != nil:
+		s.log.ErrorContext(err.Context(ctx), "error while getting followee")
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	var approvedAt *time.Time
+	if followee.IsPublic {
+		now := time.Now()
+		approvedAt = &now
+	}
+
+	err = s.d.Saver.SaveFollow(ctx, models.Follow{
+		Follower:   models.User{Id: followerId},
+		Followee:   models.User{Id: followeeId},
+		ApprovedAt: approvedAt,
+	})
+	switch {
+	case errors.Is(err, storage.ErrForeignKeyConstraint):
+		s.log.DebugContext(logCtx.BuildContext(), "user does not exist")
+		return nil, ero.New(logCtx.WithParent(err.Context(ctx)).Build(), ero.CodeNotFound, ErrUserNotFound)
+	case errors.Is(err, storage.ErrUniqueConstraint):
+		s.log.DebugContext(logCtx.BuildContext(), "already followed")
+		return nil, ero.New(logCtx.WithParent(err.Context(ctx)).Build(), ero.CodeExists, ErrAlreadyFollowed)
+	case err != nil:
+		s.log.ErrorContext(err.Context(ctx), "error while saving follow")
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return &FollowStatus{
+		Pending: approvedAt == nil,
+	}, nil
+}
+
+func (s *Service) Unfollow(ctx context.Context, followerId, followeeId uint64) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "follows.Service.Unfollow").With("follower_id", followerId).With("followee_id", followeeId)
+
+	err := s.d.Deleter.DeleteFollow(ctx, models.Follow{
+		Follower: models.User{Id: followerId},
+		Followee: models.User{Id: followeeId},
+	})
+	switch {
+	case errors.Is(err, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "user has not been followed")
+		return ero.New(logCtx.WithParent(err.Context(ctx)).Build(), ero.CodeNotFound, ErrAlreadyUnfollowed)
+	case err != nil:
+		s.log.ErrorContext(err.Context(ctx), "error while deleting follow")
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return nil
+}
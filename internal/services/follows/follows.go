@@ -0,0 +1,80 @@
+package follows
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+)
+
+type Service struct {
+	log *slog.Logger
+
+	d Dependencies
+}
+
+type FollowSaver interface {
+	SaveFollow(ctx context.Context, follow models.Follow) ero.Error
+}
+
+type FollowProvider interface {
+	Follow(ctx context.Context, followerId, followeeId uint64) (*models.Follow, ero.Error)
+}
+
+type FollowApprover interface {
+	ApproveFollow(ctx context.Context, follow models.Follow) ero.Error
+}
+
+type FollowDeleter interface {
+	DeleteFollow(ctx context.Context, follow models.Follow) ero.Error
+}
+
+type FollowsProvider interface {
+	Followers(ctx context.Context, offset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error)
+	FollowersByKeyset(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error)
+	Followings(ctx context.Context, offset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error)
+	FollowingsByKeyset(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error)
+}
+
+type FollowsCountProvider interface {
+	FollowersNum(ctx context.Context, userId uint64) (uint64, ero.Error)
+	FollowingsNum(ctx context.Context, userId uint64) (uint64, ero.Error)
+}
+
+type RequestsProvider interface {
+	FollowRequests(ctx context.Context, offset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error)
+	FollowRequestsByKeyset(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error)
+	FollowRequestsNum(ctx context.Context, userId uint64) (uint64, ero.Error)
+}
+
+type UserProvider interface {
+	UserById(ctx context.Context, id uint64) (*models.User, ero.Error)
+}
+
+type AccessChecker interface {
+	HasFullAccess(ctx context.Context, viewerId, userId uint64) (bool, ero.Error)
+}
+
+type Dependencies struct {
+	Saver    FollowSaver
+	Deleter  FollowDeleter
+	Provider FollowsProvider
+	Counter  FollowsCountProvider
+	// FollowProvider and Approver handle requests to private users
+	FollowProvider FollowProvider
+	Approver       FollowApprover
+	Requests       RequestsProvider
+	// UserProvider tells whether the followee is private
+	UserProvider UserProvider
+	// Access tells who can list followers and followings of private users
+	Access AccessChecker
+}
+
+func New(log *slog.Logger, deps Dependencies) *Service {
+	return &Service{
+		log: log,
+		d:   deps,
+	}
+}
@@ -0,0 +1,73 @@
+package follows
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+// Requests are pending requests to follow opts.UserId, the latest first
+func (s *Service) Requests(ctx context.Context, opts FollowsOptions) (*PagedFollows, ero.Error) {
+	return s.follows(ctx, opts, direction{
+		op:       "follows.Service.Requests",
+		byOffset: s.d.Requests.FollowRequests,
+		byKeyset: s.d.Requests.FollowRequestsByKeyset,
+		count:    s.d.Requests.FollowRequestsNum,
+		other:    func(f models.Follow) models.User { return f.Follower },
+	})
+}
+
+// Approve lets the follower see the private profile and posts of the user
+func (s *Service) Approve(ctx context.Context, userId, followerId uint64) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "follows.Service.Approve").With("user_id", userId).With("follower_id", followerId)
+
+	err := s.d.Approver.ApproveFollow(ctx, models.Follow{
+		Follower: models.User{Id: followerId},
+		Followee: models.User{Id: userId},
+	})
+	switch {
+	case errors.Is(err, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "no pending request")
+		return ero.New(logCtx.WithParent(err.Context(ctx)).Build(), ero.CodeNotFound, ErrNoRequest)
+	case err != nil:
+		s.log.ErrorContext(err.Context(ctx), "error while approving follow")
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return nil
+}
+
+// Reject deletes a pending request. Approved follows are not affected
+func (s *Service) Reject(ctx context.Context, userId, followerId uint64) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "follows.Service.Reject").With("user_id", userId).With("follower_id", followerId)
+
+	follow, err := s.d.FollowProvider.Follow(ctx, followerId, userId)
+	switch {
+	case errors.Is(err, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "no pending request")
+		return ero.New(logCtx.WithParent(err.Context(ctx)).Build(), ero.CodeNotFound, ErrNoRequest)
+	case err != nil:
+		s.log.ErrorContext(err.Context(ctx), "error while getting follow")
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, ErrInternal)
+	}
+	if follow.ApprovedAt != nil {
+		s.log.DebugContext(logCtx.BuildContext(), "follow has already been approved")
+		return ero.New(logCtx.Build(), ero.CodeNotFound, ErrNoRequest)
+	}
+
+	err = s.d.Deleter.DeleteFollow(ctx, *follow)
+	switch {
+	case errors.Is(err, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "request has been cancelled")
+		return ero.New(logCtx.WithParent(err.Context(ctx)).Build(), ero.CodeNotFound, ErrNoRequest)
+	case err != nil:
+		s.log.ErrorContext(err.Context(ctx), "error while deleting follow request")
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package follows
+
+type User struct {
+	Id       uint64 `json:"id"`
+	Name     string `json:"name"`
+	Lastname string `json:"surname"`
+	Image    string `json:"image"`
+}
+
+type Follow struct {
+	User       User   `json:"user"`
+	FollowedAt string `json:"followed_at"`
+}
+
+type FollowStatus struct {
+	// Pending is true if the followee is private and has not approved the request yet
+	Pending bool `json:"pending"`
+}
+
+type Page[T any] struct {
+	First uint64 `json:"first"`
+	// Current is 0 if the page has been requested by cursor
+	Current uint64 `json:"current"`
+	Last    uint64 `json:"last"`
+	Count   uint64 `json:"count"`
+	// Next and Prev are cursors to neighbouring pages, nil if there is no such page
+	Next    *string `json:"next"`
+	Prev    *string `json:"prev"`
+	Follows []T     `json:"follows"`
+}
+
+type PagedFollows Page[Follow]
@@ -4,23 +4,52 @@ import (
 	"context"
 	"time"
 
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/cursor"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
 )
 
 type LikesOptions struct {
-	Page       uint64
-	PageSize   uint64
+	Page     uint64
+	PageSize uint64
+	// Cursor is an opaque cursor from previous page. If set, Page is ignored
+	Cursor     string
 	PostId     uint64
 	FormatDate func(time.Time) string
 }
 
+func likeKey(like models.Like) (time.Time, uint64) {
+	return like.LikedAt, like.User.Id
+}
+
+// currentPage is 0 when the page has been selected by cursor, because its number is unknown
+func currentPage(keyset *storage.Keyset, page uint64) uint64 {
+	if keyset != nil {
+		return 0
+	}
+	return page
+}
+
 func (s *Service) Likes(ctx context.Context, opts LikesOptions) (*PagedLikes, ero.Error) {
 	logCtx := erolog.NewContextBuilder().With("op", "likes.Service.GetLiked").With("post_id", opts.PostId).With("page", opts.Page).With("page_size", opts.PageSize)
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	likesCh, eroCh := s.d.Provider.Likes(ctx, int((opts.Page-1)*opts.PageSize), int(opts.PageSize), opts.PostId)
+	keyset, err := cursor.Decode(opts.Cursor)
+	if err != nil {
+		s.log.DebugContext(logCtx.BuildContext(), "invalid cursor")
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeBadRequest, ErrInvalidCursor)
+	}
+
+	var likesCh <-chan models.Like
+	var eroCh <-chan ero.Error
+	if keyset != nil {
+		likesCh, eroCh = s.d.Provider.LikesByKeyset(ctx, *keyset, int(opts.PageSize)+1, opts.PostId)
+	} else {
+		likesCh, eroCh = s.d.Provider.Likes(ctx, int((opts.Page-1)*opts.PageSize), int(opts.PageSize)+1, opts.PostId)
+	}
 
 	likesCount, eroErr := s.d.LikesCounter.LikesNum(ctx, opts.PostId)
 	if eroErr != nil {
@@ -28,8 +57,20 @@ func (s *Service) Likes(ctx context.Context, opts LikesOptions) (*PagedLikes, er
 		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
 	}
 
-	likes := make([]Like, 0, opts.PageSize)
+	selected := make([]models.Like, 0, opts.PageSize+1)
 	for like := range likesCh {
+		selected = append(selected, like)
+	}
+
+	if err := <-eroCh; err != nil {
+		s.log.ErrorContext(err.Context(ctx), "error while getting likers")
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	selected, next, prev := cursor.Page(selected, int(opts.PageSize), keyset, keyset == nil && opts.Page == 1, likeKey)
+
+	likes := make([]Like, 0, opts.PageSize)
+	for _, like := range selected {
 		likes = append(likes, Like{
 			User: User{
 				Id:       like.User.Id,
@@ -41,11 +82,6 @@ func (s *Service) Likes(ctx context.Context, opts LikesOptions) (*PagedLikes, er
 		})
 	}
 
-	if err := <-eroCh; err != nil {
-		s.log.ErrorContext(err.Context(ctx), "error while getting likers")
-		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, ErrInternal)
-	}
-
 	if len(likes) == 0 {
 		s.log.DebugContext(logCtx.BuildContext(), "no likes")
 		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, ErrNoLikes)
@@ -53,9 +89,11 @@ func (s *Service) Likes(ctx context.Context, opts LikesOptions) (*PagedLikes, er
 
 	return &PagedLikes{
 		First:   1,
-		Current: opts.Page,
+		Current: currentPage(keyset, opts.Page),
 		Last:    (likesCount + opts.PageSize - 1) / opts.PageSize,
 		Count:   likesCount,
+		Next:    next,
+		Prev:    prev,
 		Likes:   likes,
 	}, nil
 }
@@ -7,5 +7,6 @@ var (
 	ErrAlreadyUnliked = errors.New("post has not been liked yet")
 	ErrNotFound       = errors.New("user or post not found")
 	ErrNoLikes        = errors.New("post has no likes")
+	ErrInvalidCursor  = errors.New("invalid cursor")
 	ErrInternal       = errors.New("internal error")
 )
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 
 	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 )
 
@@ -24,6 +25,7 @@ type LikeDeleter interface {
 
 type LikesProvider interface {
 	Likes(ctx context.Context, offset, count int, postId uint64) (<-chan models.Like, <-chan ero.Error)
+	LikesByKeyset(ctx context.Context, keyset storage.Keyset, count int, postId uint64) (<-chan models.Like, <-chan ero.Error)
 }
 
 type LikesCountProvider interface {
@@ -34,12 +36,17 @@ type LikeProvider interface {
 	Like(ctx context.Context, userId, postId uint64) (models.Like, ero.Error)
 }
 
+type PostsLikesProvider interface {
+	PostsLikes(ctx context.Context, postIds []uint64, userId uint64, top int) (map[uint64]models.PostLikes, ero.Error)
+}
+
 type Dependencies struct {
 	Saver        LikeSaver
 	Deleter      LikeDeleter
 	Provider     LikesProvider
 	LikesCounter LikesCountProvider
 	LikeProvider LikeProvider
+	PostsLikes   PostsLikesProvider
 }
 
 func New(log *slog.Logger, deps Dependencies) *Service {
@@ -0,0 +1,57 @@
+package likes
+
+import (
+	"context"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+type PostsLikesOptions struct {
+	PostIds []uint64
+	// UserId is the viewer, PostLikes.Liked tells whether the viewer has liked a post
+	UserId uint64
+	// LikesCount is how many recent likes to get for each post
+	LikesCount uint64
+	FormatDate func(time.Time) string
+}
+
+// PostsLikes gets likes of several posts at once. Posts without likes are missing in the result
+func (s *Service) PostsLikes(ctx context.Context, opts PostsLikesOptions) (map[uint64]PostLikes, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "likes.Service.PostsLikes").With("posts_count", len(opts.PostIds)).With("user_id", opts.UserId)
+
+	if len(opts.PostIds) == 0 {
+		return map[uint64]PostLikes{}, nil
+	}
+
+	summaries, eroErr := s.d.PostsLikes.PostsLikes(ctx, opts.PostIds, opts.UserId, int(opts.LikesCount))
+	if eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting likes of posts")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	res := make(map[uint64]PostLikes, len(summaries))
+	for postId, summary := range summaries {
+		likes := make([]Like, 0, len(summary.Top))
+		for _, like := range summary.Top {
+			likes = append(likes, Like{
+				User: User{
+					Id:       like.User.Id,
+					Name:     like.User.Name,
+					Lastname: like.User.Lastname,
+					Image:    like.User.Image,
+				},
+				LikedAt: opts.FormatDate(like.LikedAt),
+			})
+		}
+
+		res[postId] = PostLikes{
+			Liked: summary.IsLiked,
+			Count: summary.Count,
+			Likes: likes,
+		}
+	}
+
+	return res, nil
+}
@@ -12,12 +12,24 @@ type Like struct {
 	LikedAt string `json:"liked_at"`
 }
 
+// PostLikes summarizes likes of a post for a viewer
+type PostLikes struct {
+	Liked bool
+	Count uint64
+	// Likes are the most recent ones
+	Likes []Like
+}
+
 type Page[T any] struct {
-	First   uint64 `json:"first"`
+	First uint64 `json:"first"`
+	// Current is 0 if the page has been requested by cursor
 	Current uint64 `json:"current"`
 	Last    uint64 `json:"last"`
 	Count   uint64 `json:"count"`
-	Likes   []T    `json:"likes"`
+	// Next and Prev are cursors to neighbouring pages, nil if there is no such page
+	Next  *string `json:"next"`
+	Prev  *string `json:"prev"`
+	Likes []T     `json:"likes"`
 }
 
 type PagedLikes Page[Like]
@@ -0,0 +1,198 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/tokens"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+type UsersOptions struct {
+	// Query matches a part of email, name or lastname, everyone is listed if it is empty
+	Query      string
+	Page       uint64
+	PageSize   uint64
+	FormatDate func(time.Time) string
+}
+
+// Users lists users for moderators, ordered by id
+func (s *Service) Users(ctx context.Context, opts UsersOptions) (*PagedUsers, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.Users").With("page", opts.Page).With("page_size", opts.PageSize)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	usersCh, errCh := s.d.Admin.Users(ctx, opts.Query, int(opts.Page-1)*int(opts.PageSize), int(opts.PageSize))
+
+	usersCount, eroErr := s.d.Admin.UsersNum(ctx, opts.Query)
+	if eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while counting users")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	users := make([]AdminUser, 0, opts.PageSize)
+	for user := range usersCh {
+		users = append(users, newAdminUser(&user, opts.FormatDate))
+	}
+
+	if eroErr = <-errCh; eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting users")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	if len(users) == 0 {
+		s.log.DebugContext(logCtx.BuildContext(), "no users")
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, ErrNoUsers)
+	}
+
+	return &PagedUsers{
+		First:   1,
+		Current: opts.Page,
+		Last:    (usersCount + opts.PageSize - 1) / opts.PageSize,
+		Count:   usersCount,
+		Users:   users,
+	}, nil
+}
+
+// Suspend keeps the user from signing in until Unsuspend, their sessions are revoked
+// and access tokens are denied at once. The actor must have a higher role than the user
+func (s *Service) Suspend(ctx context.Context, actorId, userId uint64) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.Suspend").With("actor_id", actorId).With("user_id", userId)
+
+	if _, eroErr := s.outranked(ctx, logCtx, actorId, userId); eroErr != nil {
+		return eroErr
+	}
+
+	eroErr := s.d.Admin.SuspendUser(ctx, userId)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "user is already suspended")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeExists, ErrAlreadySuspended)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while suspending user")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	eroErr = s.d.Sessions.RevokeSessions(ctx, userId)
+	if eroErr != nil && !errors.Is(eroErr, storage.ErrNoRows) {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while revoking sessions")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	if eroErr = s.denyIssued(ctx, logCtx, userId); eroErr != nil {
+		return eroErr
+	}
+
+	s.log.InfoContext(logCtx.BuildContext(), "user suspended")
+	return nil
+}
+
+// Unsuspend lets the user sign in again, the actor must have a higher role than the user
+func (s *Service) Unsuspend(ctx context.Context, actorId, userId uint64) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.Unsuspend").With("actor_id", actorId).With("user_id", userId)
+
+	if _, eroErr := s.outranked(ctx, logCtx, actorId, userId); eroErr != nil {
+		return eroErr
+	}
+
+	eroErr := s.d.Admin.UnsuspendUser(ctx, userId)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "user is not suspended")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeNotFound, ErrNotSuspended)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while unsuspending user")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	s.log.InfoContext(logCtx.BuildContext(), "user unsuspended")
+	return nil
+}
+
+// SetRole grants the role up to the own one of the actor, who must have a higher role than the user.
+// Access tokens of the user are denied, so the new role is in tokens after a refresh
+func (s *Service) SetRole(ctx context.Context, actorId, userId uint64, change RoleChange) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.SetRole").With("actor_id", actorId).With("user_id", userId).With("role", change.Role)
+
+	if eroErr := change.Validate(); eroErr != nil {
+		s.log.DebugContext(logCtx.BuildContext(), "invalid role")
+		return eroErr
+	}
+
+	actor, eroErr := s.outranked(ctx, logCtx, actorId, userId)
+	if eroErr != nil {
+		return eroErr
+	}
+	if change.Role.Rank() > actor.Role.Rank() {
+		s.log.DebugContext(logCtx.BuildContext(), "role is higher than the role of the actor")
+		return ero.New(logCtx.Build(), ero.CodePermissionDenied, ErrInsufficientRole)
+	}
+
+	eroErr = s.d.Admin.SetRole(ctx, userId, change.Role)
+	if eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while setting role")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	if eroErr = s.denyIssued(ctx, logCtx, userId); eroErr != nil {
+		return eroErr
+	}
+
+	s.log.InfoContext(logCtx.BuildContext(), "role set")
+	return nil
+}
+
+// outranked gets the actor if their role is higher than the role of the user,
+// so no one acts on themselves or on their peers
+func (s *Service) outranked(ctx context.Context, logCtx *erolog.ContextBuilder, actorId, userId uint64) (*models.User, ero.Error) {
+	actor, eroErr := s.user(ctx, logCtx, actorId)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+	user, eroErr := s.user(ctx, logCtx, userId)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+
+	if actor.Role.Rank() <= user.Role.Rank() {
+		s.log.DebugContext(logCtx.BuildContext(), "actor does not outrank user")
+		return nil, ero.New(logCtx.Build(), ero.CodePermissionDenied, ErrInsufficientRole)
+	}
+
+	return actor, nil
+}
+
+// denyIssued denies every access token of the user issued so far until the latest one is no longer accepted
+func (s *Service) denyIssued(ctx context.Context, logCtx *erolog.ContextBuilder, userId uint64) ero.Error {
+	now := time.Now()
+	if err := s.d.Denylist.DenyUser(ctx, userId, now, tokens.AccessRegistered.AcceptedUntil(now.Add(tokens.AccessTTL))); err != nil {
+		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while denying access tokens")
+		return ero.New(logCtx.Build(), ero.CodeInternal, ErrInternal)
+	}
+	return nil
+}
+
+// notSuspended keeps suspended users from getting tokens
+func (s *Service) notSuspended(logCtx *erolog.ContextBuilder, user *models.User) ero.Error {
+	if user.SuspendedAt != nil {
+		s.log.DebugContext(logCtx.With("user_id", user.Id).BuildContext(), "user is suspended")
+		return ero.New(logCtx.Build(), ero.CodePermissionDenied, ErrUserSuspended)
+	}
+	return nil
+}
+
+func newAdminUser(user *models.User, formatDate func(time.Time) string) AdminUser {
+	res := AdminUser{
+		Profile: GetProfile(user),
+		Role:    user.Role,
+	}
+	if user.SuspendedAt != nil {
+		suspendedAt := formatDate(*user.SuspendedAt)
+		res.SuspendedAt = &suspendedAt
+	}
+	return res
+}
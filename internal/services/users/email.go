@@ -0,0 +1,210 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/mailer"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/tokens"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+// EmailOptions are got on every use, so they can be changed while the service runs
+type EmailOptions struct {
+	// RequireVerified rejects sign ins with emails which are not verified
+	RequireVerified bool
+	VerificationTTL time.Duration
+	ResetTTL        time.Duration
+	// LinkBase is prepended to links in emails, e.g. https://example.com
+	LinkBase string
+}
+
+// sendTimeout limits sending of one email, it is done in background
+const sendTimeout = 30 * time.Second
+
+// SendVerification emails a link verifying the email. Unknown and already verified emails
+// get nothing, but the result is the same, so they cannot be told apart
+func (s *Service) SendVerification(ctx context.Context, req EmailRequest) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.SendVerification").WithSecret("email", req.Email, 50)
+
+	user, eroErr := s.d.ByEmailProvider.UserByEmail(ctx, req.Email)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "verification of unknown email")
+		return nil
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting user")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	case user.EmailVerifiedAt != nil:
+		s.log.DebugContext(logCtx.BuildContext(), "email is already verified")
+		return nil
+	}
+
+	return s.sendVerification(logCtx, user)
+}
+
+func (s *Service) sendVerification(logCtx *erolog.ContextBuilder, user *models.User) ero.Error {
+	opts := s.d.EmailOptions()
+	link, eroErr := s.actionLink(logCtx, user, tokens.PurposeVerifyEmail, opts.VerificationTTL, opts.LinkBase+"/verify-email")
+	if eroErr != nil {
+		return eroErr
+	}
+
+	s.send(logCtx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi, %s!\n\nFollow the link to verify your email:\n%s\n\nIt is valid for %s.\n",
+			user.Name, link, opts.VerificationTTL),
+	})
+	return nil
+}
+
+// VerifyEmail verifies the email the token has been sent to. Verifying twice is not an error,
+// but the token is invalid if the email has been changed since
+func (s *Service) VerifyEmail(ctx context.Context, confirm ActionConfirm) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.VerifyEmail")
+
+	token, eroErr := s.parseAction(logCtx, confirm.Token, tokens.PurposeVerifyEmail)
+	if eroErr != nil {
+		return eroErr
+	}
+	logCtx.With("user_id", token.Id)
+
+	eroErr = s.d.EmailVerifier.VerifyEmail(ctx, token.Id, token.Email)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		user, eroErr := s.user(ctx, logCtx, token.Id)
+		if eroErr != nil {
+			return eroErr
+		}
+		if user.Email != token.Email {
+			s.log.DebugContext(logCtx.BuildContext(), "email has been changed")
+			return ero.New(logCtx.Build(), ero.CodeUnauthorized, ErrInvalidToken)
+		}
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while verifying email")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return nil
+}
+
+// RequestPasswordReset emails a link resetting the password.
+// Unknown emails get nothing, but the result is the same
+func (s *Service) RequestPasswordReset(ctx context.Context, req EmailRequest) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.RequestPasswordReset").WithSecret("email", req.Email, 50)
+
+	user, eroErr := s.d.ByEmailProvider.UserByEmail(ctx, req.Email)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "password reset of unknown email")
+		return nil
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting user")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	opts := s.d.EmailOptions()
+	link, eroErr := s.actionLink(logCtx, user, tokens.PurposeResetPassword, opts.ResetTTL, opts.LinkBase+"/reset-password")
+	if eroErr != nil {
+		return eroErr
+	}
+
+	s.send(logCtx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi, %s!\n\nFollow the link to set a new password:\n%s\n\n"+
+			"It is valid for %s. If you have not requested it, just ignore this email.\n",
+			user.Name, link, opts.ResetTTL),
+	})
+	return nil
+}
+
+// ResetPassword sets the new password and signs the user out of all devices.
+// The token can be used once, because the password change bumps the token generation
+func (s *Service) ResetPassword(ctx context.Context, reset PasswordReset) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.ResetPassword")
+
+	if err := reset.Validate(); err != nil {
+		s.log.DebugContext(err.Context(ctx), "errors validating new password")
+		return err
+	}
+
+	token, eroErr := s.parseAction(logCtx, reset.Token, tokens.PurposeResetPassword)
+	if eroErr != nil {
+		return eroErr
+	}
+	logCtx.With("user_id", token.Id)
+
+	user, eroErr := s.user(ctx, logCtx, token.Id)
+	if eroErr != nil {
+		return eroErr
+	}
+	if user.TokenGeneration != token.Rotation || user.Email != token.Email {
+		s.log.DebugContext(logCtx.BuildContext(), "reset token has already been used")
+		return ero.New(logCtx.Build(), ero.CodeUnauthorized, ErrInvalidToken)
+	}
+
+	if eroErr = s.checkPassword(logCtx, "new_password", reset.NewPassword, userInfo(user)); eroErr != nil {
+		return eroErr
+	}
+
+	if eroErr = s.setPassword(ctx, logCtx, user, reset.NewPassword); eroErr != nil {
+		return eroErr
+	}
+
+	// the link has been got by email, so it is verified as well
+	eroErr = s.d.EmailVerifier.VerifyEmail(ctx, user.Id, user.Email)
+	if eroErr != nil && !errors.Is(eroErr, storage.ErrNoRows) {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while verifying email")
+	}
+
+	return nil
+}
+
+func (s *Service) actionLink(logCtx *erolog.ContextBuilder, user *models.User, purpose tokens.Purpose, ttl time.Duration, page string) (string, ero.Error) {
+	action := tokens.Action{
+		Purpose:  purpose,
+		Id:       user.Id,
+		Email:    user.Email,
+		Rotation: user.TokenGeneration,
+	}
+	token, err := action.Sign(ttl)
+	if err != nil {
+		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while signing action token")
+		return "", ero.New(logCtx.Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return page + "?token=" + url.QueryEscape(string(token)), nil
+}
+
+func (s *Service) parseAction(logCtx *erolog.ContextBuilder, token tokens.ActionString, purpose tokens.Purpose) (*tokens.Action, ero.Error) {
+	action, err := token.ParseVerify(purpose)
+	switch {
+	case errors.Is(err, tokens.ErrExpired):
+		s.log.DebugContext(logCtx.BuildContext(), "action token has expired")
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeUnauthorized, err)
+	case err != nil:
+		s.log.DebugContext(logCtx.With("error", err).BuildContext(), "could not parse action token")
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeUnauthorized, ErrInvalidToken)
+	}
+	return action, nil
+}
+
+// send does not wait for the mailer, so responses take the same time whether an email is sent or not
+func (s *Service) send(logCtx *erolog.ContextBuilder, msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		defer cancel()
+
+		if err := s.d.Mailer.Send(ctx, msg); err != nil {
+			s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while sending email")
+		}
+	}()
+}
@@ -1,11 +1,46 @@
 package users
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
-	ErrUserExists         = errors.New("user already exists")
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrUserNotFound       = errors.New("user not found")
-	ErrInvalidToken       = errors.New("invalid token")
-	ErrInternal           = errors.New("internal error")
+	ErrUserExists          = errors.New("user already exists")
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrTokenReused         = errors.New("refresh token has already been used, the session has been revoked")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrNoSessions          = errors.New("no active sessions")
+	ErrTokenNotFound       = errors.New("personal access token not found")
+	ErrNoTokens            = errors.New("no personal access tokens")
+	ErrInsufficientScope   = errors.New("token lacks the required scope")
+	ErrTooManyAttempts     = errors.New("too many sign in attempts, try again later")
+	ErrEmailNotVerified    = errors.New("email is not verified")
+	ErrTotpEnabled         = errors.New("two-factor authentication is already enabled")
+	ErrTotpNotEnabled      = errors.New("two-factor authentication is not enabled")
+	ErrInvalidCode         = errors.New("invalid two-factor code")
+	ErrProviderNotFound    = errors.New("identity provider not found")
+	ErrProviderUnavailable = errors.New("identity provider is unavailable")
+	ErrIdentityRejected    = errors.New("identity provider has not confirmed the sign in")
+	ErrUserSuspended       = errors.New("user is suspended")
+	ErrAlreadySuspended    = errors.New("user is already suspended")
+	ErrNotSuspended        = errors.New("user is not suspended")
+	ErrNoUsers             = errors.New("no users found")
+	ErrInsufficientRole    = errors.New("role is not higher than the role of the user")
+	ErrInternal            = errors.New("internal error")
 )
+
+// TooManyAttemptsError tells when signing in is allowed again, it is ErrTooManyAttempts
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *TooManyAttemptsError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
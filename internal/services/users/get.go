@@ -5,25 +5,27 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/Onnywrite/tinkoff-prod/internal/storage"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
 )
 
-func (s *Service) UserById(ctx context.Context, id uint64, hasFullAccess bool) (PrivateOrPublicProfile, ero.Error) {
-	logCtx := erolog.NewContextBuilder().With("op", "users.Service.UserById").With("id", id)
+// UserById gets the full profile if viewer has access to it, see HasFullAccess
+func (s *Service) UserById(ctx context.Context, id, viewerId uint64) (PrivateOrPublicProfile, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.UserById").With("id", id).With("viewer_id", viewerId)
 
-	user, eroErr := s.d.ByIdProvider.UserById(context.TODO(), id)
-	switch {
-	case errors.Is(eroErr, storage.ErrNoRows):
-		s.log.DebugContext(logCtx.BuildContext(), "user not found")
-		return PrivateOrPublicProfile{}, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeNotFound, ErrUserNotFound)
-	case eroErr != nil:
-		s.log.ErrorContext(logCtx.BuildContext(), "error while getting user by id")
-		return PrivateOrPublicProfile{}, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	user, eroErr := s.user(ctx, logCtx, id)
+	if eroErr != nil {
+		return PrivateOrPublicProfile{}, eroErr
+	}
+
+	hasFullAccess, eroErr := s.hasFullAccess(ctx, logCtx, user, viewerId)
+	if eroErr != nil {
+		return PrivateOrPublicProfile{}, eroErr
 	}
 
-	if !user.IsPublic && !hasFullAccess {
+	if !hasFullAccess {
 		private := GetPrivateProfile(user)
 		return PrivateOrPublicProfile{
 			Private: &private,
@@ -36,6 +38,50 @@ func (s *Service) UserById(ctx context.Context, id uint64, hasFullAccess bool) (
 	}, nil
 }
 
+// HasFullAccess tells whether viewer can see the profile and posts of the user.
+// Everyone can see public users, private ones are seen only by themselves and by approved followers
+func (s *Service) HasFullAccess(ctx context.Context, viewerId, userId uint64) (bool, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.HasFullAccess").With("user_id", userId).With("viewer_id", viewerId)
+
+	user, eroErr := s.user(ctx, logCtx, userId)
+	if eroErr != nil {
+		return false, eroErr
+	}
+
+	return s.hasFullAccess(ctx, logCtx, user, viewerId)
+}
+
+func (s *Service) user(ctx context.Context, logCtx *erolog.ContextBuilder, id uint64) (*models.User, ero.Error) {
+	user, eroErr := s.d.ByIdProvider.UserById(ctx, id)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "user not found")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeNotFound, ErrUserNotFound)
+	case eroErr != nil:
+		s.log.ErrorContext(logCtx.BuildContext(), "error while getting user by id")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return user, nil
+}
+
+func (s *Service) hasFullAccess(ctx context.Context, logCtx *erolog.ContextBuilder, user *models.User, viewerId uint64) (bool, ero.Error) {
+	if user.IsPublic || user.Id == viewerId {
+		return true, nil
+	}
+
+	follow, eroErr := s.d.FollowProvider.Follow(ctx, viewerId, user.Id)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		return false, nil
+	case eroErr != nil:
+		s.log.ErrorContext(logCtx.BuildContext(), "error while getting follow")
+		return false, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return follow.ApprovedAt != nil, nil
+}
+
 type PrivateOrPublicProfile struct {
 	Public  *Profile
 	Private *PrivateProfile
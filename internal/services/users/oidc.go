@@ -0,0 +1,192 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/oidc"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+// OidcAuthorization starts a sign in with the provider, the user is sent to the returned url.
+// The client keeps the returned binding until the callback, see OidcCallback
+func (s *Service) OidcAuthorization(ctx context.Context, provider string) (*OidcAuthorization, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.OidcAuthorization").With("provider", provider)
+
+	authUrl, binding, err := s.d.Oidc.AuthCodeURL(ctx, provider)
+	switch {
+	case errors.Is(err, oidc.ErrUnknownProvider):
+		s.log.DebugContext(logCtx.BuildContext(), "unknown provider")
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeNotFound, ErrProviderNotFound)
+	case err != nil:
+		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while starting oidc sign in")
+		return nil, ero.New(logCtx.Build(), ero.CodeTemporaryUnavailable, ErrProviderUnavailable)
+	}
+
+	return &OidcAuthorization{AuthorizationUrl: authUrl, Binding: binding}, nil
+}
+
+// SignInOidc finishes the sign in with the code and state the provider has redirected with.
+// A new identity is linked to the user with its verified email or a new user is registered.
+// As SignIn, it returns a challenge if 2FA is enabled
+func (s *Service) SignInOidc(ctx context.Context, provider string, callback OidcCallback, device models.DeviceInfo) (*SignedIn, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.SignInOidc").With("provider", provider)
+
+	identity, err := s.d.Oidc.Exchange(ctx, provider, callback.Code, callback.State, callback.Binding)
+	switch {
+	case errors.Is(err, oidc.ErrUnknownProvider):
+		s.log.DebugContext(logCtx.BuildContext(), "unknown provider")
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeNotFound, ErrProviderNotFound)
+	case errors.Is(err, oidc.ErrInvalidState), errors.Is(err, oidc.ErrExchange), errors.Is(err, oidc.ErrInvalidIdToken):
+		s.log.DebugContext(logCtx.With("error", err).BuildContext(), "identity is rejected")
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeUnauthorized, ErrIdentityRejected)
+	case err != nil:
+		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while exchanging code")
+		return nil, ero.New(logCtx.Build(), ero.CodeTemporaryUnavailable, ErrProviderUnavailable)
+	}
+	logCtx.With("subject", identity.Subject)
+
+	user, eroErr := s.identityUser(ctx, logCtx, identity)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+
+	twoFactor, eroErr := s.twoFactorEnabled(ctx, logCtx, user.Id)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+	if twoFactor {
+		return s.challenge(logCtx, user)
+	}
+
+	authUser, eroErr := s.issue(ctx, logCtx, user, 0, device)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+	return &SignedIn{AuthorizedUser: authUser}, nil
+}
+
+// identityUser finds the user linked to the identity or links one
+func (s *Service) identityUser(ctx context.Context, logCtx *erolog.ContextBuilder, identity *oidc.Identity) (*models.User, ero.Error) {
+	linked, eroErr := s.d.Identities.Identity(ctx, identity.Issuer, identity.Subject)
+	switch {
+	case eroErr == nil:
+		return s.user(ctx, logCtx, linked.UserId)
+	case !errors.Is(eroErr, storage.ErrNoRows):
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting identity")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	email := strings.ToLower(identity.Email)
+	if email == "" || !identity.EmailVerified {
+		s.log.DebugContext(logCtx.BuildContext(), "email of the identity is not verified")
+		return nil, ero.New(logCtx.Build(), ero.CodePermissionDenied, ErrEmailNotVerified)
+	}
+
+	user, eroErr := s.d.ByEmailProvider.UserByEmail(ctx, email)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		if user, eroErr = s.provision(ctx, logCtx, identity, email); eroErr != nil {
+			return nil, eroErr
+		}
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting user")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	case user.EmailVerifiedAt == nil:
+		// anyone could have registered with the email, so the password they may know is replaced
+		s.log.InfoContext(logCtx.With("user_id", user.Id).BuildContext(), "linking unverified user, its password is dropped")
+		if eroErr = s.setPassword(ctx, logCtx, user, randomPassword()); eroErr != nil {
+			return nil, eroErr
+		}
+	}
+
+	// the provider has verified the email
+	if user.EmailVerifiedAt == nil {
+		eroErr = s.d.EmailVerifier.VerifyEmail(ctx, user.Id, user.Email)
+		if eroErr != nil && !errors.Is(eroErr, storage.ErrNoRows) {
+			s.log.ErrorContext(eroErr.Context(ctx), "error while verifying email")
+			return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+		}
+		verifiedAt := time.Now()
+		user.EmailVerifiedAt = &verifiedAt
+	}
+
+	_, eroErr = s.d.Identities.SaveIdentity(ctx, &models.Identity{
+		UserId:  user.Id,
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+		Email:   email,
+	})
+	// linked concurrently by another sign in
+	if eroErr != nil && !errors.Is(eroErr, storage.ErrUniqueConstraint) {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while saving identity")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return user, nil
+}
+
+// provision registers a user with claims of the identity. They are not validated as RegisterData is,
+// providers send names in any form and often no birthdate, so the claims are made valid or left out.
+// The password is random, the user can set one with a password reset
+func (s *Service) provision(ctx context.Context, logCtx *erolog.ContextBuilder, identity *oidc.Identity, email string) (*models.User, ero.Error) {
+	userData := RegisterData{
+		Name:     provisionedName(identity.GivenName),
+		Lastname: provisionedName(identity.FamilyName),
+		Email:    email,
+		Password: randomPassword(),
+	}
+	if userData.Name == "" {
+		local, _, _ := strings.Cut(email, "@")
+		if userData.Name = provisionedName(local); userData.Name == "" {
+			userData.Name = "User"
+		}
+	}
+	// the image column is short, long urls are replaced with the default image
+	if len(identity.Picture) <= 100 {
+		userData.Image = identity.Picture
+	}
+	// the birthday stays unknown if it is not valid
+	if birthday, err := time.Parse(time.DateOnly, identity.Birthdate); err == nil {
+		errorsMap := make(fieldErrors)
+		if errorsMap.validateBirthday("birthday", birthday); len(errorsMap) == 0 {
+			userData.Birthday = dateOnly(birthday)
+		}
+	}
+	userData.setDefaults()
+
+	s.log.InfoContext(logCtx.BuildContext(), "provisioning user")
+	return s.createUser(ctx, logCtx, &userData)
+}
+
+// provisionedName joins words of letters of the claim with hyphens, so it is a valid name, e.g. Mary-Ann of "mary ann".
+// It is empty if the claim has no letters
+func provisionedName(claim string) string {
+	words := strings.FieldsFunc(claim, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	name := []rune(strings.Join(words, "-"))
+	if len(name) > maxNameLength {
+		name = name[:maxNameLength]
+	}
+	if trimmed := strings.Trim(string(name), "-"); trimmed != "" {
+		return formatName(trimmed)
+	}
+	return ""
+}
+
+// randomPassword is never shown to anyone, it is 43 characters, so bcrypt can hash it as well
+func randomPassword() string {
+	random := make([]byte, 32)
+	// crypto/rand never fails on supported platforms
+	_, _ = rand.Read(random)
+	return base64.RawURLEncoding.EncodeToString(random)
+}
@@ -0,0 +1,238 @@
+package users_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/oidc"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/passhash"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/passpolicy"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/throttle"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/tokens"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage/memory"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubIdP issues an id token with claims for any code, the nonce is taken from the last authorization url
+type stubIdP struct {
+	*httptest.Server
+	nonce  string
+	claims jwt.MapClaims
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := &stubIdP{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "stub",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		claims := jwt.MapClaims{
+			"iss":   idp.URL,
+			"aud":   "client",
+			"sub":   "subject",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"nonce": idp.nonce,
+		}
+		for k, v := range idp.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "stub"
+		signed, _ := token.SignedString(key)
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+
+	return idp
+}
+
+func newOidcService(t *testing.T, idp *stubIdP, db *memory.MemStorage) *users.Service {
+	key, err := tokens.ParseKey([]byte("secret"))
+	require.NoError(t, err)
+	tokens.AccessKeys.Set(key, nil)
+	tokens.RefreshKeys.Set(key, nil)
+
+	passwords, err := passhash.New(passhash.DefaultOptions)
+	require.NoError(t, err)
+	policy, err := passpolicy.New(passpolicy.DefaultOptions)
+	require.NoError(t, err)
+
+	return users.New(slog.New(slog.NewTextHandler(io.Discard, nil)), users.Dependencies{
+		ByIdProvider:     db,
+		ByEmailProvider:  db,
+		Saver:            db,
+		Updater:          db,
+		PasswordUpdater:  db,
+		Passwords:        passwords,
+		PasswordPolicy:   policy,
+		Sessions:         db,
+		PersonalTokens:   db,
+		FollowProvider:   db,
+		EmailLimiter:     throttle.New(throttle.Options{}),
+		IpLimiter:        throttle.New(throttle.Options{}),
+		EmailVerifier:    db,
+		EmailOptions:     func() users.EmailOptions { return users.EmailOptions{} },
+		TwoFactor:        db,
+		TwoFactorOptions: func() users.TwoFactorOptions { return users.TwoFactorOptions{ChallengeTTL: time.Minute} },
+		Oidc: oidc.NewProviders([]oidc.ProviderConfig{{
+			Name:         "stub",
+			Issuer:       idp.URL,
+			ClientId:     "client",
+			ClientSecret: "secret",
+			RedirectUrl:  "https://app.example.com/callback",
+		}}, time.Minute, idp.Client()),
+		Identities: db,
+		Admin:      db,
+	})
+}
+
+// signInOidc goes through the whole flow as the frontend does
+func signInOidc(t *testing.T, s *users.Service, idp *stubIdP) (*users.SignedIn, error) {
+	return signInOidcWith(t, s, idp, func(binding string) string { return binding })
+}
+
+func signInOidcWith(t *testing.T, s *users.Service, idp *stubIdP, binding func(string) string) (*users.SignedIn, error) {
+	authorization, eroErr := s.OidcAuthorization(context.Background(), "stub")
+	require.Nil(t, eroErr)
+	u, err := url.Parse(authorization.AuthorizationUrl)
+	require.NoError(t, err)
+	idp.nonce = u.Query().Get("nonce")
+
+	signedIn, eroErr := s.SignInOidc(context.Background(), "stub", users.OidcCallback{
+		Code:    "code",
+		State:   u.Query().Get("state"),
+		Binding: binding(authorization.Binding),
+	}, models.DeviceInfo{})
+	if eroErr != nil {
+		return nil, eroErr
+	}
+	return signedIn, nil
+}
+
+func TestSignInOidc(t *testing.T) {
+	tests := []struct {
+		name     string
+		claims   jwt.MapClaims
+		existing bool
+		err      error
+		profile  users.Profile
+	}{
+		{
+			name:    "provisioned without birthdate",
+			claims:  jwt.MapClaims{"email": "mary@example.com", "email_verified": true, "given_name": "mary ann"},
+			profile: users.Profile{Name: "Mary-Ann", Email: "mary@example.com", EmailVerified: true},
+		},
+		{
+			name:    "provisioned without names",
+			claims:  jwt.MapClaims{"email": "j.doe@corp.example", "email_verified": true, "birthdate": "1900-01-01"},
+			profile: users.Profile{Name: "J-Doe", Email: "j.doe@corp.example", EmailVerified: true},
+		},
+		{
+			name: "provisioned with all claims",
+			claims: jwt.MapClaims{"email": "john@example.com", "email_verified": true,
+				"given_name": "John", "family_name": "O'Brien", "birthdate": "2000-01-02"},
+			profile: users.Profile{Name: "John", Lastname: "O-Brien", Email: "john@example.com", EmailVerified: true, Birthday: "2000-01-02"},
+		},
+		{
+			name:     "linked by email",
+			claims:   jwt.MapClaims{"email": "User@Example.com", "email_verified": true, "given_name": "Another"},
+			existing: true,
+			profile:  users.Profile{Name: "Name", Lastname: "Lastname", Email: "user@example.com", EmailVerified: true, Birthday: "2000-01-01"},
+		},
+		{
+			name:     "unverified email",
+			claims:   jwt.MapClaims{"email": "user@example.com", "email_verified": false},
+			existing: true,
+			err:      users.ErrEmailNotVerified,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			idp := newStubIdP(tt)
+			idp.claims = tc.claims
+			db := memory.New()
+			s := newOidcService(tt, idp, db)
+
+			var existing *models.User
+			if tc.existing {
+				var eroErr error
+				existing, eroErr = db.SaveUser(context.Background(), &models.User{
+					Name:         "Name",
+					Lastname:     "Lastname",
+					Email:        "user@example.com",
+					Country:      models.Country{Id: 1},
+					PasswordHash: "known to whoever has registered",
+					Birthday:     time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+				})
+				require.Nil(tt, eroErr)
+			}
+
+			signedIn, err := signInOidc(tt, s, idp)
+			if tc.err != nil {
+				assert.ErrorIs(tt, err, tc.err)
+				return
+			}
+			require.NoError(tt, err)
+			require.NotNil(tt, signedIn.AuthorizedUser)
+			profile := signedIn.AuthorizedUser.Profile
+			assert.NotEmpty(tt, signedIn.AuthorizedUser.Access)
+			if existing != nil {
+				assert.Equal(tt, existing.Id, profile.Id)
+				user, eroErr := db.UserById(context.Background(), existing.Id)
+				require.Nil(tt, eroErr)
+				assert.NotEqual(tt, existing.PasswordHash, user.PasswordHash)
+			}
+
+			tc.profile.Id, tc.profile.Country, tc.profile.IsPublic, tc.profile.Image =
+				profile.Id, profile.Country, profile.IsPublic, profile.Image
+			assert.Equal(tt, tc.profile, profile)
+
+			// the identity is linked now, so the same user signs in again
+			again, err := signInOidc(tt, s, idp)
+			require.NoError(tt, err)
+			assert.Equal(tt, profile.Id, again.AuthorizedUser.Profile.Id)
+		})
+	}
+
+	t.Run("callback of another client", func(tt *testing.T) {
+		idp := newStubIdP(tt)
+		idp.claims = jwt.MapClaims{"email": "mary@example.com", "email_verified": true}
+		s := newOidcService(tt, idp, memory.New())
+
+		_, err := signInOidcWith(tt, s, idp, func(string) string { return "" })
+		assert.ErrorIs(tt, err, users.ErrIdentityRejected)
+	})
+}
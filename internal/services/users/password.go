@@ -0,0 +1,156 @@
+package users
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/passhash"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/passpolicy"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+// ChangePassword replaces the password and revokes all refresh tokens of the user.
+// The returned tokens are the only valid ones afterwards
+func (s *Service) ChangePassword(ctx context.Context, id uint64, change PasswordChange, device models.DeviceInfo) (*AuthorizedUser, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.ChangePassword").With("id", id)
+
+	if err := change.Validate(); err != nil {
+		s.log.DebugContext(err.Context(ctx), "errors validating new password")
+		return nil, err
+	}
+
+	user, eroErr := s.user(ctx, logCtx, id)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+
+	if eroErr = s.verifyPassword(logCtx, user, change.OldPassword); eroErr != nil {
+		return nil, eroErr
+	}
+
+	if eroErr = s.checkPassword(logCtx, "new_password", change.NewPassword, userInfo(user)); eroErr != nil {
+		return nil, eroErr
+	}
+
+	if eroErr = s.setPassword(ctx, logCtx, user, change.NewPassword); eroErr != nil {
+		return nil, eroErr
+	}
+
+	return s.issue(ctx, logCtx, user, 0, device)
+}
+
+// setPassword stores the hash of the password, bumps the token generation of the user
+// and revokes all sessions
+func (s *Service) setPassword(ctx context.Context, logCtx *erolog.ContextBuilder, user *models.User, password string) ero.Error {
+	hash, eroErr := s.hashPassword(logCtx, "new_password", password)
+	if eroErr != nil {
+		return eroErr
+	}
+
+	generation, eroErr := s.d.PasswordUpdater.UpdatePassword(ctx, user.Id, hash)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "user not found")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeNotFound, ErrUserNotFound)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while updating password")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+	user.PasswordHash, user.TokenGeneration = hash, generation
+
+	// tokens are already invalid because of the generation, but sessions must not be listed either
+	if eroErr = s.d.Sessions.RevokeSessions(ctx, user.Id); eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while revoking sessions")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return nil
+}
+
+// checkPassword applies the password policy to a new password, field names it in validation errors.
+// If the list of breached passwords cannot be read, the password is not rejected for it
+func (s *Service) checkPassword(logCtx *erolog.ContextBuilder, field, password string, user passpolicy.UserInfo) ero.Error {
+	msgs, err := s.d.PasswordPolicy.Check(password, user)
+	if err != nil {
+		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "could not check breached passwords")
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	s.log.DebugContext(logCtx.BuildContext(), "password is rejected by the policy")
+	errorsMap := make(fieldErrors)
+	for _, msg := range msgs {
+		errorsMap.add(field, msg)
+	}
+	return errorsMap.toEro()
+}
+
+func userInfo(user *models.User) passpolicy.UserInfo {
+	return passpolicy.UserInfo{
+		Name:     user.Name,
+		Lastname: user.Lastname,
+		Email:    user.Email,
+	}
+}
+
+// hashPassword hashes with the current algorithm, field names the password in validation errors
+func (s *Service) hashPassword(logCtx *erolog.ContextBuilder, field, password string) (string, ero.Error) {
+	hash, err := s.d.Passwords.Hash(password)
+	switch {
+	case errors.Is(err, passhash.ErrTooLong):
+		s.log.DebugContext(logCtx.BuildContext(), "password is too long for the algorithm")
+		errorsMap := make(fieldErrors)
+		errorsMap.add(field, "too long, must be less than or equals 72 bytes")
+		return "", errorsMap.toEro()
+	case err != nil:
+		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while hashing password")
+		return "", ero.New(logCtx.Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return hash, nil
+}
+
+// verifyPassword returns ErrInvalidCredentials if the password does not match the hash of the user
+func (s *Service) verifyPassword(logCtx *erolog.ContextBuilder, user *models.User, password string) ero.Error {
+	err := s.d.Passwords.Verify(user.PasswordHash, password)
+	switch {
+	case errors.Is(err, passhash.ErrMismatch):
+		s.log.DebugContext(logCtx.BuildContext(), "invalid password")
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeUnauthorized, ErrInvalidCredentials)
+	case err != nil:
+		s.log.ErrorContext(logCtx.With("error", err).With("user_id", user.Id).BuildContext(), "could not verify password hash")
+		return ero.New(logCtx.Build(), ero.CodeUnauthorized, ErrInvalidCredentials)
+	}
+
+	return nil
+}
+
+// rehashPassword replaces the hash of the just verified password if it has been made with other algorithm
+// or parameters than the current ones. Failures are only logged, the user is signed in with the old hash
+func (s *Service) rehashPassword(ctx context.Context, logCtx *erolog.ContextBuilder, user *models.User, password string) {
+	if !s.d.Passwords.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	hash, err := s.d.Passwords.Hash(password)
+	if err != nil {
+		// e.g. bcrypt is current and the password is longer than it can hash
+		s.log.WarnContext(logCtx.With("error", err).BuildContext(), "could not rehash password")
+		return
+	}
+
+	eroErr := s.d.PasswordUpdater.RehashPassword(ctx, user.Id, user.PasswordHash, hash)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "password has changed concurrently, not rehashed")
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while rehashing password")
+	default:
+		user.PasswordHash = hash
+		s.log.DebugContext(logCtx.BuildContext(), "password rehashed")
+	}
+}
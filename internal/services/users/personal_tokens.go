@@ -0,0 +1,166 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/tokens"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+// CreatePersonalToken issues a personal access token. The token is in the response only,
+// it cannot be got again
+func (s *Service) CreatePersonalToken(ctx context.Context, userId uint64, data PersonalTokenData) (*CreatedPersonalToken, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.CreatePersonalToken").With("user_id", userId)
+
+	if eroErr := data.Validate(); eroErr != nil {
+		s.log.DebugContext(logCtx.BuildContext(), "invalid personal token data")
+		return nil, eroErr
+	}
+
+	token, hash, err := tokens.NewPersonal()
+	if err != nil {
+		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while generating personal token")
+		return nil, ero.New(logCtx.Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	saved := models.PersonalToken{
+		UserId: userId,
+		Name:   data.Name,
+		Hash:   hash,
+		Scopes: data.Scopes,
+	}
+	if data.ExpiresInDays != nil {
+		expiresAt := time.Now().AddDate(0, 0, int(*data.ExpiresInDays))
+		saved.ExpiresAt = &expiresAt
+	}
+
+	id, eroErr := s.d.PersonalTokens.SavePersonalToken(ctx, &saved)
+	switch {
+	case errors.Is(eroErr, storage.ErrForeignKeyConstraint):
+		s.log.DebugContext(logCtx.BuildContext(), "user not found")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeNotFound, ErrUserNotFound)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while saving personal token")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	saved.Id = id
+	saved.CreatedAt = time.Now()
+
+	return &CreatedPersonalToken{
+		PersonalToken: newPersonalToken(&saved, func(t time.Time) string { return t.Format(time.DateTime) }),
+		Token:         token,
+	}, nil
+}
+
+type PersonalTokensOptions struct {
+	UserId     uint64
+	FormatDate func(time.Time) string
+}
+
+// PersonalTokens lists active tokens of the user without the tokens themselves, the latest first
+func (s *Service) PersonalTokens(ctx context.Context, opts PersonalTokensOptions) ([]PersonalToken, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.PersonalTokens").With("user_id", opts.UserId)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tokensCh, errCh := s.d.PersonalTokens.PersonalTokens(ctx, opts.UserId)
+
+	personalTokens := make([]PersonalToken, 0)
+	for t := range tokensCh {
+		personalTokens = append(personalTokens, newPersonalToken(&t, opts.FormatDate))
+	}
+
+	if eroErr := <-errCh; eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting personal tokens")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	if len(personalTokens) == 0 {
+		s.log.DebugContext(logCtx.BuildContext(), "no personal tokens")
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, ErrNoTokens)
+	}
+
+	return personalTokens, nil
+}
+
+func (s *Service) RevokePersonalToken(ctx context.Context, userId, tokenId uint64) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.RevokePersonalToken").With("user_id", userId).With("token_id", tokenId)
+
+	eroErr := s.d.PersonalTokens.RevokePersonalToken(ctx, userId, tokenId)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "personal token not found")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeNotFound, ErrTokenNotFound)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while revoking personal token")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return nil
+}
+
+// AuthorizePersonalToken returns the id of the user owning the token, if the token is active,
+// has the scope and the user is not suspended. tokens.ScopeAccount is never granted
+func (s *Service) AuthorizePersonalToken(ctx context.Context, token string, scope tokens.Scope) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.AuthorizePersonalToken").With("scope", scope)
+
+	saved, eroErr := s.d.PersonalTokens.PersonalTokenByHash(ctx, tokens.HashPersonal(token))
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "unknown personal token")
+		return 0, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeUnauthorized, ErrInvalidToken)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting personal token")
+		return 0, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	logCtx.With("token_id", saved.Id).With("user_id", saved.UserId)
+	if saved.RevokedAt != nil || (saved.ExpiresAt != nil && saved.ExpiresAt.Before(time.Now())) {
+		s.log.DebugContext(logCtx.BuildContext(), "personal token is revoked or expired")
+		return 0, ero.New(logCtx.Build(), ero.CodeUnauthorized, ErrInvalidToken)
+	}
+	if scope == tokens.ScopeAccount || !slices.Contains(saved.Scopes, string(scope)) {
+		s.log.DebugContext(logCtx.BuildContext(), "insufficient scope")
+		return 0, ero.New(logCtx.Build(), ero.CodePermissionDenied, ErrInsufficientScope)
+	}
+
+	user, eroErr := s.user(ctx, logCtx, saved.UserId)
+	if eroErr != nil {
+		return 0, eroErr
+	}
+	if eroErr = s.notSuspended(logCtx, user); eroErr != nil {
+		return 0, eroErr
+	}
+
+	eroErr = s.d.PersonalTokens.UsePersonalToken(ctx, saved.Id)
+	if eroErr != nil && !errors.Is(eroErr, storage.ErrNoRows) {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while marking personal token used")
+	}
+
+	return saved.UserId, nil
+}
+
+func newPersonalToken(t *models.PersonalToken, formatDate func(time.Time) string) PersonalToken {
+	res := PersonalToken{
+		Id:        t.Id,
+		Name:      t.Name,
+		Scopes:    t.Scopes,
+		CreatedAt: formatDate(t.CreatedAt),
+	}
+	if t.ExpiresAt != nil {
+		expiresAt := formatDate(*t.ExpiresAt)
+		res.ExpiresAt = &expiresAt
+	}
+	if t.LastUsedAt != nil {
+		lastUsedAt := formatDate(*t.LastUsedAt)
+		res.LastUsedAt = &lastUsedAt
+	}
+	return res
+}
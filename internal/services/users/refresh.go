@@ -5,12 +5,14 @@ import (
 	"errors"
 
 	"github.com/Onnywrite/tinkoff-prod/internal/lib/tokens"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/Onnywrite/tinkoff-prod/internal/storage"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
 )
 
-func (s *Service) Refresh(ctx context.Context, refresh tokens.RefreshString) (*AuthorizedUser, ero.Error) {
+// Refresh exchanges the refresh token for a new pair, the token cannot be used again
+func (s *Service) Refresh(ctx context.Context, refresh tokens.RefreshString, device models.DeviceInfo) (*AuthorizedUser, ero.Error) {
 	logCtx := erolog.NewContextBuilder().With("op", "users.Service.Refresh")
 
 	token, err := refresh.ParseVerify()
@@ -23,6 +25,11 @@ func (s *Service) Refresh(ctx context.Context, refresh tokens.RefreshString) (*A
 		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeUnauthorized, ErrInvalidToken)
 	}
 
+	session, eroErr := s.useSession(ctx, logCtx, token)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+
 	user, eroErr := s.d.ByIdProvider.UserById(ctx, token.Id)
 	switch {
 	case errors.Is(eroErr, storage.ErrNoRows):
@@ -33,14 +40,10 @@ func (s *Service) Refresh(ctx context.Context, refresh tokens.RefreshString) (*A
 		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
 	}
 
-	pair, err := tokens.NewPair(user, 0)
-	if err != nil {
-		s.log.ErrorContext(logCtx.BuildContext(), "error while generating tokens")
-		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, ErrInternal)
+	if token.Rotation != user.TokenGeneration {
+		s.log.DebugContext(logCtx.BuildContext(), "refresh token has been revoked")
+		return nil, ero.New(logCtx.Build(), ero.CodeUnauthorized, ErrInvalidToken)
 	}
 
-	return &AuthorizedUser{
-		Profile: GetProfile(user),
-		Pair:    pair,
-	}, nil
+	return s.issue(ctx, logCtx, user, session.FamilyId, device)
 }
@@ -5,16 +5,15 @@ import (
 	"errors"
 	"time"
 
-	"github.com/Onnywrite/tinkoff-prod/internal/lib/tokens"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/passpolicy"
 	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/Onnywrite/tinkoff-prod/internal/services/countries"
 	"github.com/Onnywrite/tinkoff-prod/internal/storage"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func (s *Service) Register(ctx context.Context, userData RegisterData) (*AuthorizedUser, ero.Error) {
+func (s *Service) Register(ctx context.Context, userData RegisterData, device models.DeviceInfo) (*AuthorizedUser, ero.Error) {
 	logCtx := erolog.NewContextBuilder().With("op", "users.Service.Register").WithSecret("email", userData.Email, 50)
 
 	if err := userData.Validate(); err != nil {
@@ -22,10 +21,35 @@ func (s *Service) Register(ctx context.Context, userData RegisterData) (*Authori
 		return nil, err
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(userData.Password), bcrypt.DefaultCost)
-	if err != nil {
-		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while hashing password")
-		return nil, ero.New(logCtx.Build(), ero.CodeInternal, ErrInternal)
+	eroErr := s.checkPassword(logCtx, "password", userData.Password, passpolicy.UserInfo{
+		Name:     userData.Name,
+		Lastname: userData.Lastname,
+		Email:    userData.Email,
+	})
+	if eroErr != nil {
+		return nil, eroErr
+	}
+
+	user, eroErr := s.createUser(ctx, logCtx, &userData)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+
+	if eroErr = s.sendVerification(logCtx, user); eroErr != nil {
+		return nil, eroErr
+	}
+	if s.d.EmailOptions().RequireVerified {
+		return &AuthorizedUser{Profile: GetProfile(user)}, nil
+	}
+
+	return s.issue(ctx, logCtx, user, 0, device)
+}
+
+// createUser saves the user with the hash of the password, userData must be validated or filled by provision
+func (s *Service) createUser(ctx context.Context, logCtx *erolog.ContextBuilder, userData *RegisterData) (*models.User, ero.Error) {
+	hash, eroErr := s.hashPassword(logCtx, "password", userData.Password)
+	if eroErr != nil {
+		return nil, eroErr
 	}
 
 	user, eroErr := s.d.Saver.SaveUser(ctx, &models.User{
@@ -37,7 +61,7 @@ func (s *Service) Register(ctx context.Context, userData RegisterData) (*Authori
 		},
 		IsPublic:     *userData.IsPublic,
 		Image:        userData.Image,
-		PasswordHash: string(hash),
+		PasswordHash: hash,
 		Birthday:     time.Time(userData.Birthday),
 	})
 	switch {
@@ -52,14 +76,5 @@ func (s *Service) Register(ctx context.Context, userData RegisterData) (*Authori
 		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
 	}
 
-	pair, err := tokens.NewPair(user, 0)
-	if err != nil {
-		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while generating tokens")
-		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
-	}
-
-	return &AuthorizedUser{
-		Profile: GetProfile(user),
-		Pair:    pair,
-	}, nil
+	return user, nil
 }
@@ -0,0 +1,177 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/tokens"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+// issue signs tokens of a new session of the family, or of a new family if familyId is 0.
+// Suspended users get none
+func (s *Service) issue(ctx context.Context, logCtx *erolog.ContextBuilder, user *models.User, familyId uint64, device models.DeviceInfo) (*AuthorizedUser, ero.Error) {
+	if eroErr := s.notSuspended(logCtx, user); eroErr != nil {
+		return nil, eroErr
+	}
+
+	jti, err := tokens.NewJti()
+	if err != nil {
+		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while generating jti")
+		return nil, ero.New(logCtx.Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	_, eroErr := s.d.Sessions.SaveSession(ctx, &models.Session{
+		Jti:       jti,
+		FamilyId:  familyId,
+		UserId:    user.Id,
+		Device:    device,
+		ExpiresAt: time.Now().Add(tokens.RefreshTTL),
+	})
+	if eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while saving session")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	pair, err := tokens.NewPair(user, user.TokenGeneration, jti)
+	if err != nil {
+		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while generating tokens")
+		return nil, ero.New(logCtx.Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return &AuthorizedUser{
+		Profile: GetProfile(user),
+		Pair:    pair,
+	}, nil
+}
+
+// useSession marks the refresh token used and returns its session.
+// A token presented for the second time means it has been stolen, so its whole family is revoked
+func (s *Service) useSession(ctx context.Context, logCtx *erolog.ContextBuilder, token *tokens.Refresh) (*models.Session, ero.Error) {
+	session, eroErr := s.d.Sessions.Session(ctx, token.Jti)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "unknown refresh token")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeUnauthorized, ErrInvalidToken)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting session")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	case session.UserId != token.Id || session.RevokedAt != nil:
+		s.log.DebugContext(logCtx.BuildContext(), "refresh token has been revoked")
+		return nil, ero.New(logCtx.Build(), ero.CodeUnauthorized, ErrInvalidToken)
+	}
+
+	if session.UsedAt != nil {
+		return nil, s.revokeReused(ctx, logCtx, session)
+	}
+
+	eroErr = s.d.Sessions.UseSession(ctx, token.Jti)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		// another refresh with the same token has been faster
+		return nil, s.revokeReused(ctx, logCtx, session)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while using session")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return session, nil
+}
+
+func (s *Service) revokeReused(ctx context.Context, logCtx *erolog.ContextBuilder, session *models.Session) ero.Error {
+	s.log.WarnContext(logCtx.With("family_id", session.FamilyId).BuildContext(), "refresh token reuse, revoking session")
+
+	eroErr := s.d.Sessions.RevokeSessionFamily(ctx, session.UserId, session.FamilyId)
+	if eroErr != nil && !errors.Is(eroErr, storage.ErrNoRows) {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while revoking session family")
+	}
+
+	return ero.New(logCtx.Build(), ero.CodeUnauthorized, ErrTokenReused)
+}
+
+type SessionsOptions struct {
+	UserId     uint64
+	FormatDate func(time.Time) string
+}
+
+// Sessions lists devices the user is signed in from, the latest refreshed first
+func (s *Service) Sessions(ctx context.Context, opts SessionsOptions) ([]Session, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.Sessions").With("user_id", opts.UserId)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sessionsCh, errCh := s.d.Sessions.Sessions(ctx, opts.UserId)
+
+	sessions := make([]Session, 0)
+	for session := range sessionsCh {
+		sessions = append(sessions, Session{
+			Id:          session.FamilyId,
+			UserAgent:   session.Device.UserAgent,
+			Ip:          session.Device.Ip,
+			StartedAt:   opts.FormatDate(session.StartedAt),
+			RefreshedAt: opts.FormatDate(session.CreatedAt),
+			ExpiresAt:   opts.FormatDate(session.ExpiresAt),
+		})
+	}
+
+	if eroErr := <-errCh; eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting sessions")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	if len(sessions) == 0 {
+		s.log.DebugContext(logCtx.BuildContext(), "no sessions")
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, ErrNoSessions)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession signs the user out of the device, its refresh token can no longer be used
+func (s *Service) RevokeSession(ctx context.Context, userId, sessionId uint64) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.RevokeSession").With("user_id", userId).With("session_id", sessionId)
+
+	eroErr := s.d.Sessions.RevokeSessionFamily(ctx, userId, sessionId)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "session not found")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeNotFound, ErrSessionNotFound)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while revoking session")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return nil
+}
+
+// Logout revokes the session of the access token, sessionId is its sid claim,
+// and denies the token itself by jti until it is no longer accepted
+func (s *Service) Logout(ctx context.Context, userId uint64, sessionId, jti string, expiresAt time.Time) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.Logout").With("user_id", userId)
+
+	session, eroErr := s.d.Sessions.Session(ctx, sessionId)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "session of the token not found")
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting session")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	case session.UserId == userId:
+		eroErr = s.d.Sessions.RevokeSessionFamily(ctx, userId, session.FamilyId)
+		if eroErr != nil && !errors.Is(eroErr, storage.ErrNoRows) {
+			s.log.ErrorContext(eroErr.Context(ctx), "error while revoking session")
+			return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+		}
+	}
+
+	if err := s.d.Denylist.Deny(ctx, jti, tokens.AccessRegistered.AcceptedUntil(expiresAt)); err != nil {
+		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while denying access token")
+		return ero.New(logCtx.Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return nil
+}
@@ -3,40 +3,98 @@ package users
 import (
 	"context"
 	"errors"
+	"strings"
 
-	"github.com/Onnywrite/tinkoff-prod/internal/lib/tokens"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/Onnywrite/tinkoff-prod/internal/storage"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func (s *Service) SignIn(ctx context.Context, creds Credentials) (*AuthorizedUser, ero.Error) {
+// SignIn returns a challenge instead of tokens if 2FA is enabled, see SignInTwoFactor
+func (s *Service) SignIn(ctx context.Context, creds Credentials, device models.DeviceInfo) (*SignedIn, ero.Error) {
 	logCtx := erolog.NewContextBuilder().With("op", "users.Service.SignIn").WithSecret("email", creds.Email, 50)
+	emailKey := strings.ToLower(strings.TrimSpace(creds.Email))
+
+	attempt, eroErr := s.beginAttempt(logCtx, emailKey, device.Ip)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+	defer attempt.end()
 
 	user, eroErr := s.d.ByEmailProvider.UserByEmail(ctx, creds.Email)
 	switch {
 	case errors.Is(eroErr, storage.ErrNoRows):
 		s.log.DebugContext(logCtx.BuildContext(), "invalid email or password")
+		attempt.fail()
 		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeUnauthorized, ErrInvalidCredentials)
 	case eroErr != nil:
 		s.log.ErrorContext(logCtx.BuildContext(), "internal error")
 		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(creds.Password)); err != nil {
-		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "invalid email or password")
-		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeUnauthorized, ErrInvalidCredentials)
+	if eroErr = s.verifyPassword(logCtx, user, creds.Password); eroErr != nil {
+		attempt.fail()
+		return nil, eroErr
+	}
+	s.rehashPassword(ctx, logCtx, user, creds.Password)
+
+	if user.EmailVerifiedAt == nil && s.d.EmailOptions().RequireVerified {
+		s.log.DebugContext(logCtx.BuildContext(), "email is not verified")
+		return nil, ero.New(logCtx.Build(), ero.CodePermissionDenied, ErrEmailNotVerified)
+	}
+
+	twoFactor, eroErr := s.twoFactorEnabled(ctx, logCtx, user.Id)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+	// failures are reset after the second factor, otherwise the password would let codes be guessed freely
+	if twoFactor {
+		return s.challenge(logCtx, user)
 	}
 
-	pair, err := tokens.NewPair(user, 0)
-	if err != nil {
-		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while generating tokens")
-		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, ErrInternal)
+	// failures from the IP are not reset, otherwise one known password would let it guess others
+	s.d.EmailLimiter.Reset(emailKey)
+
+	authUser, eroErr := s.issue(ctx, logCtx, user, 0, device)
+	if eroErr != nil {
+		return nil, eroErr
 	}
+	return &SignedIn{AuthorizedUser: authUser}, nil
+}
 
-	return &AuthorizedUser{
-		Profile: GetProfile(user),
-		Pair:    pair,
-	}, nil
+// attempt of a sign in or of a check of a second factor, it is counted for the email and the IP together
+type attempt struct {
+	s        *Service
+	emailKey string
+	ip       string
+	failed   bool
+}
+
+// beginAttempt reserves an attempt before the credentials are checked, so parallel guesses are throttled as well.
+// The attempt must be ended, failures of unknown emails are counted too, so they cannot be told from known ones
+func (s *Service) beginAttempt(logCtx *erolog.ContextBuilder, emailKey, ip string) (*attempt, ero.Error) {
+	retryAfter := s.d.EmailLimiter.Begin(emailKey)
+	if retryAfter == 0 && ip != "" {
+		if retryAfter = s.d.IpLimiter.Begin(ip); retryAfter > 0 {
+			s.d.EmailLimiter.End(emailKey, false)
+		}
+	}
+	if retryAfter > 0 {
+		s.log.WarnContext(logCtx.With("ip", ip).BuildContext(), "attempt is throttled")
+		return nil, ero.New(logCtx.Build(), ero.CodeTooManyRequests, &TooManyAttemptsError{RetryAfter: retryAfter})
+	}
+
+	return &attempt{s: s, emailKey: emailKey, ip: ip}, nil
+}
+
+func (a *attempt) fail() {
+	a.failed = true
+}
+
+func (a *attempt) end() {
+	a.s.d.EmailLimiter.End(a.emailKey, a.failed)
+	if a.ip != "" {
+		a.s.d.IpLimiter.End(a.ip, a.failed)
+	}
 }
@@ -0,0 +1,307 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/tokens"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/totp"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+// TwoFactorOptions are got on every use, so they can be changed while the service runs
+type TwoFactorOptions struct {
+	// Issuer is shown by authenticator apps next to the email
+	Issuer       string
+	ChallengeTTL time.Duration
+}
+
+// EnrollTotp generates a new secret, 2FA is enabled after ConfirmTotp with a code of it
+func (s *Service) EnrollTotp(ctx context.Context, userId uint64) (*TotpEnrollment, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.EnrollTotp").With("user_id", userId)
+
+	user, eroErr := s.user(ctx, logCtx, userId)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while generating totp secret")
+		return nil, ero.New(logCtx.Build(), ero.CodeInternal, ErrInternal)
+	}
+	sealed, err := s.d.SecretBox.Seal([]byte(secret))
+	if err != nil {
+		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while sealing totp secret")
+		return nil, ero.New(logCtx.Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	eroErr = s.d.TwoFactor.SaveTotp(ctx, userId, sealed)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "totp is already enabled")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeExists, ErrTotpEnabled)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while saving totp")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return &TotpEnrollment{
+		Secret: secret,
+		Uri:    totp.URI(s.d.TwoFactorOptions().Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTotp enables 2FA and returns the first recovery codes
+func (s *Service) ConfirmTotp(ctx context.Context, userId uint64, code TwoFactorCode) (*RecoveryCodes, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.ConfirmTotp").With("user_id", userId)
+
+	saved, eroErr := s.totp(ctx, logCtx, userId)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+	if saved.EnabledAt != nil {
+		s.log.DebugContext(logCtx.BuildContext(), "totp is already enabled")
+		return nil, ero.New(logCtx.Build(), ero.CodeExists, ErrTotpEnabled)
+	}
+
+	step, eroErr := s.validateTotp(logCtx, saved, code.Code)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+
+	eroErr = s.d.TwoFactor.EnableTotp(ctx, userId, step)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "totp has been enabled concurrently")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeExists, ErrTotpEnabled)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while enabling totp")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return s.newRecoveryCodes(ctx, logCtx, userId)
+}
+
+// DisableTotp requires a code, so a stolen access token is not enough to turn 2FA off
+func (s *Service) DisableTotp(ctx context.Context, userId uint64, code TwoFactorCode) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.DisableTotp").With("user_id", userId)
+
+	if eroErr := s.checkOwnTwoFactor(ctx, logCtx, userId, code.Code); eroErr != nil {
+		return eroErr
+	}
+
+	eroErr := s.d.TwoFactor.DeleteTotp(ctx, userId)
+	if eroErr != nil && !errors.Is(eroErr, storage.ErrNoRows) {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while deleting totp")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, used or not
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userId uint64, code TwoFactorCode) (*RecoveryCodes, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.RegenerateRecoveryCodes").With("user_id", userId)
+
+	if eroErr := s.checkOwnTwoFactor(ctx, logCtx, userId, code.Code); eroErr != nil {
+		return nil, eroErr
+	}
+
+	return s.newRecoveryCodes(ctx, logCtx, userId)
+}
+
+// SignInTwoFactor exchanges the challenge returned by SignIn and a TOTP or recovery code for tokens.
+// Failed codes are throttled together with failed passwords of the email
+func (s *Service) SignInTwoFactor(ctx context.Context, data TwoFactorSignIn, device models.DeviceInfo) (*AuthorizedUser, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.SignInTwoFactor")
+
+	challenge, eroErr := s.parseAction(logCtx, data.Challenge, tokens.PurposeTwoFactor)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+	logCtx.With("user_id", challenge.Id)
+	emailKey := strings.ToLower(strings.TrimSpace(challenge.Email))
+
+	attempt, eroErr := s.beginAttempt(logCtx, emailKey, device.Ip)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+	defer attempt.end()
+
+	user, eroErr := s.user(ctx, logCtx, challenge.Id)
+	if eroErr != nil {
+		return nil, eroErr
+	}
+	if user.TokenGeneration != challenge.Rotation || user.Email != challenge.Email {
+		s.log.DebugContext(logCtx.BuildContext(), "password or email has been changed since the challenge")
+		return nil, ero.New(logCtx.Build(), ero.CodeUnauthorized, ErrInvalidToken)
+	}
+
+	if eroErr = s.checkTwoFactor(ctx, logCtx, user.Id, data.Code); eroErr != nil {
+		if errors.Is(eroErr, ErrInvalidCode) {
+			attempt.fail()
+		}
+		return nil, eroErr
+	}
+
+	s.d.EmailLimiter.Reset(emailKey)
+
+	return s.issue(ctx, logCtx, user, 0, device)
+}
+
+// twoFactorEnabled is checked on sign in, a failure must not let the user in without the second factor
+func (s *Service) twoFactorEnabled(ctx context.Context, logCtx *erolog.ContextBuilder, userId uint64) (bool, ero.Error) {
+	saved, eroErr := s.d.TwoFactor.Totp(ctx, userId)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		return false, nil
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting totp")
+		return false, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+	return saved.EnabledAt != nil, nil
+}
+
+func (s *Service) challenge(logCtx *erolog.ContextBuilder, user *models.User) (*SignedIn, ero.Error) {
+	if eroErr := s.notSuspended(logCtx, user); eroErr != nil {
+		return nil, eroErr
+	}
+
+	ttl := s.d.TwoFactorOptions().ChallengeTTL
+	action := tokens.Action{
+		Purpose:  tokens.PurposeTwoFactor,
+		Id:       user.Id,
+		Email:    user.Email,
+		Rotation: user.TokenGeneration,
+	}
+	token, err := action.Sign(ttl)
+	if err != nil {
+		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while signing challenge")
+		return nil, ero.New(logCtx.Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return &SignedIn{
+		TwoFactorChallenge: &TwoFactorChallenge{
+			Challenge: token,
+			ExpiresIn: int64(ttl.Seconds()),
+		},
+	}, nil
+}
+
+func (s *Service) totp(ctx context.Context, logCtx *erolog.ContextBuilder, userId uint64) (*models.Totp, ero.Error) {
+	saved, eroErr := s.d.TwoFactor.Totp(ctx, userId)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "totp is not enrolled")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeNotFound, ErrTotpNotEnabled)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while getting totp")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+	return saved, nil
+}
+
+// checkOwnTwoFactor checks a code of the signed in user. Failed codes are throttled together with failed sign ins
+// of the email, so a stolen access token is not enough to guess a code either
+func (s *Service) checkOwnTwoFactor(ctx context.Context, logCtx *erolog.ContextBuilder, userId uint64, code string) ero.Error {
+	user, eroErr := s.user(ctx, logCtx, userId)
+	if eroErr != nil {
+		return eroErr
+	}
+	emailKey := strings.ToLower(strings.TrimSpace(user.Email))
+
+	attempt, eroErr := s.beginAttempt(logCtx, emailKey, "")
+	if eroErr != nil {
+		return eroErr
+	}
+	defer attempt.end()
+
+	if eroErr = s.checkTwoFactor(ctx, logCtx, userId, code); eroErr != nil {
+		if errors.Is(eroErr, ErrInvalidCode) {
+			attempt.fail()
+		}
+		return eroErr
+	}
+
+	s.d.EmailLimiter.Reset(emailKey)
+
+	return nil
+}
+
+// checkTwoFactor accepts either a TOTP code, which cannot be replayed, or an unused recovery code
+func (s *Service) checkTwoFactor(ctx context.Context, logCtx *erolog.ContextBuilder, userId uint64, code string) ero.Error {
+	saved, eroErr := s.totp(ctx, logCtx, userId)
+	if eroErr != nil {
+		return eroErr
+	}
+	if saved.EnabledAt == nil {
+		s.log.DebugContext(logCtx.BuildContext(), "totp is not confirmed")
+		return ero.New(logCtx.Build(), ero.CodeNotFound, ErrTotpNotEnabled)
+	}
+
+	if totp.IsRecoveryCode(code) {
+		eroErr = s.d.TwoFactor.UseRecoveryCode(ctx, userId, totp.HashRecoveryCode(code))
+		switch {
+		case errors.Is(eroErr, storage.ErrNoRows):
+			s.log.DebugContext(logCtx.BuildContext(), "unknown or used recovery code")
+			return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeUnauthorized, ErrInvalidCode)
+		case eroErr != nil:
+			s.log.ErrorContext(eroErr.Context(ctx), "error while using recovery code")
+			return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+		}
+		return nil
+	}
+
+	step, eroErr := s.validateTotp(logCtx, saved, code)
+	if eroErr != nil {
+		return eroErr
+	}
+
+	eroErr = s.d.TwoFactor.UseTotpStep(ctx, userId, step)
+	switch {
+	case errors.Is(eroErr, storage.ErrNoRows):
+		s.log.DebugContext(logCtx.BuildContext(), "totp code has already been used")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeUnauthorized, ErrInvalidCode)
+	case eroErr != nil:
+		s.log.ErrorContext(eroErr.Context(ctx), "error while using totp code")
+		return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return nil
+}
+
+func (s *Service) validateTotp(logCtx *erolog.ContextBuilder, saved *models.Totp, code string) (int64, ero.Error) {
+	secret, err := s.d.SecretBox.Open(saved.Secret)
+	if err != nil {
+		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while opening totp secret")
+		return 0, ero.New(logCtx.Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	step, ok := totp.Validate(string(secret), code, time.Now())
+	if !ok {
+		s.log.DebugContext(logCtx.BuildContext(), "invalid totp code")
+		return 0, ero.New(logCtx.Build(), ero.CodeUnauthorized, ErrInvalidCode)
+	}
+	return step, nil
+}
+
+func (s *Service) newRecoveryCodes(ctx context.Context, logCtx *erolog.ContextBuilder, userId uint64) (*RecoveryCodes, ero.Error) {
+	codes, hashes, err := totp.NewRecoveryCodes()
+	if err != nil {
+		s.log.ErrorContext(logCtx.With("error", err).BuildContext(), "error while generating recovery codes")
+		return nil, ero.New(logCtx.Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	if eroErr := s.d.TwoFactor.SaveRecoveryCodes(ctx, userId, hashes); eroErr != nil {
+		s.log.ErrorContext(eroErr.Context(ctx), "error while saving recovery codes")
+		return nil, ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+	}
+
+	return &RecoveryCodes{Codes: codes}, nil
+}
@@ -0,0 +1,69 @@
+package users
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/countries"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+// Update changes only the fields set in data and returns the updated profile.
+// Pending follow requests are approved when the user becomes public
+func (s *Service) Update(ctx context.Context, id uint64, data UpdateData) (*Profile, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "users.Service.Update").With("id", id)
+
+	if err := data.Validate(); err != nil {
+		s.log.DebugContext(err.Context(ctx), "errors validating update data")
+		return nil, err
+	}
+
+	var updated *models.User
+	eroErr := s.d.Updater.WithTx(ctx, func(tx storage.Storage) ero.Error {
+		// the row is locked, so concurrent updates do not overwrite fields of each other
+		user, eroErr := tx.LockUser(ctx, id)
+		switch {
+		case errors.Is(eroErr, storage.ErrNoRows):
+			s.log.DebugContext(logCtx.BuildContext(), "user not found")
+			return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeNotFound, ErrUserNotFound)
+		case eroErr != nil:
+			s.log.ErrorContext(eroErr.Context(ctx), "error while locking user")
+			return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+		}
+
+		becomesPublic := !user.IsPublic && data.IsPublic != nil && *data.IsPublic
+		data.Apply(user)
+
+		updated, eroErr = tx.UpdateUser(ctx, user)
+		switch {
+		case errors.Is(eroErr, storage.ErrNoRows):
+			s.log.DebugContext(logCtx.BuildContext(), "user not found")
+			return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeNotFound, ErrUserNotFound)
+		case errors.Is(eroErr, storage.ErrForeignKeyConstraint):
+			s.log.DebugContext(logCtx.BuildContext(), "country with given id does not exist")
+			return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeNotFound, countries.ErrCountryNotFound)
+		case eroErr != nil:
+			s.log.ErrorContext(eroErr.Context(ctx), "error while updating user")
+			return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+		}
+
+		// a public user has no one to approve, so pending requests become follows
+		if becomesPublic {
+			if eroErr = tx.ApproveFollows(ctx, id); eroErr != nil {
+				s.log.ErrorContext(eroErr.Context(ctx), "error while approving pending follows")
+				return ero.New(logCtx.With("error", eroErr).Build(), ero.CodeInternal, ErrInternal)
+			}
+		}
+
+		return nil
+	})
+	if eroErr != nil {
+		return nil, eroErr
+	}
+
+	profile := GetProfile(updated)
+	return &profile, nil
+}
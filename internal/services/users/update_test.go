@@ -0,0 +1,64 @@
+package users_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/services/users"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdate(t *testing.T) {
+	public, private := true, false
+	tests := []struct {
+		name     string
+		isPublic *bool
+		approved bool
+	}{
+		{name: "becomes public", isPublic: &public, approved: true},
+		{name: "stays private", isPublic: &private},
+		{name: "name only"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			db := memory.New()
+			s := users.New(slog.New(slog.NewTextHandler(io.Discard, nil)), users.Dependencies{
+				ByIdProvider: db,
+				Updater:      db,
+			})
+
+			var ids [2]uint64
+			for i, email := range []string{"private@example.com", "follower@example.com"} {
+				user, eroErr := db.SaveUser(context.Background(), &models.User{
+					Name:     "Name",
+					Lastname: "Lastname",
+					Email:    email,
+					Country:  models.Country{Id: 1},
+					Birthday: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+				})
+				require.Nil(tt, eroErr)
+				ids[i] = user.Id
+			}
+			userId, followerId := ids[0], ids[1]
+			require.Nil(tt, db.SaveFollow(context.Background(), models.Follow{
+				Follower: models.User{Id: followerId},
+				Followee: models.User{Id: userId},
+			}))
+
+			name := "Another"
+			profile, err := s.Update(context.Background(), userId, users.UpdateData{Name: &name, IsPublic: tc.isPublic})
+			require.Nil(tt, err)
+			assert.Equal(tt, name, profile.Name)
+
+			follow, err := db.Follow(context.Background(), followerId, userId)
+			require.Nil(tt, err)
+			assert.Equal(tt, tc.approved, follow.ApprovedAt != nil)
+		})
+	}
+}
@@ -3,8 +3,13 @@ package users
 import (
 	"context"
 	"log/slog"
+	"time"
 
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/mailer"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/oidc"
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/passpolicy"
 	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 )
 
@@ -26,10 +31,131 @@ type UserSaver interface {
 	SaveUser(ctx context.Context, user *models.User) (*models.User, ero.Error)
 }
 
+type PasswordUpdater interface {
+	UpdatePassword(ctx context.Context, userId uint64, hash string) (uint64, ero.Error)
+	RehashPassword(ctx context.Context, userId uint64, oldHash, newHash string) ero.Error
+}
+
+// PasswordHasher hashes with the current algorithm and verifies hashes of any supported one, see passhash.Hasher
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(hash, password string) error
+	NeedsRehash(hash string) bool
+}
+
+// PasswordPolicy tells what is wrong with a new password, see passpolicy.Policy
+type PasswordPolicy interface {
+	Check(password string, user passpolicy.UserInfo) ([]string, error)
+}
+
+// SessionsStorage keeps issued refresh tokens, see models.Session
+type SessionsStorage interface {
+	SaveSession(ctx context.Context, session *models.Session) (uint64, ero.Error)
+	Session(ctx context.Context, jti string) (*models.Session, ero.Error)
+	UseSession(ctx context.Context, jti string) ero.Error
+	RevokeSessionFamily(ctx context.Context, userId, familyId uint64) ero.Error
+	RevokeSessions(ctx context.Context, userId uint64) ero.Error
+	Sessions(ctx context.Context, userId uint64) (<-chan models.Session, <-chan ero.Error)
+}
+
+// PersonalTokensStorage keeps hashes of personal access tokens, see models.PersonalToken
+type PersonalTokensStorage interface {
+	SavePersonalToken(ctx context.Context, token *models.PersonalToken) (uint64, ero.Error)
+	PersonalTokenByHash(ctx context.Context, hash string) (*models.PersonalToken, ero.Error)
+	UsePersonalToken(ctx context.Context, id uint64) ero.Error
+	RevokePersonalToken(ctx context.Context, userId, id uint64) ero.Error
+	PersonalTokens(ctx context.Context, userId uint64) (<-chan models.PersonalToken, <-chan ero.Error)
+}
+
+// TwoFactorStorage keeps sealed TOTP secrets and hashes of recovery codes, see models.Totp
+type TwoFactorStorage interface {
+	SaveTotp(ctx context.Context, userId uint64, secret string) ero.Error
+	Totp(ctx context.Context, userId uint64) (*models.Totp, ero.Error)
+	EnableTotp(ctx context.Context, userId uint64, step int64) ero.Error
+	UseTotpStep(ctx context.Context, userId uint64, step int64) ero.Error
+	DeleteTotp(ctx context.Context, userId uint64) ero.Error
+	SaveRecoveryCodes(ctx context.Context, userId uint64, hashes []string) ero.Error
+	UseRecoveryCode(ctx context.Context, userId uint64, hash string) ero.Error
+}
+
+// SecretSealer encrypts secrets at rest, see secretbox.Box
+type SecretSealer interface {
+	Seal(plaintext []byte) (string, error)
+	Open(sealed string) ([]byte, error)
+}
+
+// OidcProviders signs in with OpenID Connect providers by their names, see oidc.Providers
+type OidcProviders interface {
+	AuthCodeURL(ctx context.Context, provider string) (url string, binding string, err error)
+	Exchange(ctx context.Context, provider, code, state, binding string) (*oidc.Identity, error)
+}
+
+// IdentitiesStorage links users to accounts at providers, see models.Identity
+type IdentitiesStorage interface {
+	SaveIdentity(ctx context.Context, identity *models.Identity) (uint64, ero.Error)
+	Identity(ctx context.Context, issuer, subject string) (*models.Identity, ero.Error)
+}
+
+// AttemptsLimiter slows down failed sign ins of a key, see throttle.Limiter
+type AttemptsLimiter interface {
+	Begin(key string) time.Duration
+	End(key string, failed bool) time.Duration
+	Reset(key string)
+}
+
+// EmailVerifier sets the email of the user verified, if it is still the same and not verified yet
+type EmailVerifier interface {
+	VerifyEmail(ctx context.Context, userId uint64, email string) ero.Error
+}
+
+// TokenDenier revokes access tokens before they expire, see tokens.Denylist
+type TokenDenier interface {
+	Deny(ctx context.Context, jti string, until time.Time) error
+	DenyUser(ctx context.Context, userId uint64, issuedBefore, until time.Time) error
+}
+
+// AdminStorage lists users and changes what only moderators and admins can
+type AdminStorage interface {
+	Users(ctx context.Context, query string, offset, count int) (<-chan models.User, <-chan ero.Error)
+	UsersNum(ctx context.Context, query string) (uint64, ero.Error)
+	SuspendUser(ctx context.Context, userId uint64) ero.Error
+	UnsuspendUser(ctx context.Context, userId uint64) ero.Error
+	SetRole(ctx context.Context, userId uint64, role models.Role) ero.Error
+}
+
+type FollowProvider interface {
+	Follow(ctx context.Context, followerId, followeeId uint64) (*models.Follow, ero.Error)
+}
+
 type Dependencies struct {
 	ByIdProvider    UserByIdProvider
 	ByEmailProvider UserByEmailProvider
 	Saver           UserSaver
+	// Updater runs profile updates in transactions
+	Updater         storage.Transactor
+	PasswordUpdater PasswordUpdater
+	Passwords       PasswordHasher
+	PasswordPolicy  PasswordPolicy
+	Sessions        SessionsStorage
+	PersonalTokens  PersonalTokensStorage
+	// Denylist revokes access tokens on logout, suspension and change of role
+	Denylist TokenDenier
+	// FollowProvider grants access to private profiles
+	FollowProvider FollowProvider
+	// EmailLimiter and IpLimiter throttle failed sign ins
+	EmailLimiter AttemptsLimiter
+	IpLimiter    AttemptsLimiter
+	// Mailer sends verification and password reset links, see EmailOptions
+	Mailer        mailer.Mailer
+	EmailVerifier EmailVerifier
+	EmailOptions  func() EmailOptions
+	// TwoFactor secrets are sealed with SecretBox
+	TwoFactor        TwoFactorStorage
+	SecretBox        SecretSealer
+	TwoFactorOptions func() TwoFactorOptions
+	Oidc             OidcProviders
+	Identities       IdentitiesStorage
+	Admin            AdminStorage
 }
 
 func New(log *slog.Logger, deps Dependencies) *Service {
@@ -3,6 +3,7 @@ package users
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -20,11 +21,82 @@ type Credentials struct {
 	Password string `json:"password"`
 }
 
+// AuthorizedUser has no tokens after registration if the email must be verified first
 type AuthorizedUser struct {
 	Profile Profile `json:"profile"`
 	tokens.Pair
 }
 
+// SignedIn has either tokens or a challenge, if the user has enabled 2FA
+type SignedIn struct {
+	*AuthorizedUser
+	*TwoFactorChallenge
+}
+
+type TwoFactorChallenge struct {
+	Challenge tokens.ActionString `json:"challenge"`
+	ExpiresIn int64               `json:"expires_in"`
+}
+
+type TwoFactorSignIn struct {
+	Challenge tokens.ActionString `json:"challenge"`
+	Code      string              `json:"code"`
+}
+
+// TwoFactorCode is either a TOTP code or a recovery code
+type TwoFactorCode struct {
+	Code string `json:"code"`
+}
+
+type TotpEnrollment struct {
+	Secret string `json:"secret"`
+	Uri    string `json:"otpauth_uri"`
+}
+
+// RecoveryCodes are in the response only, they cannot be got again
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+type OidcAuthorization struct {
+	AuthorizationUrl string `json:"authorization_url"`
+	// Binding must be kept by the client, e.g. in session storage, and sent with the callback
+	Binding string `json:"binding"`
+}
+
+// OidcCallback is what the provider has redirected the user with and the binding of the sign in
+type OidcCallback struct {
+	Code    string `json:"code"`
+	State   string `json:"state"`
+	Binding string `json:"binding"`
+}
+
+type Session struct {
+	Id        uint64 `json:"id"`
+	UserAgent string `json:"user_agent"`
+	Ip        string `json:"ip"`
+	StartedAt string `json:"started_at"`
+	// RefreshedAt is when the current refresh token of the session has been issued
+	RefreshedAt string `json:"refreshed_at"`
+	ExpiresAt   string `json:"expires_at"`
+}
+
+type PersonalToken struct {
+	Id        uint64   `json:"id"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	CreatedAt string   `json:"created_at"`
+	// ExpiresAt is null for tokens which never expire
+	ExpiresAt  *string `json:"expires_at"`
+	LastUsedAt *string `json:"last_used_at"`
+}
+
+// CreatedPersonalToken is the only response containing the token itself
+type CreatedPersonalToken struct {
+	PersonalToken
+	Token string `json:"token"`
+}
+
 type PrivateProfile struct {
 	Id       uint64 `json:"id"`
 	Name     string `json:"name"`
@@ -33,14 +105,16 @@ type PrivateProfile struct {
 }
 
 type Profile struct {
-	Id       uint64         `json:"id"`
-	Name     string         `json:"name"`
-	Lastname string         `json:"surname"`
-	Email    string         `json:"email"`
-	Country  models.Country `json:"country"`
-	IsPublic bool           `json:"is_public"`
-	Image    string         `json:"image"`
-	Birthday string         `json:"birthday"`
+	Id            uint64         `json:"id"`
+	Name          string         `json:"name"`
+	Lastname      string         `json:"surname"`
+	Email         string         `json:"email"`
+	EmailVerified bool           `json:"email_verified"`
+	Country       models.Country `json:"country"`
+	IsPublic      bool           `json:"is_public"`
+	Image         string         `json:"image"`
+	// Birthday is omitted if it is unknown, e.g. of users provisioned by an identity provider
+	Birthday string `json:"birthday,omitempty"`
 }
 
 func GetPrivateProfile(user *models.User) PrivateProfile {
@@ -53,16 +127,20 @@ func GetPrivateProfile(user *models.User) PrivateProfile {
 }
 
 func GetProfile(user *models.User) Profile {
-	return Profile{
-		Id:       user.Id,
-		Name:     user.Name,
-		Lastname: user.Lastname,
-		Email:    user.Email,
-		Country:  user.Country,
-		IsPublic: user.IsPublic,
-		Image:    user.Image,
-		Birthday: user.Birthday.Format(time.DateOnly),
+	profile := Profile{
+		Id:            user.Id,
+		Name:          user.Name,
+		Lastname:      user.Lastname,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Country:       user.Country,
+		IsPublic:      user.IsPublic,
+		Image:         user.Image,
 	}
+	if !user.Birthday.IsZero() {
+		profile.Birthday = user.Birthday.Format(time.DateOnly)
+	}
+	return profile
 }
 
 type RegisterData struct {
@@ -81,64 +159,117 @@ var (
 	emailRegex = regexp.MustCompile(`^[a-z0-9._-]+@[a-z0-9.-]+\.[a-z]{2,4}$`)
 )
 
-func (d *RegisterData) Validate() ero.Error {
+type UpdateData struct {
+	Name      *string `json:"name"`
+	Lastname  *string `json:"surname"`
+	Image     *string `json:"image"`
+	CountryId *uint64 `json:"country_id"`
+	IsPublic  *bool   `json:"is_public"`
+}
+
+const defaultImage = "https://th.bing.com/th/id/R.0f176a0452d52cf716b2391db3ceb7e9?rik=yQN6JCCMB7a4QQ"
+
+// fieldErrors maps json field names to validation messages
+type fieldErrors map[string][]string
+
+func (errorsMap fieldErrors) add(field, msg string) {
+	errorsMap[field] = append(errorsMap[field], msg)
+}
 
+func (errorsMap fieldErrors) toEro() ero.Error {
 	type fieldError struct {
 		Field    string   `json:"field"`
 		Messages []string `json:"messages"`
 	}
 
-	formatName := func(name string) string {
-		runes := []rune(strings.ToLower(name))
-		runes[0] = unicode.ToUpper(runes[0])
-		for i := 1; i < len(runes); i++ {
-			if runes[i-1] == '-' {
-				runes[i] = unicode.ToUpper(runes[i])
-			}
+	if len(errorsMap) == 0 {
+		return nil
+	}
+
+	errors := make([]fieldError, 0, 4)
+	fields := make([]string, 0, 4)
+	for field, msgs := range errorsMap {
+		if len(msgs) > 0 {
+			errors = append(errors, fieldError{
+				Field:    field,
+				Messages: msgs,
+			})
+			fields = append(fields, field)
 		}
-		return string(runes)
 	}
 
-	errorsMap := make(map[string][]string)
+	return ero.NewValidation(erolog.NewContextBuilder().With("fields", fields).Build(), errors)
+}
 
-	if utf8.RuneCountInString(d.Name) > 32 {
-		errorsMap["name"] = append(errorsMap["name"], "too long, must be less than 32 characters")
-	}
-	if nameRegex.MatchString(d.Name) {
-		d.Name = formatName(d.Name)
-	} else {
-		errorsMap["name"] = append(errorsMap["name"], "invalid characters set")
+func formatName(name string) string {
+	runes := []rune(strings.ToLower(name))
+	runes[0] = unicode.ToUpper(runes[0])
+	for i := 1; i < len(runes); i++ {
+		if runes[i-1] == '-' {
+			runes[i] = unicode.ToUpper(runes[i])
+		}
 	}
+	return string(runes)
+}
+
+const maxNameLength = 32
 
-	if utf8.RuneCountInString(d.Lastname) > 32 {
-		errorsMap["surname"] = append(errorsMap["surname"], "too long, must be less than 32 characters")
+// validateName checks the length and characters of name and formats it in place
+func (errorsMap fieldErrors) validateName(field string, name *string) {
+	if utf8.RuneCountInString(*name) > maxNameLength {
+		errorsMap.add(field, "too long, must be less than 32 characters")
 	}
-	if nameRegex.MatchString(d.Lastname) {
-		d.Lastname = formatName(d.Lastname)
+	if nameRegex.MatchString(*name) {
+		*name = formatName(*name)
 	} else {
-		errorsMap["surname"] = append(errorsMap["surname"], "invalid characters set")
+		errorsMap.add(field, "invalid characters set")
 	}
+}
 
-	if !emailRegex.MatchString(d.Email) {
-		errorsMap["email"] = append(errorsMap["email"], "invalid email")
+func (errorsMap fieldErrors) validateBirthday(field string, birthday time.Time) {
+	if time.Now().Before(birthday) {
+		errorsMap.add(field, "you haven't born yet")
 	}
+	if birthday.Before(time.Date(1945, time.September, 2, 0, 0, 0, 0, time.UTC)) {
+		errorsMap.add(field, "you must have born after WW2")
+	}
+}
 
-	if utf8.RuneCountInString(d.Password) < 8 {
-		errorsMap["password"] = append(errorsMap["password"], "too short, must be at least 8 characters")
+// maxPasswordLength is in bytes, bcrypt hashes only 72 of them, see Service.hashPassword
+const maxPasswordLength = 256
+
+func (errorsMap fieldErrors) validatePassword(field, password string) {
+	if utf8.RuneCountInString(password) < 8 {
+		errorsMap.add(field, "too short, must be at least 8 characters")
 	}
-	if len(d.Password) > 72 {
-		errorsMap["password"] = append(errorsMap["password"], "too long, must be less than or equals 72 bytes")
+	// hashing costs the same for any length, the limit only keeps requests small
+	if len(password) > maxPasswordLength {
+		errorsMap.add(field, fmt.Sprintf("too long, must be less than or equals %d bytes", maxPasswordLength))
 	}
+}
 
-	if time.Now().Before(time.Time(d.Birthday)) {
-		errorsMap["birthday"] = append(errorsMap["birthday"], "you haven't born yet")
-	}
-	if time.Time(d.Birthday).Before(time.Date(1945, time.September, 2, 0, 0, 0, 0, time.UTC)) {
-		errorsMap["birthday"] = append(errorsMap["birthday"], "you must have born after WW2")
+func (d *RegisterData) Validate() ero.Error {
+	errorsMap := make(fieldErrors)
+
+	errorsMap.validateName("name", &d.Name)
+	errorsMap.validateName("surname", &d.Lastname)
+
+	if !emailRegex.MatchString(d.Email) {
+		errorsMap.add("email", "invalid email")
 	}
 
+	errorsMap.validatePassword("password", d.Password)
+	errorsMap.validateBirthday("birthday", time.Time(d.Birthday))
+
+	d.setDefaults()
+
+	return errorsMap.toEro()
+}
+
+// setDefaults fills the optional fields which are not set
+func (d *RegisterData) setDefaults() {
 	if d.Image == "" {
-		d.Image = "https://th.bing.com/th/id/R.0f176a0452d52cf716b2391db3ceb7e9?rik=yQN6JCCMB7a4QQ"
+		d.Image = defaultImage
 	}
 	if d.CountryId == 0 || d.CountryId > 249 {
 		// errorsMap["country_id"] = append(errorsMap["country_id"], "invalid country id")
@@ -148,24 +279,145 @@ func (d *RegisterData) Validate() ero.Error {
 		d.IsPublic = new(bool)
 		*d.IsPublic = true
 	}
+}
 
-	if len(errorsMap) == 0 {
-		return nil
+// Validate checks only the fields which are set, using the same rules as RegisterData.Validate
+func (d *UpdateData) Validate() ero.Error {
+	errorsMap := make(fieldErrors)
+
+	if d.Name != nil {
+		errorsMap.validateName("name", d.Name)
+	}
+	if d.Lastname != nil {
+		errorsMap.validateName("surname", d.Lastname)
+	}
+	if d.Image != nil && *d.Image == "" {
+		*d.Image = defaultImage
+	}
+	if d.CountryId != nil && *d.CountryId == 0 {
+		errorsMap.add("country_id", "invalid country id")
 	}
 
-	errors := make([]fieldError, 0, 4)
-	fields := make([]string, 0, 4)
-	for field, msgs := range errorsMap {
-		if len(msgs) > 0 {
-			errors = append(errors, fieldError{
-				Field:    field,
-				Messages: msgs,
-			})
-			fields = append(fields, field)
+	return errorsMap.toEro()
+}
+
+type PasswordChange struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+func (d *PasswordChange) Validate() ero.Error {
+	errorsMap := make(fieldErrors)
+
+	errorsMap.validatePassword("new_password", d.NewPassword)
+
+	return errorsMap.toEro()
+}
+
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+// ActionConfirm carries a token sent by email
+type ActionConfirm struct {
+	Token tokens.ActionString `json:"token"`
+}
+
+type PasswordReset struct {
+	Token       tokens.ActionString `json:"token"`
+	NewPassword string              `json:"new_password"`
+}
+
+func (d *PasswordReset) Validate() ero.Error {
+	errorsMap := make(fieldErrors)
+
+	errorsMap.validatePassword("new_password", d.NewPassword)
+
+	return errorsMap.toEro()
+}
+
+type PersonalTokenData struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresInDays is optional, tokens without it never expire
+	ExpiresInDays *uint64 `json:"expires_in_days"`
+}
+
+func (d *PersonalTokenData) Validate() ero.Error {
+	errorsMap := make(fieldErrors)
+
+	d.Name = strings.TrimSpace(d.Name)
+	if l := utf8.RuneCountInString(d.Name); l == 0 || l > 64 {
+		errorsMap.add("name", "must contain from 1 to 64 characters")
+	}
+
+	if len(d.Scopes) == 0 {
+		errorsMap.add("scopes", "at least one scope is required")
+	}
+	for _, scope := range d.Scopes {
+		if !tokens.IsPersonalScope(scope) {
+			errorsMap.add("scopes", fmt.Sprintf("unknown scope '%s'", scope))
 		}
 	}
+	slices.Sort(d.Scopes)
+	d.Scopes = slices.Compact(d.Scopes)
 
-	return ero.NewValidation(erolog.NewContextBuilder().With("fields", fields).Build(), errors)
+	if d.ExpiresInDays != nil && (*d.ExpiresInDays == 0 || *d.ExpiresInDays > 3650) {
+		errorsMap.add("expires_in_days", "must be from 1 to 3650")
+	}
+
+	return errorsMap.toEro()
+}
+
+// AdminUser is a Profile with what moderators see
+type AdminUser struct {
+	Profile
+	Role models.Role `json:"role"`
+	// SuspendedAt is nil unless the user is suspended
+	SuspendedAt *string `json:"suspended_at"`
+}
+
+type Page[T any] struct {
+	First   uint64 `json:"first"`
+	Current uint64 `json:"current"`
+	Last    uint64 `json:"last"`
+	Count   uint64 `json:"count"`
+	Users   []T    `json:"users"`
+}
+
+type PagedUsers Page[AdminUser]
+
+type RoleChange struct {
+	Role models.Role `json:"role"`
+}
+
+func (d *RoleChange) Validate() ero.Error {
+	errorsMap := make(fieldErrors)
+
+	if !d.Role.Valid() {
+		errorsMap.add("role", fmt.Sprintf("must be one of '%s', '%s' or '%s'", models.RoleUser, models.RoleModerator, models.RoleAdmin))
+	}
+
+	return errorsMap.toEro()
+}
+
+// Apply sets the fields of the user which are set in d
+func (d *UpdateData) Apply(user *models.User) {
+	if d.Name != nil {
+		user.Name = *d.Name
+	}
+	if d.Lastname != nil {
+		user.Lastname = *d.Lastname
+	}
+	if d.Image != nil {
+		user.Image = *d.Image
+	}
+	if d.CountryId != nil {
+		user.Country = models.Country{Id: *d.CountryId}
+	}
+	if d.IsPublic != nil {
+		user.IsPublic = *d.IsPublic
+	}
 }
 
 type dateOnly time.Time
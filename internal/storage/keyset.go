@@ -0,0 +1,12 @@
+package storage
+
+import "time"
+
+// Keyset points to a row of a list ordered by (Time DESC, Id DESC).
+// Rows after it are selected, or rows before it if Backward is true.
+// Either way rows are returned in the list order
+type Keyset struct {
+	Time     time.Time
+	Id       uint64
+	Backward bool
+}
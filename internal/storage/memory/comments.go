@@ -0,0 +1,212 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (m *MemStorage) SaveComment(ctx context.Context, comment *models.Comment) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "memory.MemStorage.SaveComment").With("post_id", comment.PostId).With("author_id", comment.Author.Id)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, postOk := m.posts[comment.PostId]
+	_, userOk := m.users[comment.Author.Id]
+	if !postOk || !userOk {
+		return 0, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrForeignKeyConstraint)
+	}
+	if comment.ParentId != nil {
+		if _, ok := m.comments[*comment.ParentId]; !ok {
+			return 0, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrForeignKeyConstraint)
+		}
+	}
+
+	m.lastCommentId++
+	saved := models.Comment{
+		Id:        m.lastCommentId,
+		PostId:    comment.PostId,
+		Author:    models.User{Id: comment.Author.Id},
+		Content:   comment.Content,
+		CreatedAt: now(),
+	}
+	if comment.ParentId != nil {
+		parentId := *comment.ParentId
+		saved.ParentId = &parentId
+	}
+	m.comments[saved.Id] = saved
+
+	return saved.Id, nil
+}
+
+func (m *MemStorage) Comment(ctx context.Context, id uint64) (*models.Comment, ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "memory.MemStorage.Comment").With("comment_id", id)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.comments[id]
+	if _, deleted := m.deletedPosts[c.PostId]; !ok || deleted {
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+	c.Author = m.users[c.Author.Id]
+
+	return &c, nil
+}
+
+func (m *MemStorage) UpdateComment(ctx context.Context, comment *models.Comment) ero.Error {
+	return m.changeComment(ctx, "memory.MemStorage.UpdateComment", comment.Id, func(c *models.Comment) {
+		updatedAt := now()
+		c.Content = comment.Content
+		c.UpdatedAt = &updatedAt
+	})
+}
+
+func (m *MemStorage) DeleteComment(ctx context.Context, id uint64) ero.Error {
+	return m.changeComment(ctx, "memory.MemStorage.DeleteComment", id, func(c *models.Comment) {
+		deletedAt := now()
+		c.DeletedAt = &deletedAt
+	})
+}
+
+func (m *MemStorage) changeComment(ctx context.Context, op string, id uint64, change func(*models.Comment)) ero.Error {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", op).With("comment_id", id)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.comments[id]
+	if !ok || c.DeletedAt != nil {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	change(&c)
+	m.comments[id] = c
+	return nil
+}
+
+func (m *MemStorage) Comments(ctx context.Context, offset, count int, postId uint64) (<-chan models.Comment, <-chan ero.Error) {
+	m.mu.RLock()
+	selected := m.selectComments(ofPost(postId))
+	m.mu.RUnlock()
+
+	return sendComments(ctx, offsetPage[models.Comment](offset, count)(selected))
+}
+
+func (m *MemStorage) RootComments(ctx context.Context, offset, count int, postId uint64) (<-chan models.Comment, <-chan ero.Error) {
+	m.mu.RLock()
+	selected := m.selectComments(isRootOf(postId))
+	m.mu.RUnlock()
+
+	return sendComments(ctx, offsetPage[models.Comment](offset, count)(selected))
+}
+
+func (m *MemStorage) Replies(ctx context.Context, rootIds []uint64) (<-chan models.Comment, <-chan ero.Error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	inTree := make(map[uint64]bool, len(rootIds))
+	for _, id := range rootIds {
+		inTree[id] = true
+	}
+
+	// comments are ordered by creation, so parents always go before their replies
+	all := m.selectComments(func(*models.Comment) bool { return true })
+	selected := make([]models.Comment, 0)
+	for _, c := range all {
+		if c.ParentId != nil && inTree[*c.ParentId] {
+			inTree[c.Id] = true
+			selected = append(selected, c)
+		}
+	}
+
+	return sendComments(ctx, selected)
+}
+
+func (m *MemStorage) CommentsNum(ctx context.Context, postId uint64) (uint64, ero.Error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return uint64(len(m.selectComments(ofPost(postId)))), nil
+}
+
+func (m *MemStorage) RootCommentsNum(ctx context.Context, postId uint64) (uint64, ero.Error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return uint64(len(m.selectComments(isRootOf(postId)))), nil
+}
+
+func (m *MemStorage) PostsCommentsNum(ctx context.Context, postIds []uint64) (map[uint64]uint64, ero.Error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := make(map[uint64]uint64, len(postIds))
+	for _, c := range m.comments {
+		if c.DeletedAt == nil && slices.Contains(postIds, c.PostId) {
+			res[c.PostId]++
+		}
+	}
+
+	return res, nil
+}
+
+func ofPost(postId uint64) func(*models.Comment) bool {
+	return func(c *models.Comment) bool {
+		return c.PostId == postId
+	}
+}
+
+func isRootOf(postId uint64) func(*models.Comment) bool {
+	return func(c *models.Comment) bool {
+		return c.PostId == postId && c.ParentId == nil
+	}
+}
+
+// selectComments joins comments of not deleted posts with their authors,
+// filters them and orders by created_at, id.
+// m.mu must be held by caller
+func (m *MemStorage) selectComments(where func(*models.Comment) bool) []models.Comment {
+	selected := make([]models.Comment, 0)
+	for _, c := range m.comments {
+		if _, deleted := m.deletedPosts[c.PostId]; deleted || !where(&c) {
+			continue
+		}
+		c.Author = m.users[c.Author.Id]
+		selected = append(selected, c)
+	}
+
+	slices.SortFunc(selected, func(a, b models.Comment) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return compareIds(a.Id, b.Id)
+	})
+
+	return selected
+}
+
+func sendComments(ctx context.Context, selected []models.Comment) (<-chan models.Comment, <-chan ero.Error) {
+	comments := make(chan models.Comment, 10)
+	errChan := make(chan ero.Error, 1)
+
+	go func() {
+		defer close(comments)
+		defer close(errChan)
+
+		for _, c := range selected {
+			select {
+			case comments <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return comments, errChan
+}
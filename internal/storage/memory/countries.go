@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (m *MemStorage) Countries(ctx context.Context, regions ...string) ([]models.Country, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.Countries").With("regions", regions)
+
+	if len(regions) == 0 {
+		return m.AllCountries(ctx)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	countries := make([]models.Country, 0, 256)
+	for _, c := range m.countries {
+		if slices.Contains(regions, c.Region) {
+			countries = append(countries, c)
+		}
+	}
+
+	if len(countries) == 0 {
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	sortByAlpha2(countries)
+	return countries, nil
+}
+
+func (m *MemStorage) AllCountries(ctx context.Context) ([]models.Country, ero.Error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	countries := slices.Clone(m.countries)
+	sortByAlpha2(countries)
+	return countries, nil
+}
+
+func (m *MemStorage) Country(ctx context.Context, alpha2 string) (models.Country, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.Country").With("alpha2", alpha2)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, c := range m.countries {
+		if c.Alpha2 == alpha2 {
+			return c, nil
+		}
+	}
+
+	return models.Country{}, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+}
+
+func sortByAlpha2(countries []models.Country) {
+	slices.SortStableFunc(countries, func(a, b models.Country) int {
+		return strings.Compare(a.Alpha2, b.Alpha2)
+	})
+}
@@ -0,0 +1,238 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (m *MemStorage) SaveFollow(ctx context.Context, follow models.Follow) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.SaveFollow").With("follower_id", follow.Follower.Id).With("followee_id", follow.Followee.Id)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := followKey{followerId: follow.Follower.Id, followeeId: follow.Followee.Id}
+	if _, ok := m.follows[key]; ok {
+		return ero.New(logCtx.Build(), ero.CodeExists, storage.ErrUniqueConstraint)
+	}
+	if key.followerId == key.followeeId {
+		return ero.New(logCtx.Build(), ero.CodeBadRequest, storage.ErrCheckConstraint)
+	}
+	_, followerOk := m.users[key.followerId]
+	_, followeeOk := m.users[key.followeeId]
+	if !followerOk || !followeeOk {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrForeignKeyConstraint)
+	}
+
+	m.follows[key] = followDates{followedAt: now(), approvedAt: follow.ApprovedAt}
+	return nil
+}
+
+func (m *MemStorage) Follow(ctx context.Context, followerId, followeeId uint64) (*models.Follow, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.Follow").With("follower_id", followerId).With("followee_id", followeeId)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	dates, ok := m.follows[followKey{followerId: followerId, followeeId: followeeId}]
+	if !ok {
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	return &models.Follow{
+		Follower:   models.User{Id: followerId},
+		Followee:   models.User{Id: followeeId},
+		FollowedAt: dates.followedAt,
+		ApprovedAt: dates.approvedAt,
+	}, nil
+}
+
+func (m *MemStorage) ApproveFollow(ctx context.Context, follow models.Follow) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.ApproveFollow").With("follower_id", follow.Follower.Id).With("followee_id", follow.Followee.Id)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := followKey{followerId: follow.Follower.Id, followeeId: follow.Followee.Id}
+	dates, ok := m.follows[key]
+	if !ok || dates.approvedAt != nil {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	approvedAt := now()
+	dates.approvedAt = &approvedAt
+	m.follows[key] = dates
+	return nil
+}
+
+func (m *MemStorage) ApproveFollows(ctx context.Context, followeeId uint64) ero.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	approvedAt := now()
+	for key, dates := range m.follows {
+		if key.followeeId == followeeId && dates.approvedAt == nil {
+			dates.approvedAt = &approvedAt
+			m.follows[key] = dates
+		}
+	}
+	return nil
+}
+
+func (m *MemStorage) DeleteFollow(ctx context.Context, follow models.Follow) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.DeleteFollow").With("follower_id", follow.Follower.Id).With("followee_id", follow.Followee.Id)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := followKey{followerId: follow.Follower.Id, followeeId: follow.Followee.Id}
+	if _, ok := m.follows[key]; !ok {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	delete(m.follows, key)
+	return nil
+}
+
+func (m *MemStorage) Followers(ctx context.Context, offset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error) {
+	return m.followsBy(ctx, offsetPage[models.Follow](offset, count), userId, followersSide)
+}
+
+func (m *MemStorage) FollowersByKeyset(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error) {
+	return m.followsBy(ctx, keysetPage(keyset, count, followerKeyOf), userId, followersSide)
+}
+
+func (m *MemStorage) Followings(ctx context.Context, offset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error) {
+	return m.followsBy(ctx, offsetPage[models.Follow](offset, count), userId, followingsSide)
+}
+
+func (m *MemStorage) FollowingsByKeyset(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error) {
+	return m.followsBy(ctx, keysetPage(keyset, count, followeeKeyOf), userId, followingsSide)
+}
+
+func (m *MemStorage) FollowRequests(ctx context.Context, offset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error) {
+	return m.followsBy(ctx, offsetPage[models.Follow](offset, count), userId, requestsSide)
+}
+
+func (m *MemStorage) FollowRequestsByKeyset(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error) {
+	return m.followsBy(ctx, keysetPage(keyset, count, followerKeyOf), userId, requestsSide)
+}
+
+// followSide tells which follows of a user are selected
+type followSide struct {
+	// followers selects who follows the user, otherwise whom the user follows
+	followers bool
+	// approved is true for follows, false for pending requests
+	approved bool
+}
+
+var (
+	followersSide  = followSide{followers: true, approved: true}
+	followingsSide = followSide{followers: false, approved: true}
+	requestsSide   = followSide{followers: true, approved: false}
+)
+
+func followerKeyOf(follow models.Follow) (time.Time, uint64) {
+	return follow.FollowedAt, follow.Follower.Id
+}
+
+func followeeKeyOf(follow models.Follow) (time.Time, uint64) {
+	return follow.FollowedAt, follow.Followee.Id
+}
+
+func (m *MemStorage) followsBy(ctx context.Context, page func([]models.Follow) []models.Follow, userId uint64, side followSide) (<-chan models.Follow, <-chan ero.Error) {
+	follows := make(chan models.Follow, 10)
+	errChan := make(chan ero.Error, 1)
+
+	m.mu.RLock()
+	selected := m.selectFollows(userId, side)
+	m.mu.RUnlock()
+
+	selected = page(selected)
+
+	go func() {
+		defer close(follows)
+		defer close(errChan)
+
+		for _, follow := range selected {
+			select {
+			case follows <- follow:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return follows, errChan
+}
+
+func (m *MemStorage) FollowersNum(ctx context.Context, userId uint64) (uint64, ero.Error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return uint64(len(m.selectFollows(userId, followersSide))), nil
+}
+
+func (m *MemStorage) FollowingsNum(ctx context.Context, userId uint64) (uint64, ero.Error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return uint64(len(m.selectFollows(userId, followingsSide))), nil
+}
+
+func (m *MemStorage) FollowRequestsNum(ctx context.Context, userId uint64) (uint64, ero.Error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return uint64(len(m.selectFollows(userId, requestsSide))), nil
+}
+
+// selectFollows selects follows of the user from the given side.
+// The other side is joined with users, follows are ordered by followed_at DESC.
+// m.mu must be held by caller
+func (m *MemStorage) selectFollows(userId uint64, side followSide) []models.Follow {
+	selected := make([]models.Follow, 0)
+	for key, dates := range m.follows {
+		if (dates.approvedAt != nil) != side.approved {
+			continue
+		}
+
+		switch {
+		case side.followers && key.followeeId == userId:
+			selected = append(selected, models.Follow{
+				Follower:   m.users[key.followerId],
+				Followee:   models.User{Id: userId},
+				FollowedAt: dates.followedAt,
+				ApprovedAt: dates.approvedAt,
+			})
+		case !side.followers && key.followerId == userId:
+			selected = append(selected, models.Follow{
+				Follower:   models.User{Id: userId},
+				Followee:   m.users[key.followeeId],
+				FollowedAt: dates.followedAt,
+				ApprovedAt: dates.approvedAt,
+			})
+		}
+	}
+
+	key := followeeKeyOf
+	if side.followers {
+		key = followerKeyOf
+	}
+	slices.SortFunc(selected, func(a, b models.Follow) int {
+		aTime, aId := key(a)
+		bTime, bId := key(b)
+		if c := bTime.Compare(aTime); c != 0 {
+			return c
+		}
+		return compareIds(bId, aId)
+	})
+
+	return selected
+}
@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (m *MemStorage) SaveIdentity(ctx context.Context, identity *models.Identity) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.SaveIdentity").With("user_id", identity.UserId)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[identity.UserId]; !ok {
+		return 0, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrForeignKeyConstraint)
+	}
+	for _, i := range m.identities {
+		if i.Issuer == identity.Issuer && i.Subject == identity.Subject {
+			return 0, ero.New(logCtx.Build(), ero.CodeExists, storage.ErrUniqueConstraint)
+		}
+	}
+
+	m.lastIdentityId++
+	saved := *identity
+	saved.Id = m.lastIdentityId
+	saved.CreatedAt = now()
+
+	m.identities[saved.Id] = saved
+	return saved.Id, nil
+}
+
+func (m *MemStorage) Identity(ctx context.Context, issuer, subject string) (*models.Identity, ero.Error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, i := range m.identities {
+		if i.Issuer == issuer && i.Subject == subject {
+			return &i, nil
+		}
+	}
+	return nil, ero.New(erolog.NewContextBuilder().With("op", "memory.MemStorage.Identity").With("issuer", issuer).Build(),
+		ero.CodeNotFound, storage.ErrNoRows)
+}
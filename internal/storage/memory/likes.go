@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (m *MemStorage) SaveLike(ctx context.Context, like models.Like) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.SaveLike").With("user_id", like.User.Id).With("post_id", like.Post.Id)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := likeKey{userId: like.User.Id, postId: like.Post.Id}
+	if _, ok := m.likes[key]; ok {
+		return ero.New(logCtx.Build(), ero.CodeExists, storage.ErrUniqueConstraint)
+	}
+	if _, ok := m.users[key.userId]; !ok {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrForeignKeyConstraint)
+	}
+	if _, ok := m.posts[key.postId]; !ok {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrForeignKeyConstraint)
+	}
+
+	m.likes[key] = now()
+	return nil
+}
+
+func (m *MemStorage) DeleteLike(ctx context.Context, like models.Like) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.DeleteLike").With("user_id", like.User.Id).With("post_id", like.Post.Id)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := likeKey{userId: like.User.Id, postId: like.Post.Id}
+	if _, ok := m.likes[key]; !ok {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	delete(m.likes, key)
+	return nil
+}
+
+func (m *MemStorage) Likes(ctx context.Context, offset, count int, postId uint64) (<-chan models.Like, <-chan ero.Error) {
+	return m.likesBy(ctx, offsetPage[models.Like](offset, count), postId)
+}
+
+func (m *MemStorage) LikesByKeyset(ctx context.Context, keyset storage.Keyset, count int, postId uint64) (<-chan models.Like, <-chan ero.Error) {
+	return m.likesBy(ctx, keysetPage(keyset, count, likeKeyOf), postId)
+}
+
+func likeKeyOf(like models.Like) (time.Time, uint64) {
+	return like.LikedAt, like.User.Id
+}
+
+func (m *MemStorage) likesBy(ctx context.Context, page func([]models.Like) []models.Like, postId uint64) (<-chan models.Like, <-chan ero.Error) {
+	likesCh := make(chan models.Like, 10)
+	errChan := make(chan ero.Error, 1)
+
+	m.mu.RLock()
+	selected := m.selectLikes(postId)
+	m.mu.RUnlock()
+
+	selected = page(selected)
+
+	go func() {
+		defer close(likesCh)
+		defer close(errChan)
+
+		for _, like := range selected {
+			select {
+			case likesCh <- like:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return likesCh, errChan
+}
+
+func (m *MemStorage) LikesNum(ctx context.Context, postId uint64) (uint64, ero.Error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return uint64(len(m.selectLikes(postId))), nil
+}
+
+func (m *MemStorage) Like(ctx context.Context, userId, postId uint64) (models.Like, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.Like").With("user_id", userId).With("post_id", postId)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	likedAt, ok := m.likes[likeKey{userId: userId, postId: postId}]
+	if !ok {
+		return models.Like{}, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	return models.Like{
+		User:    models.User{Id: userId},
+		Post:    models.Post{Id: postId},
+		LikedAt: likedAt,
+	}, nil
+}
+
+// selectLikes joins likes of the post with users and orders them by liked_at DESC.
+// Deleted posts have no likes.
+// m.mu must be held by caller
+func (m *MemStorage) selectLikes(postId uint64) []models.Like {
+	selected := make([]models.Like, 0)
+	if _, deleted := m.deletedPosts[postId]; deleted {
+		return selected
+	}
+	for key, likedAt := range m.likes {
+		if key.postId != postId {
+			continue
+		}
+		selected = append(selected, models.Like{
+			User:    m.users[key.userId],
+			Post:    models.Post{Id: postId},
+			LikedAt: likedAt,
+		})
+	}
+
+	slices.SortFunc(selected, func(a, b models.Like) int {
+		if c := b.LikedAt.Compare(a.LikedAt); c != 0 {
+			return c
+		}
+		return compareIds(b.User.Id, a.User.Id)
+	})
+
+	return selected
+}
+
+func (m *MemStorage) PostsLikes(ctx context.Context, postIds []uint64, userId uint64, top int) (map[uint64]models.PostLikes, ero.Error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := make(map[uint64]models.PostLikes, len(postIds))
+	for _, postId := range postIds {
+		likes := m.selectLikes(postId)
+		if len(likes) == 0 {
+			continue
+		}
+
+		_, isLiked := m.likes[likeKey{userId: userId, postId: postId}]
+		res[postId] = models.PostLikes{
+			PostId:  postId,
+			Count:   uint64(len(likes)),
+			IsLiked: isLiked,
+			Top:     likes[:min(max(top, 0), len(likes))],
+		}
+	}
+
+	return res, nil
+}
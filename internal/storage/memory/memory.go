@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+)
+
+// MemStorage keeps everything in process memory.
+// It reproduces pg.PgStorage semantics (constraints, ordering, storage.Err* errors),
+// so the app can be run and tested without postgres
+type MemStorage struct {
+	mu sync.RWMutex
+	// txMu serializes transactions
+	txMu sync.Mutex
+
+	data
+}
+
+var (
+	_ storage.Storage    = (*MemStorage)(nil)
+	_ storage.Transactor = (*MemStorage)(nil)
+)
+
+type data struct {
+	countries []models.Country
+	users     map[uint64]models.User
+	emails    map[string]uint64
+	posts     map[uint64]models.Post
+	// deletedPosts are posts' deleted_at
+	deletedPosts map[uint64]time.Time
+	revisions    map[uint64]models.PostRevision
+	likes        map[likeKey]time.Time
+	comments     map[uint64]models.Comment
+	follows      map[followKey]followDates
+	// sessions are keyed by jti
+	sessions       map[string]models.Session
+	personalTokens map[uint64]models.PersonalToken
+	// totp and recoveryCodes are keyed by user id, recoveryCodes are hashes of unused codes
+	totp          map[uint64]models.Totp
+	recoveryCodes map[uint64][]string
+	identities    map[uint64]models.Identity
+
+	lastUserId          uint64
+	lastPostId          uint64
+	lastRevisionId      uint64
+	lastCommentId       uint64
+	lastFamilyId        uint64
+	lastPersonalTokenId uint64
+	lastIdentityId      uint64
+}
+
+type likeKey struct {
+	userId uint64
+	postId uint64
+}
+
+type followKey struct {
+	followerId uint64
+	followeeId uint64
+}
+
+type followDates struct {
+	followedAt time.Time
+	// approvedAt is nil for pending requests
+	approvedAt *time.Time
+}
+
+func New() *MemStorage {
+	return &MemStorage{
+		data: data{
+			countries:      slices.Clone(seedCountries),
+			users:          make(map[uint64]models.User),
+			emails:         make(map[string]uint64),
+			posts:          make(map[uint64]models.Post),
+			deletedPosts:   make(map[uint64]time.Time),
+			revisions:      make(map[uint64]models.PostRevision),
+			likes:          make(map[likeKey]time.Time),
+			comments:       make(map[uint64]models.Comment),
+			follows:        make(map[followKey]followDates),
+			sessions:       make(map[string]models.Session),
+			personalTokens: make(map[uint64]models.PersonalToken),
+			totp:           make(map[uint64]models.Totp),
+			recoveryCodes:  make(map[uint64][]string),
+			identities:     make(map[uint64]models.Identity),
+		},
+	}
+}
+
+// clone makes a snapshot to roll back to.
+// Values stored in maps are never modified in place, so shallow copies are enough
+func (d *data) clone() data {
+	return data{
+		countries:           slices.Clone(d.countries),
+		users:               maps.Clone(d.users),
+		emails:              maps.Clone(d.emails),
+		posts:               maps.Clone(d.posts),
+		deletedPosts:        maps.Clone(d.deletedPosts),
+		revisions:           maps.Clone(d.revisions),
+		likes:               maps.Clone(d.likes),
+		comments:            maps.Clone(d.comments),
+		follows:             maps.Clone(d.follows),
+		sessions:            maps.Clone(d.sessions),
+		personalTokens:      maps.Clone(d.personalTokens),
+		totp:                maps.Clone(d.totp),
+		recoveryCodes:       maps.Clone(d.recoveryCodes),
+		identities:          maps.Clone(d.identities),
+		lastUserId:          d.lastUserId,
+		lastPostId:          d.lastPostId,
+		lastRevisionId:      d.lastRevisionId,
+		lastCommentId:       d.lastCommentId,
+		lastFamilyId:        d.lastFamilyId,
+		lastPersonalTokenId: d.lastPersonalTokenId,
+		lastIdentityId:      d.lastIdentityId,
+	}
+}
+
+func (m *MemStorage) Disconnect() error {
+	return nil
+}
+
+// now imitates postgres NOW() stored in a TIMESTAMP column
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func (m *MemStorage) countryById(id uint64) (models.Country, bool) {
+	for _, c := range m.countries {
+		if c.Id == id {
+			return c, true
+		}
+	}
+	return models.Country{}, false
+}
@@ -0,0 +1,453 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage/memory"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func saveUser(t *testing.T, m *memory.MemStorage, email string, isPublic bool) *models.User {
+	user, err := m.SaveUser(context.Background(), &models.User{
+		Name:     "Name",
+		Lastname: "Lastname",
+		Email:    email,
+		Country:  models.Country{Id: 1},
+		IsPublic: isPublic,
+		Birthday: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+	})
+	require.Nil(t, err)
+	return user
+}
+
+func collect[T any](ch <-chan T) []T {
+	res := make([]T, 0)
+	for v := range ch {
+		res = append(res, v)
+	}
+	return res
+}
+
+func TestUsers(t *testing.T) {
+	ctx := context.Background()
+	m := memory.New()
+
+	user := saveUser(t, m, "email@email.com", true)
+	assert.Equal(t, "AF", user.Country.Alpha2)
+
+	_, err := m.SaveUser(ctx, &models.User{Email: "email@email.com", Country: models.Country{Id: 1}})
+	assert.ErrorIs(t, err, storage.ErrUniqueConstraint)
+
+	_, err = m.SaveUser(ctx, &models.User{Email: "another@email.com", Country: models.Country{Id: 1000}})
+	assert.ErrorIs(t, err, storage.ErrForeignKeyConstraint)
+
+	got, err := m.UserByEmail(ctx, "email@email.com")
+	require.Nil(t, err)
+	assert.Equal(t, user, got)
+
+	_, err = m.UserById(ctx, 1000)
+	assert.ErrorIs(t, err, storage.ErrNoRows)
+
+	changed := *user
+	changed.Name, changed.Country, changed.Email = "Changed", models.Country{Id: 2}, "ignored@email.com"
+	updated, err := m.UpdateUser(ctx, &changed)
+	require.Nil(t, err)
+	assert.Equal(t, "Changed", updated.Name)
+	assert.Equal(t, user.Email, updated.Email, "email cannot be updated")
+	assert.NotEqual(t, user.Country.Alpha2, updated.Country.Alpha2)
+
+	changed.Country.Id = 1000
+	_, err = m.UpdateUser(ctx, &changed)
+	assert.ErrorIs(t, err, storage.ErrForeignKeyConstraint)
+
+	generation, err := m.UpdatePassword(ctx, user.Id, "hash")
+	require.Nil(t, err)
+	assert.Equal(t, user.TokenGeneration+1, generation)
+
+	require.Nil(t, m.RehashPassword(ctx, user.Id, "hash", "rehashed"))
+	assert.ErrorIs(t, m.RehashPassword(ctx, user.Id, "hash", "stale"), storage.ErrNoRows, "password has changed")
+	rehashed, err := m.UserById(ctx, user.Id)
+	require.Nil(t, err)
+	assert.Equal(t, "rehashed", rehashed.PasswordHash)
+	assert.Equal(t, generation, rehashed.TokenGeneration, "sessions are kept")
+}
+
+func TestAdminUsers(t *testing.T) {
+	ctx := context.Background()
+	m := memory.New()
+
+	user := saveUser(t, m, "john@email.com", true)
+	saveUser(t, m, "jane@email.com", false)
+	assert.Equal(t, models.RoleUser, user.Role)
+
+	found := collect(first(m.Users(ctx, "JOHN", 0, 10)))
+	require.Len(t, found, 1)
+	assert.Equal(t, user.Id, found[0].Id)
+
+	num, err := m.UsersNum(ctx, "")
+	require.Nil(t, err)
+	assert.Equal(t, uint64(2), num)
+
+	require.Nil(t, m.SuspendUser(ctx, user.Id))
+	assert.ErrorIs(t, m.SuspendUser(ctx, user.Id), storage.ErrNoRows, "already suspended")
+	suspended, err := m.UserById(ctx, user.Id)
+	require.Nil(t, err)
+	assert.NotNil(t, suspended.SuspendedAt)
+
+	require.Nil(t, m.UnsuspendUser(ctx, user.Id))
+	assert.ErrorIs(t, m.UnsuspendUser(ctx, user.Id), storage.ErrNoRows, "not suspended")
+
+	require.Nil(t, m.SetRole(ctx, user.Id, models.RoleModerator))
+	assert.ErrorIs(t, m.SetRole(ctx, user.Id, "root"), storage.ErrCheckConstraint)
+	moderator, err := m.UserById(ctx, user.Id)
+	require.Nil(t, err)
+	assert.Equal(t, models.RoleModerator, moderator.Role)
+}
+
+func TestPosts(t *testing.T) {
+	ctx := context.Background()
+	m := memory.New()
+
+	public := saveUser(t, m, "public@email.com", true)
+	private := saveUser(t, m, "private@email.com", false)
+
+	_, err := m.SavePost(ctx, &models.Post{Author: models.User{Id: 1000}, Content: "content"})
+	assert.ErrorIs(t, err, storage.ErrForeignKeyConstraint)
+
+	ids := make([]uint64, 0, 3)
+	for _, author := range []*models.User{public, private, public} {
+		id, err := m.SavePost(ctx, &models.Post{Author: *author, Content: "content"})
+		require.Nil(t, err)
+		ids = append(ids, id)
+	}
+
+	postsCh, errCh := m.Posts(ctx, 0, 10)
+	posts := collect(postsCh)
+	assert.Nil(t, <-errCh)
+	require.Len(t, posts, 2)
+	assert.Equal(t, ids[2], posts[0].Id)
+	assert.Equal(t, ids[0], posts[1].Id)
+	assert.Equal(t, public.Email, posts[0].Author.Email)
+
+	postsCh, errCh = m.UsersPosts(ctx, 1, 10, public.Id)
+	posts = collect(postsCh)
+	assert.Nil(t, <-errCh)
+	require.Len(t, posts, 1)
+	assert.Equal(t, ids[0], posts[0].Id)
+
+	count, err := m.PostsNum(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), count)
+
+	all := collect(first(m.UsersPosts(ctx, 0, 10, public.Id)))
+	require.Len(t, all, 2)
+
+	postsCh, errCh = m.UsersPostsByKeyset(ctx, storage.Keyset{Time: all[0].PublishedAt, Id: all[0].Id}, 10, public.Id)
+	posts = collect(postsCh)
+	assert.Nil(t, <-errCh)
+	require.Len(t, posts, 1)
+	assert.Equal(t, all[1].Id, posts[0].Id)
+
+	postsCh, errCh = m.UsersPostsByKeyset(ctx, storage.Keyset{Time: all[1].PublishedAt, Id: all[1].Id, Backward: true}, 10, public.Id)
+	posts = collect(postsCh)
+	assert.Nil(t, <-errCh)
+	require.Len(t, posts, 1)
+	assert.Equal(t, all[0].Id, posts[0].Id)
+}
+
+func first[T, U any](t T, _ U) T {
+	return t
+}
+
+func TestLikes(t *testing.T) {
+	ctx := context.Background()
+	m := memory.New()
+
+	user := saveUser(t, m, "email@email.com", true)
+	postId, err := m.SavePost(ctx, &models.Post{Author: *user, Content: "content"})
+	require.Nil(t, err)
+
+	like := models.Like{User: *user, Post: models.Post{Id: postId}}
+	assert.Nil(t, m.SaveLike(ctx, like))
+	assert.ErrorIs(t, m.SaveLike(ctx, like), storage.ErrUniqueConstraint)
+	assert.ErrorIs(t, m.SaveLike(ctx, models.Like{User: *user, Post: models.Post{Id: 1000}}), storage.ErrForeignKeyConstraint)
+
+	likesCh, errCh := m.Likes(ctx, 0, 10, postId)
+	likes := collect(likesCh)
+	assert.Nil(t, <-errCh)
+	require.Len(t, likes, 1)
+	assert.Equal(t, user.Email, likes[0].User.Email)
+
+	summaries, err := m.PostsLikes(ctx, []uint64{postId, 1000}, user.Id, 0)
+	require.Nil(t, err)
+	require.Len(t, summaries, 1)
+	assert.Equal(t, uint64(1), summaries[postId].Count)
+	assert.True(t, summaries[postId].IsLiked)
+	assert.Empty(t, summaries[postId].Top)
+
+	assert.Nil(t, m.DeleteLike(ctx, like))
+	assert.ErrorIs(t, m.DeleteLike(ctx, like), storage.ErrNoRows)
+
+	_, err = m.Like(ctx, user.Id, postId)
+	assert.ErrorIs(t, err, storage.ErrNoRows)
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	m := memory.New()
+
+	user := saveUser(t, m, "email@email.com", true)
+
+	eroErr := m.WithTx(ctx, func(tx storage.Storage) ero.Error {
+		postId, eroErr := tx.SavePost(ctx, &models.Post{Author: *user, Content: "content"})
+		require.Nil(t, eroErr)
+
+		return tx.SaveLike(ctx, models.Like{User: models.User{Id: 1000}, Post: models.Post{Id: postId}})
+	})
+	assert.ErrorIs(t, eroErr, storage.ErrForeignKeyConstraint)
+
+	count, eroErr := m.UsersPostsNum(ctx, user.Id)
+	assert.Nil(t, eroErr)
+	assert.Zero(t, count, "post must be rolled back")
+
+	eroErr = m.WithTx(ctx, func(tx storage.Storage) ero.Error {
+		_, eroErr := tx.SavePost(ctx, &models.Post{Author: *user, Content: "content"})
+		return eroErr
+	})
+	assert.Nil(t, eroErr)
+
+	count, eroErr = m.UsersPostsNum(ctx, user.Id)
+	assert.Nil(t, eroErr)
+	assert.Equal(t, uint64(1), count)
+}
+
+func TestSearchPosts(t *testing.T) {
+	ctx := context.Background()
+	m := memory.New()
+
+	public := saveUser(t, m, "public@email.com", true)
+	private := saveUser(t, m, "private@email.com", false)
+
+	for _, p := range []struct {
+		author  *models.User
+		content string
+	}{
+		{public, "Go is fun"},
+		{public, "Go, go, go! Write Go"},
+		{public, "Rust is fun too"},
+		{private, "Go is private"},
+	} {
+		_, err := m.SavePost(ctx, &models.Post{Author: *p.author, Content: p.content})
+		require.Nil(t, err)
+	}
+
+	postsCh, errCh := m.SearchPosts(ctx, "go", 0, 10)
+	found := collect(postsCh)
+	assert.Nil(t, <-errCh)
+	require.Len(t, found, 2)
+	assert.Equal(t, "<b>Go</b>, <b>go</b>, <b>go</b>! Write <b>Go</b>", found[0].Headline)
+	assert.Equal(t, "<b>Go</b> is fun", found[1].Headline)
+	assert.Greater(t, found[0].Rank, found[1].Rank)
+
+	count, err := m.SearchPostsNum(ctx, "is FUN")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), count)
+}
+
+func TestEditDeletePost(t *testing.T) {
+	ctx := context.Background()
+	m := memory.New()
+
+	user := saveUser(t, m, "email@email.com", true)
+	postId, err := m.SavePost(ctx, &models.Post{Author: *user, Content: "content"})
+	require.Nil(t, err)
+	require.Nil(t, m.SaveLike(ctx, models.Like{User: *user, Post: models.Post{Id: postId}}))
+
+	post, err := m.Post(ctx, postId)
+	require.Nil(t, err)
+	assert.Nil(t, post.UpdatedAt)
+
+	require.Nil(t, m.SavePostRevision(ctx, models.PostRevision{PostId: postId, Content: post.Content, CreatedAt: post.PublishedAt}))
+	require.Nil(t, m.UpdatePost(ctx, &models.Post{Id: postId, Content: "edited"}))
+
+	post, err = m.Post(ctx, postId)
+	require.Nil(t, err)
+	assert.Equal(t, "edited", post.Content)
+	assert.NotNil(t, post.UpdatedAt)
+
+	revisions := collect(first(m.PostRevisions(ctx, postId)))
+	require.Len(t, revisions, 1)
+	assert.Equal(t, "content", revisions[0].Content)
+
+	require.Nil(t, m.DeletePost(ctx, postId))
+	assert.ErrorIs(t, m.DeletePost(ctx, postId), storage.ErrNoRows)
+
+	_, err = m.Post(ctx, postId)
+	assert.ErrorIs(t, err, storage.ErrNoRows)
+	assert.Empty(t, collect(first(m.UsersPosts(ctx, 0, 10, user.Id))))
+	assert.Empty(t, collect(first(m.Likes(ctx, 0, 10, postId))))
+
+	count, err := m.LikesNum(ctx, postId)
+	assert.Nil(t, err)
+	assert.Zero(t, count)
+}
+
+func TestComments(t *testing.T) {
+	ctx := context.Background()
+	m := memory.New()
+
+	user := saveUser(t, m, "email@email.com", true)
+	postId, err := m.SavePost(ctx, &models.Post{Author: *user, Content: "content"})
+	require.Nil(t, err)
+
+	saveComment := func(parentId *uint64) uint64 {
+		id, err := m.SaveComment(ctx, &models.Comment{PostId: postId, ParentId: parentId, Author: *user, Content: "comment"})
+		require.Nil(t, err)
+		return id
+	}
+
+	root := saveComment(nil)
+	reply := saveComment(&root)
+	replyToReply := saveComment(&reply)
+	anotherRoot := saveComment(nil)
+
+	missing := uint64(1000)
+	_, err = m.SaveComment(ctx, &models.Comment{PostId: postId, ParentId: &missing, Author: *user, Content: "comment"})
+	assert.ErrorIs(t, err, storage.ErrForeignKeyConstraint)
+
+	roots := collect(first(m.RootComments(ctx, 0, 10, postId)))
+	require.Len(t, roots, 2)
+	assert.Equal(t, root, roots[0].Id)
+	assert.Equal(t, user.Email, roots[0].Author.Email)
+
+	replies := collect(first(m.Replies(ctx, []uint64{root})))
+	require.Len(t, replies, 2)
+	assert.Equal(t, reply, replies[0].Id)
+	assert.Equal(t, replyToReply, replies[1].Id)
+
+	require.Nil(t, m.DeleteComment(ctx, anotherRoot))
+	assert.ErrorIs(t, m.UpdateComment(ctx, &models.Comment{Id: anotherRoot, Content: "edited"}), storage.ErrNoRows)
+
+	count, err := m.CommentsNum(ctx, postId)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), count, "deleted comments are still listed")
+
+	counts, err := m.PostsCommentsNum(ctx, []uint64{postId})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), counts[postId])
+}
+
+func TestFollows(t *testing.T) {
+	ctx := context.Background()
+	m := memory.New()
+
+	follower := saveUser(t, m, "follower@email.com", true)
+	followee := saveUser(t, m, "followee@email.com", false)
+	stranger := saveUser(t, m, "stranger@email.com", true)
+
+	follow := models.Follow{Follower: *follower, Followee: *followee}
+	require.Nil(t, m.SaveFollow(ctx, follow))
+	assert.ErrorIs(t, m.SaveFollow(ctx, follow), storage.ErrUniqueConstraint)
+	assert.ErrorIs(t, m.SaveFollow(ctx, models.Follow{Follower: *follower, Followee: *follower}), storage.ErrCheckConstraint)
+	assert.ErrorIs(t, m.SaveFollow(ctx, models.Follow{Follower: *follower, Followee: models.User{Id: 1000}}), storage.ErrForeignKeyConstraint)
+
+	for _, author := range []*models.User{follower, followee, stranger} {
+		_, err := m.SavePost(ctx, &models.Post{Author: *author, Content: "content"})
+		require.Nil(t, err)
+	}
+
+	requests := collect(first(m.FollowRequests(ctx, 0, 10, followee.Id)))
+	require.Len(t, requests, 1)
+	assert.Equal(t, follower.Email, requests[0].Follower.Email)
+	assert.Empty(t, collect(first(m.Followers(ctx, 0, 10, followee.Id))), "request is not approved yet")
+	assert.Len(t, collect(first(m.TimelinePosts(ctx, 0, 10, follower.Id))), 1)
+
+	require.Nil(t, m.ApproveFollow(ctx, follow))
+	assert.ErrorIs(t, m.ApproveFollow(ctx, follow), storage.ErrNoRows)
+
+	approved, err := m.Follow(ctx, follower.Id, followee.Id)
+	require.Nil(t, err)
+	assert.NotNil(t, approved.ApprovedAt)
+
+	followings, err := m.FollowingsNum(ctx, follower.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), followings)
+
+	timeline := collect(first(m.TimelinePosts(ctx, 0, 10, follower.Id)))
+	require.Len(t, timeline, 2, "posts of the user and of followed private user")
+	assert.Equal(t, followee.Id, timeline[0].Author.Id)
+	assert.Equal(t, follower.Id, timeline[1].Author.Id)
+
+	require.Nil(t, m.DeleteFollow(ctx, follow))
+	assert.ErrorIs(t, m.DeleteFollow(ctx, follow), storage.ErrNoRows)
+
+	count, err := m.TimelinePostsNum(ctx, follower.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), count)
+}
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	m := memory.New()
+
+	user := saveUser(t, m, "email@email.com", true)
+	expiresAt := time.Now().Add(time.Hour)
+
+	familyId, err := m.SaveSession(ctx, &models.Session{Jti: "first", UserId: user.Id, ExpiresAt: expiresAt})
+	require.Nil(t, err)
+	require.Nil(t, m.UseSession(ctx, "first"))
+	assert.ErrorIs(t, m.UseSession(ctx, "first"), storage.ErrNoRows, "token cannot be used twice")
+
+	rotated, err := m.SaveSession(ctx, &models.Session{Jti: "second", FamilyId: familyId, UserId: user.Id, ExpiresAt: expiresAt})
+	require.Nil(t, err)
+	assert.Equal(t, familyId, rotated)
+
+	another, err := m.SaveSession(ctx, &models.Session{Jti: "another", UserId: user.Id, ExpiresAt: expiresAt})
+	require.Nil(t, err)
+	assert.NotEqual(t, familyId, another)
+
+	sessions := collect(first(m.Sessions(ctx, user.Id)))
+	require.Len(t, sessions, 2, "only the latest token of every family is active")
+
+	require.Nil(t, m.RevokeSessionFamily(ctx, user.Id, familyId))
+	assert.ErrorIs(t, m.RevokeSessionFamily(ctx, user.Id, familyId), storage.ErrNoRows)
+
+	session, err := m.Session(ctx, "second")
+	require.Nil(t, err)
+	assert.NotNil(t, session.RevokedAt)
+	assert.Len(t, collect(first(m.Sessions(ctx, user.Id))), 1)
+}
+
+func TestPersonalTokens(t *testing.T) {
+	ctx := context.Background()
+	m := memory.New()
+
+	user := saveUser(t, m, "email@email.com", true)
+	expired := time.Now().Add(-time.Hour)
+
+	id, err := m.SavePersonalToken(ctx, &models.PersonalToken{UserId: user.Id, Name: "bot", Hash: "hash", Scopes: []string{"feed:read"}})
+	require.Nil(t, err)
+	_, err = m.SavePersonalToken(ctx, &models.PersonalToken{UserId: user.Id, Name: "copy", Hash: "hash"})
+	assert.ErrorIs(t, err, storage.ErrUniqueConstraint)
+	_, err = m.SavePersonalToken(ctx, &models.PersonalToken{UserId: user.Id, Name: "old", Hash: "old", ExpiresAt: &expired})
+	require.Nil(t, err)
+
+	saved, err := m.PersonalTokenByHash(ctx, "hash")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"feed:read"}, saved.Scopes)
+
+	require.Nil(t, m.UsePersonalToken(ctx, id))
+	assert.ErrorIs(t, m.UsePersonalToken(ctx, id), storage.ErrNoRows, "last use is updated once a minute")
+
+	assert.Len(t, collect(first(m.PersonalTokens(ctx, user.Id))), 1, "expired tokens are not listed")
+
+	assert.ErrorIs(t, m.RevokePersonalToken(ctx, user.Id+1, id), storage.ErrNoRows, "only the owner revokes")
+	require.Nil(t, m.RevokePersonalToken(ctx, user.Id, id))
+	assert.Empty(t, collect(first(m.PersonalTokens(ctx, user.Id))))
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+)
+
+func compareIds(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// offsetPage works like OFFSET offset LIMIT count
+func offsetPage[T any](offset, count int) func([]T) []T {
+	return func(s []T) []T {
+		if offset < 0 {
+			offset = 0
+		}
+		if offset >= len(s) {
+			return nil
+		}
+		end := len(s)
+		if count >= 0 && offset+count < end {
+			end = offset + count
+		}
+		return s[offset:end]
+	}
+}
+
+// keysetPage selects up to count items after (or before if keyset.Backward) the keyset
+// from items ordered by (time DESC, id DESC), keeping that order
+func keysetPage[T any](keyset storage.Keyset, count int, key func(T) (time.Time, uint64)) func([]T) []T {
+	// compare compares item's position in the list with the keyset's one
+	compare := func(item T) int {
+		t, id := key(item)
+		if c := keyset.Time.Compare(t); c != 0 {
+			return c
+		}
+		return compareIds(keyset.Id, id)
+	}
+
+	return func(s []T) []T {
+		if keyset.Backward {
+			end := sort.Search(len(s), func(i int) bool {
+				return compare(s[i]) >= 0
+			})
+			return s[max(0, end-count):end]
+		}
+
+		start := sort.Search(len(s), func(i int) bool {
+			return compare(s[i]) > 0
+		})
+		return s[start:min(len(s), start+count)]
+	}
+}
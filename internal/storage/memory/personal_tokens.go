@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (m *MemStorage) SavePersonalToken(ctx context.Context, token *models.PersonalToken) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.SavePersonalToken").With("user_id", token.UserId)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[token.UserId]; !ok {
+		return 0, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrForeignKeyConstraint)
+	}
+	for _, t := range m.personalTokens {
+		if t.Hash == token.Hash {
+			return 0, ero.New(logCtx.Build(), ero.CodeExists, storage.ErrUniqueConstraint)
+		}
+	}
+
+	m.lastPersonalTokenId++
+	saved := *token
+	saved.Id = m.lastPersonalTokenId
+	saved.Scopes = slices.Clone(token.Scopes)
+	saved.CreatedAt = now()
+	saved.LastUsedAt, saved.RevokedAt = nil, nil
+
+	m.personalTokens[saved.Id] = saved
+	return saved.Id, nil
+}
+
+func (m *MemStorage) PersonalTokenByHash(ctx context.Context, hash string) (*models.PersonalToken, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.PersonalTokenByHash")
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, t := range m.personalTokens {
+		if t.Hash == hash {
+			return &t, nil
+		}
+	}
+	return nil, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+}
+
+func (m *MemStorage) UsePersonalToken(ctx context.Context, id uint64) ero.Error {
+	return m.changePersonalToken("memory.MemStorage.UsePersonalToken", func(t *models.PersonalToken) bool {
+		return t.Id == id && (t.LastUsedAt == nil || now().Sub(*t.LastUsedAt) > time.Minute)
+	}, func(t *models.PersonalToken, at time.Time) {
+		t.LastUsedAt = &at
+	})
+}
+
+func (m *MemStorage) RevokePersonalToken(ctx context.Context, userId, id uint64) ero.Error {
+	return m.changePersonalToken("memory.MemStorage.RevokePersonalToken", func(t *models.PersonalToken) bool {
+		return t.Id == id && t.UserId == userId
+	}, func(t *models.PersonalToken, at time.Time) {
+		t.RevokedAt = &at
+	})
+}
+
+// changePersonalToken changes not revoked tokens matching where,
+// returns storage.ErrNoRows if there are no such tokens
+func (m *MemStorage) changePersonalToken(op string, where func(*models.PersonalToken) bool, change func(*models.PersonalToken, time.Time)) ero.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	at := now()
+	changed := 0
+	for id, t := range m.personalTokens {
+		if t.RevokedAt != nil || !where(&t) {
+			continue
+		}
+		change(&t, at)
+		m.personalTokens[id] = t
+		changed++
+	}
+
+	if changed == 0 {
+		return ero.New(erolog.NewContextBuilder().With("op", op).Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+	return nil
+}
+
+func (m *MemStorage) PersonalTokens(ctx context.Context, userId uint64) (<-chan models.PersonalToken, <-chan ero.Error) {
+	personalTokens := make(chan models.PersonalToken, 10)
+	errChan := make(chan ero.Error, 1)
+
+	m.mu.RLock()
+	selected := make([]models.PersonalToken, 0)
+	for _, t := range m.personalTokens {
+		if t.UserId == userId && t.RevokedAt == nil && (t.ExpiresAt == nil || t.ExpiresAt.After(now())) {
+			selected = append(selected, t)
+		}
+	}
+	m.mu.RUnlock()
+
+	slices.SortFunc(selected, func(a, b models.PersonalToken) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Id, a.Id)
+	})
+
+	go func() {
+		defer close(personalTokens)
+		defer close(errChan)
+
+		for _, t := range selected {
+			select {
+			case personalTokens <- t:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return personalTokens, errChan
+}
@@ -0,0 +1,202 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (m *MemStorage) SavePost(ctx context.Context, post *models.Post) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "memory.MemStorage.SavePost").With("post_author_id", post.Author.Id)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[post.Author.Id]; !ok {
+		return 0, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrForeignKeyConstraint)
+	}
+
+	m.lastPostId++
+	m.posts[m.lastPostId] = models.Post{
+		Id:          m.lastPostId,
+		Author:      models.User{Id: post.Author.Id},
+		Content:     post.Content,
+		ImagesUrls:  slices.Clone(post.ImagesUrls),
+		PublishedAt: now(),
+	}
+
+	return m.lastPostId, nil
+}
+
+func (m *MemStorage) Posts(ctx context.Context, offset, count int) (<-chan models.Post, <-chan ero.Error) {
+	return m.postsBy(ctx, offsetPage[models.Post](offset, count), isPublic)
+}
+
+func (m *MemStorage) PostsByKeyset(ctx context.Context, keyset storage.Keyset, count int) (<-chan models.Post, <-chan ero.Error) {
+	return m.postsBy(ctx, keysetPage(keyset, count, postKey), isPublic)
+}
+
+func (m *MemStorage) UsersPosts(ctx context.Context, offset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error) {
+	return m.postsBy(ctx, offsetPage[models.Post](offset, count), byAuthor(userId))
+}
+
+func (m *MemStorage) UsersPostsByKeyset(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error) {
+	return m.postsBy(ctx, keysetPage(keyset, count, postKey), byAuthor(userId))
+}
+
+func (m *MemStorage) TimelinePosts(ctx context.Context, offset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error) {
+	return m.postsBy(ctx, offsetPage[models.Post](offset, count), m.inTimeline(userId))
+}
+
+func (m *MemStorage) TimelinePostsByKeyset(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error) {
+	return m.postsBy(ctx, keysetPage(keyset, count, postKey), m.inTimeline(userId))
+}
+
+func isPublic(p *models.Post) bool {
+	return p.Author.IsPublic
+}
+
+func byAuthor(userId uint64) func(*models.Post) bool {
+	return func(p *models.Post) bool {
+		return p.Author.Id == userId
+	}
+}
+
+// inTimeline selects posts of the user and of everyone the user follows with approval.
+// m.mu must be held when the filter is called
+func (m *MemStorage) inTimeline(userId uint64) func(*models.Post) bool {
+	return func(p *models.Post) bool {
+		dates, follows := m.follows[followKey{followerId: userId, followeeId: p.Author.Id}]
+		return p.Author.Id == userId || follows && dates.approvedAt != nil
+	}
+}
+
+func postKey(p models.Post) (time.Time, uint64) {
+	return p.PublishedAt, p.Id
+}
+
+func (m *MemStorage) PostsNum(ctx context.Context) (uint64, ero.Error) {
+	return m.postsNum(isPublic), nil
+}
+
+func (m *MemStorage) UsersPostsNum(ctx context.Context, userId uint64) (uint64, ero.Error) {
+	return m.postsNum(byAuthor(userId)), nil
+}
+
+func (m *MemStorage) TimelinePostsNum(ctx context.Context, userId uint64) (uint64, ero.Error) {
+	return m.postsNum(m.inTimeline(userId)), nil
+}
+
+func (m *MemStorage) postsBy(ctx context.Context, page func([]models.Post) []models.Post, where func(*models.Post) bool) (<-chan models.Post, <-chan ero.Error) {
+	posts := make(chan models.Post, 10)
+	errChan := make(chan ero.Error, 1)
+
+	m.mu.RLock()
+	selected := m.selectPosts(where)
+	m.mu.RUnlock()
+
+	selected = page(selected)
+
+	go func() {
+		defer close(posts)
+		defer close(errChan)
+
+		for _, p := range selected {
+			select {
+			case posts <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return posts, errChan
+}
+
+func (m *MemStorage) postsNum(where func(*models.Post) bool) uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return uint64(len(m.selectPosts(where)))
+}
+
+// selectPosts joins not deleted posts with their authors, filters them and orders by published_at DESC.
+// m.mu must be held by caller
+func (m *MemStorage) selectPosts(where func(*models.Post) bool) []models.Post {
+	selected := make([]models.Post, 0, len(m.posts))
+	for _, p := range m.posts {
+		if _, deleted := m.deletedPosts[p.Id]; deleted {
+			continue
+		}
+		p.Author = m.users[p.Author.Id]
+		if where(&p) {
+			p.ImagesUrls = slices.Clone(p.ImagesUrls)
+			selected = append(selected, p)
+		}
+	}
+
+	slices.SortFunc(selected, func(a, b models.Post) int {
+		if c := b.PublishedAt.Compare(a.PublishedAt); c != 0 {
+			return c
+		}
+		return compareIds(b.Id, a.Id)
+	})
+
+	return selected
+}
+
+func (m *MemStorage) Post(ctx context.Context, id uint64) (*models.Post, ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "memory.MemStorage.Post").With("post_id", id)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, ok := m.posts[id]
+	if _, deleted := m.deletedPosts[id]; !ok || deleted {
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+	p.Author = m.users[p.Author.Id]
+	p.ImagesUrls = slices.Clone(p.ImagesUrls)
+
+	return &p, nil
+}
+
+func (m *MemStorage) UpdatePost(ctx context.Context, post *models.Post) ero.Error {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "memory.MemStorage.UpdatePost").With("post_id", post.Id)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.posts[post.Id]
+	if _, deleted := m.deletedPosts[post.Id]; !ok || deleted {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	updatedAt := now()
+	p.Content = post.Content
+	p.ImagesUrls = slices.Clone(post.ImagesUrls)
+	p.UpdatedAt = &updatedAt
+	m.posts[post.Id] = p
+
+	return nil
+}
+
+func (m *MemStorage) DeletePost(ctx context.Context, id uint64) ero.Error {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "memory.MemStorage.DeletePost").With("post_id", id)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.posts[id]
+	if _, deleted := m.deletedPosts[id]; !ok || deleted {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	m.deletedPosts[id] = now()
+	return nil
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (m *MemStorage) SavePostRevision(ctx context.Context, revision models.PostRevision) ero.Error {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "memory.MemStorage.SavePostRevision").With("post_id", revision.PostId)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.posts[revision.PostId]; !ok {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrForeignKeyConstraint)
+	}
+
+	m.lastRevisionId++
+	revision.Id = m.lastRevisionId
+	revision.ImagesUrls = slices.Clone(revision.ImagesUrls)
+	revision.ReplacedAt = now()
+	m.revisions[revision.Id] = revision
+
+	return nil
+}
+
+func (m *MemStorage) PostRevisions(ctx context.Context, postId uint64) (<-chan models.PostRevision, <-chan ero.Error) {
+	revisions := make(chan models.PostRevision, 10)
+	errChan := make(chan ero.Error, 1)
+
+	m.mu.RLock()
+	selected := make([]models.PostRevision, 0)
+	for _, r := range m.revisions {
+		if r.PostId == postId {
+			r.ImagesUrls = slices.Clone(r.ImagesUrls)
+			selected = append(selected, r)
+		}
+	}
+	m.mu.RUnlock()
+
+	slices.SortFunc(selected, func(a, b models.PostRevision) int {
+		return compareIds(b.Id, a.Id)
+	})
+
+	go func() {
+		defer close(revisions)
+		defer close(errChan)
+
+		for _, r := range selected {
+			select {
+			case revisions <- r:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return revisions, errChan
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/lib/fulltext"
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+)
+
+func (m *MemStorage) SearchPosts(ctx context.Context, query string, offset, count int) (<-chan models.FoundPost, <-chan ero.Error) {
+	posts := make(chan models.FoundPost, 10)
+	errChan := make(chan ero.Error, 1)
+
+	queryWords := fulltext.Tokenize(query)
+
+	m.mu.RLock()
+	selected := m.selectPosts(matches(queryWords))
+	m.mu.RUnlock()
+
+	found := make([]models.FoundPost, len(selected))
+	for i, p := range selected {
+		found[i] = models.FoundPost{
+			Post:     p,
+			Rank:     fulltext.Rank(fulltext.Tokenize(p.Content), queryWords),
+			Headline: fulltext.Headline(p.Content, queryWords, "<b>", "</b>"),
+		}
+	}
+	// selected are already ordered by published_at DESC, id DESC
+	slices.SortStableFunc(found, func(a, b models.FoundPost) int {
+		return cmp.Compare(b.Rank, a.Rank)
+	})
+	found = offsetPage[models.FoundPost](offset, count)(found)
+
+	go func() {
+		defer close(posts)
+		defer close(errChan)
+
+		for _, p := range found {
+			select {
+			case posts <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return posts, errChan
+}
+
+func (m *MemStorage) SearchPostsNum(ctx context.Context, query string) (uint64, ero.Error) {
+	return m.postsNum(matches(fulltext.Tokenize(query))), nil
+}
+
+// matches is a filter of public posts containing all queryWords
+func matches(queryWords []string) func(*models.Post) bool {
+	return func(p *models.Post) bool {
+		return isPublic(p) && fulltext.Match(fulltext.Tokenize(p.Content), queryWords)
+	}
+}
@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (m *MemStorage) SaveSession(ctx context.Context, session *models.Session) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.SaveSession").With("user_id", session.UserId).With("family_id", session.FamilyId)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.sessions[session.Jti]; ok {
+		return 0, ero.New(logCtx.Build(), ero.CodeExists, storage.ErrUniqueConstraint)
+	}
+	if _, ok := m.users[session.UserId]; !ok {
+		return 0, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrForeignKeyConstraint)
+	}
+
+	saved := *session
+	saved.CreatedAt = now()
+	saved.StartedAt = saved.CreatedAt
+	saved.UsedAt, saved.RevokedAt = nil, nil
+	if saved.FamilyId == 0 {
+		m.lastFamilyId++
+		saved.FamilyId = m.lastFamilyId
+	}
+
+	m.sessions[saved.Jti] = saved
+	return saved.FamilyId, nil
+}
+
+func (m *MemStorage) Session(ctx context.Context, jti string) (*models.Session, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.Session")
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.sessions[jti]
+	if !ok {
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	s.StartedAt = m.familyStartedAt(s.FamilyId)
+	return &s, nil
+}
+
+func (m *MemStorage) UseSession(ctx context.Context, jti string) ero.Error {
+	return m.changeSessions("memory.MemStorage.UseSession", func(s *models.Session) bool {
+		return s.Jti == jti && s.UsedAt == nil
+	}, func(s *models.Session, at time.Time) {
+		s.UsedAt = &at
+	})
+}
+
+func (m *MemStorage) RevokeSessionFamily(ctx context.Context, userId, familyId uint64) ero.Error {
+	return m.changeSessions("memory.MemStorage.RevokeSessionFamily", func(s *models.Session) bool {
+		return s.UserId == userId && s.FamilyId == familyId
+	}, revokeSession)
+}
+
+func (m *MemStorage) RevokeSessions(ctx context.Context, userId uint64) ero.Error {
+	m.changeSessions("memory.MemStorage.RevokeSessions", func(s *models.Session) bool {
+		return s.UserId == userId
+	}, revokeSession)
+	return nil
+}
+
+func revokeSession(s *models.Session, at time.Time) {
+	s.RevokedAt = &at
+}
+
+// changeSessions changes not revoked sessions matching where,
+// returns storage.ErrNoRows if there are no such sessions
+func (m *MemStorage) changeSessions(op string, where func(*models.Session) bool, change func(*models.Session, time.Time)) ero.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	at := now()
+	changed := 0
+	for jti, s := range m.sessions {
+		if s.RevokedAt != nil || !where(&s) {
+			continue
+		}
+		change(&s, at)
+		m.sessions[jti] = s
+		changed++
+	}
+
+	if changed == 0 {
+		return ero.New(erolog.NewContextBuilder().With("op", op).Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+	return nil
+}
+
+func (m *MemStorage) Sessions(ctx context.Context, userId uint64) (<-chan models.Session, <-chan ero.Error) {
+	sessions := make(chan models.Session, 10)
+	errChan := make(chan ero.Error, 1)
+
+	m.mu.RLock()
+	selected := make([]models.Session, 0)
+	for _, s := range m.sessions {
+		if s.UserId == userId && s.UsedAt == nil && s.RevokedAt == nil && s.ExpiresAt.After(time.Now()) {
+			s.StartedAt = m.familyStartedAt(s.FamilyId)
+			selected = append(selected, s)
+		}
+	}
+	m.mu.RUnlock()
+
+	slices.SortFunc(selected, func(a, b models.Session) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	go func() {
+		defer close(sessions)
+		defer close(errChan)
+
+		for _, s := range selected {
+			select {
+			case sessions <- s:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return sessions, errChan
+}
+
+// familyStartedAt is creation time of the first token of the family.
+// m.mu must be held by caller
+func (m *MemStorage) familyStartedAt(familyId uint64) time.Time {
+	var started time.Time
+	for _, s := range m.sessions {
+		if s.FamilyId == familyId && (started.IsZero() || s.CreatedAt.Before(started)) {
+			started = s.CreatedAt
+		}
+	}
+	return started
+}
@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (m *MemStorage) SaveTotp(ctx context.Context, userId uint64, secret string) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.SaveTotp").With("user_id", userId)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userId]; !ok {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrForeignKeyConstraint)
+	}
+	if t, ok := m.totp[userId]; ok && t.EnabledAt != nil {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	m.totp[userId] = models.Totp{
+		UserId:    userId,
+		Secret:    secret,
+		CreatedAt: now(),
+	}
+	return nil
+}
+
+func (m *MemStorage) Totp(ctx context.Context, userId uint64) (*models.Totp, ero.Error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok := m.totp[userId]
+	if !ok {
+		return nil, ero.New(erolog.NewContextBuilder().With("op", "memory.MemStorage.Totp").With("user_id", userId).Build(),
+			ero.CodeNotFound, storage.ErrNoRows)
+	}
+	return &t, nil
+}
+
+func (m *MemStorage) EnableTotp(ctx context.Context, userId uint64, step int64) ero.Error {
+	return m.changeTotp("memory.MemStorage.EnableTotp", userId, func(t *models.Totp) bool {
+		if t.EnabledAt != nil {
+			return false
+		}
+		enabledAt := now()
+		t.EnabledAt, t.LastStep = &enabledAt, step
+		return true
+	})
+}
+
+func (m *MemStorage) UseTotpStep(ctx context.Context, userId uint64, step int64) ero.Error {
+	return m.changeTotp("memory.MemStorage.UseTotpStep", userId, func(t *models.Totp) bool {
+		if t.EnabledAt == nil || t.LastStep >= step {
+			return false
+		}
+		t.LastStep = step
+		return true
+	})
+}
+
+// changeTotp stores TOTP of the user changed by change, unless it returns false.
+// Returns storage.ErrNoRows if it has not been changed
+func (m *MemStorage) changeTotp(op string, userId uint64, change func(*models.Totp) bool) ero.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.totp[userId]
+	if !ok || !change(&t) {
+		return ero.New(erolog.NewContextBuilder().With("op", op).With("user_id", userId).Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+	m.totp[userId] = t
+	return nil
+}
+
+func (m *MemStorage) DeleteTotp(ctx context.Context, userId uint64) ero.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.recoveryCodes, userId)
+	if _, ok := m.totp[userId]; !ok {
+		return ero.New(erolog.NewContextBuilder().With("op", "memory.MemStorage.DeleteTotp").With("user_id", userId).Build(),
+			ero.CodeNotFound, storage.ErrNoRows)
+	}
+	delete(m.totp, userId)
+	return nil
+}
+
+func (m *MemStorage) SaveRecoveryCodes(ctx context.Context, userId uint64, hashes []string) ero.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userId]; !ok {
+		return ero.New(erolog.NewContextBuilder().With("op", "memory.MemStorage.SaveRecoveryCodes").With("user_id", userId).Build(),
+			ero.CodeNotFound, storage.ErrForeignKeyConstraint)
+	}
+	m.recoveryCodes[userId] = slices.Clone(hashes)
+	return nil
+}
+
+// UseRecoveryCode removes the code, memory keeps unused codes only
+func (m *MemStorage) UseRecoveryCode(ctx context.Context, userId uint64, hash string) ero.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hashes := m.recoveryCodes[userId]
+	i := slices.Index(hashes, hash)
+	if i < 0 {
+		return ero.New(erolog.NewContextBuilder().With("op", "memory.MemStorage.UseRecoveryCode").With("user_id", userId).Build(),
+			ero.CodeNotFound, storage.ErrNoRows)
+	}
+	m.recoveryCodes[userId] = slices.Delete(slices.Clone(hashes), i, i+1)
+	return nil
+}
@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+)
+
+// WithTx runs transactions one by one and restores the snapshot taken
+// before fn if it fails or panics. Operations outside of transactions are not isolated from it
+func (m *MemStorage) WithTx(ctx context.Context, fn func(tx storage.Storage) ero.Error) ero.Error {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	m.mu.RLock()
+	snapshot := m.data.clone()
+	m.mu.RUnlock()
+
+	rollback := func() {
+		m.mu.Lock()
+		m.data = snapshot
+		m.mu.Unlock()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			rollback()
+			panic(r)
+		}
+	}()
+
+	if eroErr := fn(memTx{m}); eroErr != nil {
+		rollback()
+		return eroErr
+	}
+
+	return nil
+}
+
+// memTx joins nested transactions to the outer one
+type memTx struct {
+	*MemStorage
+}
+
+func (tx memTx) WithTx(ctx context.Context, fn func(tx storage.Storage) ero.Error) ero.Error {
+	return fn(tx)
+}
@@ -0,0 +1,260 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (m *MemStorage) SaveUser(ctx context.Context, user *models.User) (*models.User, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.SaveUser").With("user_email", user.Email)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.emails[user.Email]; exists {
+		return nil, ero.New(logCtx.Build(), ero.CodeExists, storage.ErrUniqueConstraint)
+	}
+	country, ok := m.countryById(user.Country.Id)
+	if !ok {
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrForeignKeyConstraint)
+	}
+
+	m.lastUserId++
+	saved := *user
+	saved.Id = m.lastUserId
+	saved.Country = country
+	saved.EmailVerifiedAt = nil
+	saved.Role = models.RoleUser
+	saved.SuspendedAt = nil
+
+	m.users[saved.Id] = saved
+	m.emails[saved.Email] = saved.Id
+
+	return &saved, nil
+}
+
+func (m *MemStorage) UpdateUser(ctx context.Context, user *models.User) (*models.User, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.UpdateUser").With("user_id", user.Id)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	updated, ok := m.users[user.Id]
+	if !ok {
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+	country, ok := m.countryById(user.Country.Id)
+	if !ok {
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrForeignKeyConstraint)
+	}
+
+	updated.Name = user.Name
+	updated.Lastname = user.Lastname
+	updated.Image = user.Image
+	updated.Country = country
+	updated.IsPublic = user.IsPublic
+
+	m.users[updated.Id] = updated
+	return &updated, nil
+}
+
+func (m *MemStorage) UpdatePassword(ctx context.Context, userId uint64, hash string) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.UpdatePassword").With("user_id", userId)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userId]
+	if !ok {
+		return 0, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	user.PasswordHash = hash
+	user.TokenGeneration++
+	m.users[userId] = user
+
+	return user.TokenGeneration, nil
+}
+
+func (m *MemStorage) RehashPassword(ctx context.Context, userId uint64, oldHash, newHash string) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.RehashPassword").With("user_id", userId)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userId]
+	if !ok || user.PasswordHash != oldHash {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	user.PasswordHash = newHash
+	m.users[userId] = user
+
+	return nil
+}
+
+func (m *MemStorage) VerifyEmail(ctx context.Context, userId uint64, email string) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.VerifyEmail").With("user_id", userId)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userId]
+	if !ok || user.Email != email || user.EmailVerifiedAt != nil {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	at := now()
+	user.EmailVerifiedAt = &at
+	m.users[userId] = user
+
+	return nil
+}
+
+func (m *MemStorage) UserByEmail(ctx context.Context, email string) (*models.User, ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "memory.MemStorage.UserByEmail")
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	id, ok := m.emails[email]
+	if !ok {
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	user := m.users[id]
+	return &user, nil
+}
+
+func (m *MemStorage) UserById(ctx context.Context, id uint64) (*models.User, ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "memory.MemStorage.UserById").With("id", id)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	return &user, nil
+}
+
+// LockUser is UserById, transactions of MemStorage are run one by one anyway
+func (m *MemStorage) LockUser(ctx context.Context, id uint64) (*models.User, ero.Error) {
+	return m.UserById(ctx, id)
+}
+
+func (m *MemStorage) Users(ctx context.Context, query string, offset, count int) (<-chan models.User, <-chan ero.Error) {
+	users := make(chan models.User, 10)
+	errChan := make(chan ero.Error, 1)
+
+	m.mu.RLock()
+	selected := m.selectUsers(query)
+	m.mu.RUnlock()
+	selected = offsetPage[models.User](offset, count)(selected)
+
+	go func() {
+		defer close(users)
+		defer close(errChan)
+
+		for _, u := range selected {
+			select {
+			case users <- u:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return users, errChan
+}
+
+func (m *MemStorage) UsersNum(ctx context.Context, query string) (uint64, ero.Error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return uint64(len(m.selectUsers(query))), nil
+}
+
+// selectUsers matches query as pg does, ordered by id, m.mu must be locked
+func (m *MemStorage) selectUsers(query string) []models.User {
+	query = strings.ToLower(query)
+
+	selected := make([]models.User, 0)
+	for _, u := range m.users {
+		if strings.Contains(strings.ToLower(u.Email), query) ||
+			strings.Contains(strings.ToLower(u.Name), query) ||
+			strings.Contains(strings.ToLower(u.Lastname), query) {
+			selected = append(selected, u)
+		}
+	}
+	slices.SortFunc(selected, func(a, b models.User) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+
+	return selected
+}
+
+func (m *MemStorage) SuspendUser(ctx context.Context, userId uint64) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.SuspendUser").With("user_id", userId)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userId]
+	if !ok || user.SuspendedAt != nil {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	at := now()
+	user.SuspendedAt = &at
+	m.users[userId] = user
+
+	return nil
+}
+
+func (m *MemStorage) UnsuspendUser(ctx context.Context, userId uint64) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.UnsuspendUser").With("user_id", userId)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userId]
+	if !ok || user.SuspendedAt == nil {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	user.SuspendedAt = nil
+	m.users[userId] = user
+
+	return nil
+}
+
+func (m *MemStorage) SetRole(ctx context.Context, userId uint64, role models.Role) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "memory.MemStorage.SetRole").With("user_id", userId)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userId]
+	if !ok {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+	// the role column is constrained in pg
+	if !role.Valid() {
+		return ero.New(logCtx.Build(), ero.CodeBadRequest, storage.ErrCheckConstraint)
+	}
+
+	user.Role = role
+	m.users[userId] = user
+
+	return nil
+}
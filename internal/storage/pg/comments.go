@@ -0,0 +1,267 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (pg *PgStorage) SaveComment(ctx context.Context, comment *models.Comment) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.SaveComment").With("post_id", comment.PostId).With("author_id", comment.Author.Id)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		INSERT INTO comments (post_fk, author_fk, parent_id, content)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`,
+	)
+	if err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var id uint64
+	err = stmt.GetContext(ctx, &id, comment.PostId, comment.Author.Id, comment.ParentId, comment.Content)
+	if err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+	}
+
+	return id, nil
+}
+
+// commentColumns are selected from comments joined with users, see commentDest
+const commentColumns = `comments.id, comments.post_fk, comments.parent_id, comments.content,
+	comments.created_at, comments.updated_at, comments.deleted_at,
+	users.id, users.name, users.lastname, users.image`
+
+func commentDest(c *models.Comment) []any {
+	return []any{&c.Id, &c.PostId, &c.ParentId, &c.Content,
+		&c.CreatedAt, &c.UpdatedAt, &c.DeletedAt,
+		&c.Author.Id, &c.Author.Name, &c.Author.Lastname, &c.Author.Image}
+}
+
+// Comment gets the comment even if it is deleted, but not if its post is
+func (pg *PgStorage) Comment(ctx context.Context, id uint64) (*models.Comment, ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.Comment").With("comment_id", id)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM comments
+		JOIN users ON users.id = comments.author_fk
+		JOIN posts ON posts.id = comments.post_fk
+		WHERE comments.id = $1 AND posts.deleted_at IS NULL`, commentColumns),
+	)
+	if err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var c models.Comment
+	if err = stmt.QueryRowxContext(ctx, id).Scan(commentDest(&c)...); err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeNotFound, getError(err))
+	}
+
+	return &c, nil
+}
+
+func (pg *PgStorage) UpdateComment(ctx context.Context, comment *models.Comment) ero.Error {
+	return pg.changeComment(ctx, "pg.PgStorage.UpdateComment", "content = $2, updated_at = NOW()", comment.Id, comment.Content)
+}
+
+// DeleteComment marks the comment deleted, its replies are kept
+func (pg *PgStorage) DeleteComment(ctx context.Context, id uint64) ero.Error {
+	return pg.changeComment(ctx, "pg.PgStorage.DeleteComment", "deleted_at = NOW()", id)
+}
+
+func (pg *PgStorage) changeComment(ctx context.Context, op, set string, id uint64, args ...any) ero.Error {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", op).With("comment_id", id)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, fmt.Sprintf(`
+		UPDATE comments
+		SET %s
+		WHERE id = $1 AND deleted_at IS NULL`, set),
+	)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	res, err := stmt.ExecContext(ctx, append([]any{id}, args...)...)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	return nil
+}
+
+// Comments selects all comments of the post including replies, the oldest first
+func (pg *PgStorage) Comments(ctx context.Context, offset, count int, postId uint64) (<-chan models.Comment, <-chan ero.Error) {
+	return pg.commentsBy(ctx, `
+		WHERE comments.post_fk = $1 AND posts.deleted_at IS NULL
+		ORDER BY comments.created_at, comments.id
+		OFFSET $2
+		LIMIT $3`, postId, offset, count)
+}
+
+// RootComments selects comments to the post itself, the oldest first
+func (pg *PgStorage) RootComments(ctx context.Context, offset, count int, postId uint64) (<-chan models.Comment, <-chan ero.Error) {
+	return pg.commentsBy(ctx, `
+		WHERE comments.post_fk = $1 AND comments.parent_id IS NULL AND posts.deleted_at IS NULL
+		ORDER BY comments.created_at, comments.id
+		OFFSET $2
+		LIMIT $3`, postId, offset, count)
+}
+
+// Replies selects all replies to the comments and replies to them recursively, the oldest first
+func (pg *PgStorage) Replies(ctx context.Context, rootIds []uint64) (<-chan models.Comment, <-chan ero.Error) {
+	ids := make([]int64, len(rootIds))
+	for i := range rootIds {
+		ids[i] = int64(rootIds[i])
+	}
+
+	return pg.commentsBy(ctx, `
+		WHERE comments.id IN (
+			WITH RECURSIVE tree AS (
+				SELECT id FROM comments WHERE parent_id = ANY($1)
+				UNION ALL
+				SELECT comments.id FROM comments JOIN tree ON comments.parent_id = tree.id
+			)
+			SELECT id FROM tree
+		)
+		ORDER BY comments.created_at, comments.id`, ids)
+}
+
+func (pg *PgStorage) commentsBy(ctx context.Context, whereOrderLimit string, args ...any) (<-chan models.Comment, <-chan ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.Comments")
+
+	comments := make(chan models.Comment, 10)
+	errChan := make(chan ero.Error, 1)
+
+	go func() {
+		defer close(comments)
+		defer close(errChan)
+
+		ctx, cancel := pg.withTimeout(ctx)
+		defer cancel()
+
+		stmt, err := pg.prepare(ctx, fmt.Sprintf(`
+			SELECT %s
+			FROM comments
+			JOIN users ON users.id = comments.author_fk
+			JOIN posts ON posts.id = comments.post_fk
+			%s`, commentColumns, whereOrderLimit),
+		)
+		if err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+			return
+		}
+
+		rows, err := stmt.QueryxContext(ctx, args...)
+		if err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var c models.Comment
+			if err = rows.Scan(commentDest(&c)...); err != nil {
+				errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+
+			select {
+			case comments <- c:
+			case <-ctx.Done():
+				errChan <- ero.New(logCtx.With("error", ctx.Err()).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+			return
+		}
+	}()
+
+	return comments, errChan
+}
+
+// CommentsNum counts all comments of the post including deleted ones,
+// because they are listed as well
+func (pg *PgStorage) CommentsNum(ctx context.Context, postId uint64) (uint64, ero.Error) {
+	return pg.commentsNum(ctx, "post_fk = $1", postId)
+}
+
+func (pg *PgStorage) RootCommentsNum(ctx context.Context, postId uint64) (uint64, ero.Error) {
+	return pg.commentsNum(ctx, "post_fk = $1 AND parent_id IS NULL", postId)
+}
+
+func (pg *PgStorage) commentsNum(ctx context.Context, where string, args ...any) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.commentsNum")
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM comments WHERE %s`, where))
+	if err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var count uint64
+	if err = stmt.GetContext(ctx, &count, args...); err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+	}
+
+	return count, nil
+}
+
+// PostsCommentsNum counts not deleted comments of every post in one query.
+// Posts without comments are missing in the result
+func (pg *PgStorage) PostsCommentsNum(ctx context.Context, postIds []uint64) (map[uint64]uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.PostsCommentsNum").With("posts_count", len(postIds))
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		SELECT post_fk, COUNT(*)
+		FROM comments
+		WHERE post_fk = ANY($1) AND deleted_at IS NULL
+		GROUP BY post_fk`,
+	)
+	if err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	ids := make([]int64, len(postIds))
+	for i := range postIds {
+		ids[i] = int64(postIds[i])
+	}
+
+	rows, err := stmt.QueryxContext(ctx, ids)
+	if err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+	}
+	defer rows.Close()
+
+	res := make(map[uint64]uint64, len(postIds))
+	for rows.Next() {
+		var postId, count uint64
+		if err = rows.Scan(&postId, &count); err != nil {
+			return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+		}
+		res[postId] = count
+	}
+	if err = rows.Err(); err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+	}
+
+	return res, nil
+}
@@ -9,35 +9,30 @@ import (
 	"github.com/Onnywrite/tinkoff-prod/internal/storage"
 	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
-	"github.com/jmoiron/sqlx"
 )
 
 func (pg *PgStorage) Countries(ctx context.Context, regions ...string) ([]models.Country, ero.Error) {
 	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.Countries").With("regions", regions)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
 
 	if len(regions) == 0 {
 		return pg.AllCountries(ctx)
 	}
 
-	query, args, err := sqlx.In(`
+	stmt, err := pg.prepare(ctx, `
 		SELECT id, name, alpha2, alpha3, region
 		FROM countries
-		WHERE region IN(?)
+		WHERE region = ANY($1)
 		ORDER BY alpha2`,
-		regions,
 	)
 	if err != nil {
 		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
 	}
 
-	stmt, err := pg.db.PreparexContext(ctx, pg.db.Rebind(query))
-	if err != nil {
-		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
-	}
-
 	countries := make([]models.Country, 0, 256)
 
-	err = stmt.SelectContext(ctx, &countries, args...)
+	err = stmt.SelectContext(ctx, &countries, regions)
 	if err != nil {
 		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
 	}
@@ -51,14 +46,21 @@ func (pg *PgStorage) Countries(ctx context.Context, regions ...string) ([]models
 
 func (pg *PgStorage) AllCountries(ctx context.Context) ([]models.Country, ero.Error) {
 	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.AllCountries")
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
 
-	countries := make([]models.Country, 0, 256)
-
-	err := pg.db.SelectContext(ctx, &countries, `
+	stmt, err := pg.prepare(ctx, `
 		SELECT id, name, alpha2, alpha3, region
 		FROM countries
 		ORDER BY alpha2`,
 	)
+	if err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	countries := make([]models.Country, 0, 256)
+
+	err = stmt.SelectContext(ctx, &countries)
 	switch {
 	case errors.Is(err, sql.ErrNoRows):
 		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeNotFound, storage.ErrNoRows)
@@ -71,8 +73,10 @@ func (pg *PgStorage) AllCountries(ctx context.Context) ([]models.Country, ero.Er
 
 func (pg *PgStorage) Country(ctx context.Context, alpha2 string) (models.Country, ero.Error) {
 	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.Country").With("alpha2", alpha2)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
 
-	stmt, err := pg.db.PreparexContext(ctx, `
+	stmt, err := pg.prepare(ctx, `
   		SELECT id, name, alpha2, alpha3, region
   		FROM countries
   		WHERE alpha2 = $1`,
@@ -0,0 +1,288 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (pg *PgStorage) SaveFollow(ctx context.Context, follow models.Follow) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.SaveFollow").With("follower_id", follow.Follower.Id).With("followee_id", follow.Followee.Id)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		INSERT INTO follows (follower_fk, followee_fk, approved_at)
+		VALUES ($1, $2, $3)`,
+	)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	_, err = stmt.ExecContext(ctx, follow.Follower.Id, follow.Followee.Id, follow.ApprovedAt)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
+	}
+
+	return nil
+}
+
+// Follow gets the follow whether it is approved or not. Users are not joined
+func (pg *PgStorage) Follow(ctx context.Context, followerId, followeeId uint64) (*models.Follow, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.Follow").With("follower_id", followerId).With("followee_id", followeeId)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		SELECT followed_at, approved_at
+		FROM follows
+		WHERE follower_fk = $1 AND followee_fk = $2`,
+	)
+	if err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	follow := models.Follow{
+		Follower: models.User{Id: followerId},
+		Followee: models.User{Id: followeeId},
+	}
+	if err = stmt.QueryRowxContext(ctx, followerId, followeeId).Scan(&follow.FollowedAt, &follow.ApprovedAt); err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeNotFound, getError(err))
+	}
+
+	return &follow, nil
+}
+
+// ApproveFollow approves a pending follow request
+func (pg *PgStorage) ApproveFollow(ctx context.Context, follow models.Follow) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.ApproveFollow").With("follower_id", follow.Follower.Id).With("followee_id", follow.Followee.Id)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		UPDATE follows
+		SET approved_at = NOW()
+		WHERE follower_fk = $1 AND followee_fk = $2 AND approved_at IS NULL`,
+	)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	res, err := stmt.ExecContext(ctx, follow.Follower.Id, follow.Followee.Id)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	return nil
+}
+
+func (pg *PgStorage) ApproveFollows(ctx context.Context, followeeId uint64) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.ApproveFollows").With("followee_id", followeeId)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		UPDATE follows
+		SET approved_at = NOW()
+		WHERE followee_fk = $1 AND approved_at IS NULL`,
+	)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	if _, err = stmt.ExecContext(ctx, followeeId); err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
+	}
+
+	return nil
+}
+
+func (pg *PgStorage) DeleteFollow(ctx context.Context, follow models.Follow) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.DeleteFollow").With("follower_id", follow.Follower.Id).With("followee_id", follow.Followee.Id)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		DELETE FROM follows
+		WHERE follower_fk = $1 AND followee_fk = $2`,
+	)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	res, err := stmt.ExecContext(ctx, follow.Follower.Id, follow.Followee.Id)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	return nil
+}
+
+// followSide tells which user of a follow is selected and which one is filtered by
+type followSide struct {
+	// selected is joined with users
+	selected string
+	// by is compared with the user id
+	by string
+	// approved is true for follows, false for pending requests
+	approved bool
+}
+
+var (
+	followersSide  = followSide{selected: "follower_fk", by: "followee_fk", approved: true}
+	followingsSide = followSide{selected: "followee_fk", by: "follower_fk", approved: true}
+	requestsSide   = followSide{selected: "follower_fk", by: "followee_fk", approved: false}
+)
+
+func (side followSide) where() string {
+	if side.approved {
+		return side.by + " = $1 AND approved_at IS NOT NULL"
+	}
+	return side.by + " = $1 AND approved_at IS NULL"
+}
+
+func (pg *PgStorage) Followers(ctx context.Context, offset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error) {
+	return pg.followsBy(ctx, paging{offset: offset, count: count}, followersSide, userId)
+}
+
+func (pg *PgStorage) FollowersByKeyset(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error) {
+	return pg.followsBy(ctx, paging{keyset: &keyset, count: count}, followersSide, userId)
+}
+
+func (pg *PgStorage) Followings(ctx context.Context, offset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error) {
+	return pg.followsBy(ctx, paging{offset: offset, count: count}, followingsSide, userId)
+}
+
+func (pg *PgStorage) FollowingsByKeyset(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error) {
+	return pg.followsBy(ctx, paging{keyset: &keyset, count: count}, followingsSide, userId)
+}
+
+// FollowRequests are pending requests to follow the user
+func (pg *PgStorage) FollowRequests(ctx context.Context, offset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error) {
+	return pg.followsBy(ctx, paging{offset: offset, count: count}, requestsSide, userId)
+}
+
+func (pg *PgStorage) FollowRequestsByKeyset(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error) {
+	return pg.followsBy(ctx, paging{keyset: &keyset, count: count}, requestsSide, userId)
+}
+
+func (pg *PgStorage) followsBy(ctx context.Context, page paging, side followSide, userId uint64) (<-chan models.Follow, <-chan ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.Follows").With("offset", page.offset).With("count", page.count).With("user_id", userId)
+
+	follows := make(chan models.Follow, 10)
+	errChan := make(chan ero.Error, 1)
+
+	go func() {
+		defer close(follows)
+		defer close(errChan)
+
+		ctx, cancel := pg.withTimeout(ctx)
+		defer cancel()
+
+		pageCond, orderLimit, pageArgs := page.sql("followed_at", side.selected, 1)
+		stmt, err := pg.prepare(ctx, fmt.Sprintf(`
+			SELECT users.id, users.name, users.lastname, users.image, users.is_public, followed_at, approved_at
+			FROM follows
+			JOIN users ON users.id = %s
+			WHERE %s AND %s
+			%s`, side.selected, side.where(), pageCond, orderLimit),
+		)
+		if err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+			return
+		}
+
+		rows, err := stmt.QueryxContext(ctx, append([]any{userId}, pageArgs...)...)
+		if err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
+			return
+		}
+		defer rows.Close()
+
+		var buffered []models.Follow
+		for rows.Next() {
+			var (
+				follow models.Follow
+				user   models.User
+			)
+			err = rows.Scan(&user.Id, &user.Name, &user.Lastname, &user.Image, &user.IsPublic, &follow.FollowedAt, &follow.ApprovedAt)
+			if err != nil {
+				errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+
+			if side.selected == "follower_fk" {
+				follow.Follower, follow.Followee.Id = user, userId
+			} else {
+				follow.Followee, follow.Follower.Id = user, userId
+			}
+
+			if page.reversed() {
+				buffered = append(buffered, follow)
+				continue
+			}
+
+			select {
+			case follows <- follow:
+			case <-ctx.Done():
+				errChan <- ero.New(logCtx.With("error", ctx.Err()).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+			return
+		}
+
+		for i := len(buffered) - 1; i >= 0; i-- {
+			select {
+			case follows <- buffered[i]:
+			case <-ctx.Done():
+				errChan <- ero.New(logCtx.With("error", ctx.Err()).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+		}
+	}()
+
+	return follows, errChan
+}
+
+func (pg *PgStorage) FollowersNum(ctx context.Context, userId uint64) (uint64, ero.Error) {
+	return pg.followsNum(ctx, followersSide, userId)
+}
+
+func (pg *PgStorage) FollowingsNum(ctx context.Context, userId uint64) (uint64, ero.Error) {
+	return pg.followsNum(ctx, followingsSide, userId)
+}
+
+func (pg *PgStorage) FollowRequestsNum(ctx context.Context, userId uint64) (uint64, ero.Error) {
+	return pg.followsNum(ctx, requestsSide, userId)
+}
+
+func (pg *PgStorage) followsNum(ctx context.Context, side followSide, userId uint64) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.followsNum").With("user_id", userId)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `SELECT COUNT(*) FROM follows WHERE `+side.where())
+	if err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var count uint64
+	if err = stmt.GetContext(ctx, &count, userId); err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+	}
+
+	return count, nil
+}
@@ -0,0 +1,56 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (pg *PgStorage) SaveIdentity(ctx context.Context, identity *models.Identity) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.SaveIdentity").With("user_id", identity.UserId)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		INSERT INTO identities (user_fk, issuer, subject, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`,
+	)
+	if err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var id uint64
+	err = stmt.GetContext(ctx, &id, identity.UserId, identity.Issuer, identity.Subject, identity.Email)
+	if err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
+	}
+
+	return id, nil
+}
+
+func (pg *PgStorage) Identity(ctx context.Context, issuer, subject string) (*models.Identity, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.Identity").With("issuer", issuer)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		SELECT id, user_fk, issuer, subject, email, created_at
+		FROM identities
+		WHERE issuer = $1 AND subject = $2`,
+	)
+	if err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var i models.Identity
+	err = stmt.QueryRowxContext(ctx, issuer, subject).Scan(&i.Id, &i.UserId, &i.Issuer, &i.Subject, &i.Email, &i.CreatedAt)
+	if err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeNotFound, getError(err))
+	}
+
+	return &i, nil
+}
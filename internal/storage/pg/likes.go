@@ -2,6 +2,7 @@ package pg
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/Onnywrite/tinkoff-prod/internal/storage"
@@ -11,8 +12,10 @@ import (
 
 func (pg *PgStorage) SaveLike(ctx context.Context, like models.Like) ero.Error {
 	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.SaveLike").With("user_id", like.User.Id).With("post_id", like.Post.Id)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
 
-	stmt, err := pg.db.PreparexContext(ctx, `
+	stmt, err := pg.prepare(ctx, `
 		INSERT INTO likes (user_fk, post_fk)
 		VALUES ($1, $2)`,
 	)
@@ -30,8 +33,10 @@ func (pg *PgStorage) SaveLike(ctx context.Context, like models.Like) ero.Error {
 
 func (pg *PgStorage) DeleteLike(ctx context.Context, like models.Like) ero.Error {
 	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.SaveLike").With("user_id", like.User.Id).With("post_id", like.Post.Id)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
 
-	stmt, err := pg.db.PreparexContext(ctx, `
+	stmt, err := pg.prepare(ctx, `
 		DELETE FROM likes
 		WHERE user_fk = $1 AND post_fk = $2`,
 	)
@@ -54,7 +59,15 @@ func (pg *PgStorage) DeleteLike(ctx context.Context, like models.Like) ero.Error
 }
 
 func (pg *PgStorage) Likes(ctx context.Context, offset, count int, postId uint64) (<-chan models.Like, <-chan ero.Error) {
-	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.SaveLike").With("offset", offset).With("count", count).With("post_id", postId)
+	return pg.likesBy(ctx, paging{offset: offset, count: count}, postId)
+}
+
+func (pg *PgStorage) LikesByKeyset(ctx context.Context, keyset storage.Keyset, count int, postId uint64) (<-chan models.Like, <-chan ero.Error) {
+	return pg.likesBy(ctx, paging{keyset: &keyset, count: count}, postId)
+}
+
+func (pg *PgStorage) likesBy(ctx context.Context, page paging, postId uint64) (<-chan models.Like, <-chan ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.Likes").With("offset", page.offset).With("count", page.count).With("post_id", postId)
 
 	likesCh := make(chan models.Like, 10)
 	errChan := make(chan ero.Error, 1)
@@ -63,31 +76,36 @@ func (pg *PgStorage) Likes(ctx context.Context, offset, count int, postId uint64
 		defer close(likesCh)
 		defer close(errChan)
 
-		stmt, err := pg.db.PreparexContext(ctx, `
+		ctx, cancel := pg.withTimeout(ctx)
+		defer cancel()
+
+		pageCond, orderLimit, pageArgs := page.sql("liked_at", "user_fk", 1)
+		stmt, err := pg.prepare(ctx, fmt.Sprintf(`
 			SELECT users.id, users.name, users.lastname, users.email, country_fk,
 				   users.is_public, users.image, users.password, users.birthday, liked_at
 			FROM likes
 			JOIN users ON users.id = user_fk
-			WHERE post_fk = $3
-			ORDER BY liked_at DESC
-			OFFSET $1
-			LIMIT $2`,
+			JOIN posts ON posts.id = post_fk
+			WHERE post_fk = $1 AND posts.deleted_at IS NULL AND %s
+			%s`, pageCond, orderLimit),
 		)
 		if err != nil {
 			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
 			return
 		}
 
-		rows, err := stmt.QueryxContext(ctx, offset, count, postId)
+		rows, err := stmt.QueryxContext(ctx, append([]any{postId}, pageArgs...)...)
 		if err != nil {
 			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
 			return
 		}
 		defer rows.Close()
 
+		var buffered []models.Like
 		for rows.Next() {
 			select {
 			case <-ctx.Done():
+				errChan <- ero.New(logCtx.With("error", ctx.Err()).Build(), ero.CodeInternal, storage.ErrInternal)
 				return
 			default:
 			}
@@ -101,9 +119,28 @@ func (pg *PgStorage) Likes(ctx context.Context, offset, count int, postId uint64
 				return
 			}
 
+			if page.reversed() {
+				buffered = append(buffered, like)
+				continue
+			}
+
 			select {
 			case likesCh <- like:
 			case <-ctx.Done():
+				errChan <- ero.New(logCtx.With("error", ctx.Err()).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+			return
+		}
+
+		for i := len(buffered) - 1; i >= 0; i-- {
+			select {
+			case likesCh <- buffered[i]:
+			case <-ctx.Done():
+				errChan <- ero.New(logCtx.With("error", ctx.Err()).Build(), ero.CodeInternal, storage.ErrInternal)
 				return
 			}
 		}
@@ -114,14 +151,21 @@ func (pg *PgStorage) Likes(ctx context.Context, offset, count int, postId uint64
 
 func (pg *PgStorage) LikesNum(ctx context.Context, postId uint64) (uint64, ero.Error) {
 	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.LikesNum").With("post_id", postId)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
 
-	var count uint64
-	err := pg.db.GetContext(ctx, &count, `
+	stmt, err := pg.prepare(ctx, `
 		SELECT COUNT(*)
 		FROM likes
-		WHERE post_fk = $1`,
-		postId,
+		JOIN posts ON posts.id = post_fk
+		WHERE post_fk = $1 AND posts.deleted_at IS NULL`,
 	)
+	if err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var count uint64
+	err = stmt.GetContext(ctx, &count, postId)
 	if err != nil {
 		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
 	}
@@ -130,23 +174,96 @@ func (pg *PgStorage) LikesNum(ctx context.Context, postId uint64) (uint64, ero.E
 }
 
 func (pg *PgStorage) Like(ctx context.Context, userId, postId uint64) (models.Like, ero.Error) {
-	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.LikesNum").With("post_id", postId)
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.Like").With("post_id", postId)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
 
-	var like models.Like
-	row := pg.db.QueryRowxContext(ctx, `
+	stmt, err := pg.prepare(ctx, `
 		SELECT liked_at, user_fk, post_fk
 		FROM likes
 		WHERE user_fk = $1 AND post_fk = $2`,
-		userId, postId,
 	)
+	if err != nil {
+		return models.Like{}, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var like models.Like
+	row := stmt.QueryRowxContext(ctx, userId, postId)
 	if err := row.Err(); err != nil {
 		return models.Like{}, ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
 	}
 
-	err := row.Scan(&like.LikedAt, &like.User.Id, &like.Post.Id)
+	err = row.Scan(&like.LikedAt, &like.User.Id, &like.Post.Id)
 	if err != nil {
 		return models.Like{}, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
 	}
 
 	return like, nil
 }
+
+// PostsLikes gets likes count, whether the user has liked the post
+// and top most recent likes of every post in one query. Posts without likes are missing in the result
+func (pg *PgStorage) PostsLikes(ctx context.Context, postIds []uint64, userId uint64, top int) (map[uint64]models.PostLikes, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.PostsLikes").With("posts_count", len(postIds)).With("user_id", userId)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	// at least one row per post is selected to get its count even if top is 0
+	stmt, err := pg.prepare(ctx, `
+		SELECT l.post_fk, l.num, l.count, l.is_liked, l.liked_at,
+			   users.id, users.name, users.lastname, users.image
+		FROM (
+			SELECT post_fk, user_fk, liked_at,
+				   ROW_NUMBER() OVER (PARTITION BY post_fk ORDER BY liked_at DESC, user_fk DESC) AS num,
+				   COUNT(*) OVER (PARTITION BY post_fk) AS count,
+				   BOOL_OR(user_fk = $2) OVER (PARTITION BY post_fk) AS is_liked
+			FROM likes
+			WHERE post_fk = ANY($1)
+		) l
+		JOIN users ON users.id = l.user_fk
+		WHERE l.num <= GREATEST($3, 1)
+		ORDER BY l.post_fk, l.num`,
+	)
+	if err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	ids := make([]int64, len(postIds))
+	for i := range postIds {
+		ids[i] = int64(postIds[i])
+	}
+
+	rows, err := stmt.QueryxContext(ctx, ids, userId, top)
+	if err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
+	}
+	defer rows.Close()
+
+	res := make(map[uint64]models.PostLikes, len(postIds))
+	for rows.Next() {
+		var (
+			summary models.PostLikes
+			num     int
+			like    models.Like
+		)
+		err = rows.Scan(&summary.PostId, &num, &summary.Count, &summary.IsLiked, &like.LikedAt,
+			&like.User.Id, &like.User.Name, &like.User.Lastname, &like.User.Image)
+		if err != nil {
+			return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+		}
+
+		if prev, ok := res[summary.PostId]; ok {
+			summary.Top = prev.Top
+		}
+		if num <= top {
+			like.Post.Id = summary.PostId
+			summary.Top = append(summary.Top, like)
+		}
+		res[summary.PostId] = summary
+	}
+	if err = rows.Err(); err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
+	}
+
+	return res, nil
+}
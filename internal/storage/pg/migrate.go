@@ -0,0 +1,354 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrDirtyLegacy      = errors.New("legacy schema_migrations table is dirty")
+	ErrNoDownMigration  = errors.New("migration has no down file")
+	ErrUnknownVersion   = errors.New("unknown migration version")
+	ErrNoMigrationsLeft = errors.New("no migrations left")
+)
+
+// migrationsLockId is an arbitrary key of the advisory lock,
+// so that two instances never migrate the same database simultaneously
+const migrationsLockId = 7_351_204_118
+
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version uint64
+	Name    string
+
+	up   string
+	down string
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies migrations read from src. Applied versions are
+// stored in schema_versions table
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+func (pg *PgStorage) Migrator(src fs.FS) (*Migrator, error) {
+	migrations, err := readMigrations(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         pg.conn,
+		migrations: migrations,
+	}, nil
+}
+
+func readMigrations(src fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(src, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, entry := range entries {
+		matches := migrationFileRegexp.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("pg.readMigrations: %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(src, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		} else if m.Name != matches[2] {
+			return nil, fmt.Errorf("pg.readMigrations: version %d has two names '%s' and '%s'", version, m.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("pg.readMigrations: version %d has no up file", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmpUint(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
+
+func cmpUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Up applies all migrations that are not applied yet
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if len(m.migrations) == 0 {
+		return nil, nil
+	}
+	return m.Goto(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down reverts the last applied migration
+func (m *Migrator) Down(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			return ErrNoMigrationsLeft
+		}
+
+		last := slices.Max(applied)
+		done, err = m.revert(ctx, conn, []uint64{last})
+		return err
+	})
+	return done, err
+}
+
+// Goto applies or reverts migrations until version is the last applied one
+func (m *Migrator) Goto(ctx context.Context, version uint64) ([]Migration, error) {
+	if !slices.ContainsFunc(m.migrations, func(mig Migration) bool { return mig.Version == version }) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	var done []Migration
+	err := m.locked(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		toRevert := make([]uint64, 0)
+		for _, v := range applied {
+			if v > version {
+				toRevert = append(toRevert, v)
+			}
+		}
+		slices.Sort(toRevert)
+		slices.Reverse(toRevert)
+
+		done, err = m.revert(ctx, conn, toRevert)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if mig.Version > version || slices.Contains(applied, mig.Version) {
+				continue
+			}
+			if err = m.apply(ctx, conn, mig, mig.up, insertVersionQuery, mig.Version, mig.Name); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists all known migrations and versions which are applied,
+// but not present in the source
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.locked(ctx, func(conn *sqlx.Conn) error {
+		rows, err := conn.QueryxContext(ctx, `SELECT version, name, applied_at FROM schema_versions`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		applied := make(map[uint64]MigrationStatus)
+		for rows.Next() {
+			var s MigrationStatus
+			if err = rows.Scan(&s.Version, &s.Name, &s.AppliedAt); err != nil {
+				return err
+			}
+			s.Applied = true
+			applied[s.Version] = s
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			s, ok := applied[mig.Version]
+			delete(applied, mig.Version)
+			s.Migration = mig
+			s.Applied = ok
+			statuses = append(statuses, s)
+		}
+		for _, s := range applied {
+			statuses = append(statuses, s)
+		}
+		slices.SortFunc(statuses, func(a, b MigrationStatus) int {
+			return cmpUint(a.Version, b.Version)
+		})
+		return nil
+	})
+	return statuses, err
+}
+
+const (
+	insertVersionQuery = `INSERT INTO schema_versions (version, name) VALUES ($1, $2)`
+	deleteVersionQuery = `DELETE FROM schema_versions WHERE version = $1`
+)
+
+func (m *Migrator) revert(ctx context.Context, conn *sqlx.Conn, versions []uint64) ([]Migration, error) {
+	done := make([]Migration, 0, len(versions))
+	for _, v := range versions {
+		idx := slices.IndexFunc(m.migrations, func(mig Migration) bool { return mig.Version == v })
+		if idx == -1 {
+			return done, fmt.Errorf("%w: %d", ErrUnknownVersion, v)
+		}
+		mig := m.migrations[idx]
+		if mig.down == "" {
+			return done, fmt.Errorf("%w: %d_%s", ErrNoDownMigration, mig.Version, mig.Name)
+		}
+
+		if err := m.apply(ctx, conn, mig, mig.down, deleteVersionQuery, mig.Version); err != nil {
+			return done, err
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// apply runs script and records the version in one transaction,
+// so a failed migration leaves nothing behind
+func (m *Migrator) apply(ctx context.Context, conn *sqlx.Conn, mig Migration, script, versionQuery string, args ...any) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// without arguments pgx uses simple protocol, so scripts may contain many statements
+	if _, err = tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("pg.Migrator: %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	if _, err = tx.ExecContext(ctx, versionQuery, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// locked runs fn on a dedicated connection holding the advisory lock
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationsLockId); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, migrationsLockId)
+
+	if err = m.prepareTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) prepareTable(ctx context.Context, conn *sqlx.Conn) error {
+	_, err := conn.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_versions (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		return err
+	}
+
+	return m.adoptLegacy(ctx, conn)
+}
+
+// adoptLegacy marks migrations as applied if the database was migrated
+// by golang-migrate before and schema_versions is still empty
+func (m *Migrator) adoptLegacy(ctx context.Context, conn *sqlx.Conn) error {
+	var exists bool
+	err := conn.QueryRowxContext(ctx, `
+	SELECT to_regclass('schema_migrations') IS NOT NULL
+	AND NOT EXISTS (SELECT 1 FROM schema_versions)`).Scan(&exists)
+	if err != nil || !exists {
+		return err
+	}
+
+	var (
+		version int64
+		dirty   bool
+	)
+	err = conn.QueryRowxContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w: version %d", ErrDirtyLegacy, version)
+	}
+
+	for _, mig := range m.migrations {
+		if int64(mig.Version) > version {
+			break
+		}
+		if _, err = conn.ExecContext(ctx, insertVersionQuery, mig.Version, mig.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appliedVersions(ctx context.Context, conn *sqlx.Conn) ([]uint64, error) {
+	versions := make([]uint64, 0)
+	err := conn.SelectContext(ctx, &versions, `SELECT version FROM schema_versions`)
+	return versions, err
+}
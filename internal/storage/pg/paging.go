@@ -0,0 +1,40 @@
+package pg
+
+import (
+	"fmt"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+)
+
+// paging is either OFFSET/LIMIT or keyset pagination
+// of a list ordered by (time column DESC, id column DESC)
+type paging struct {
+	offset int
+	count  int
+	keyset *storage.Keyset
+}
+
+// sql returns a condition to be added to WHERE and an ORDER BY ... LIMIT ... clause.
+// Placeholders are numbered starting from argsNum+1
+func (p paging) sql(timeColumn, idColumn string, argsNum int) (cond, orderLimit string, args []any) {
+	if p.keyset == nil {
+		return "true",
+			fmt.Sprintf("ORDER BY %s DESC, %s DESC OFFSET $%d LIMIT $%d", timeColumn, idColumn, argsNum+1, argsNum+2),
+			[]any{p.offset, p.count}
+	}
+
+	op, order := "<", "DESC"
+	if p.keyset.Backward {
+		op, order = ">", "ASC"
+	}
+
+	return fmt.Sprintf("(%s, %s) %s ($%d, $%d)", timeColumn, idColumn, op, argsNum+1, argsNum+2),
+		fmt.Sprintf("ORDER BY %s %s, %s %s LIMIT $%d", timeColumn, order, idColumn, order, argsNum+3),
+		[]any{p.keyset.Time, p.keyset.Id, p.count}
+}
+
+// reversed reports whether selected rows go in ascending order
+// and must be reversed before returning them
+func (p paging) reversed() bool {
+	return p.keyset != nil && p.keyset.Backward
+}
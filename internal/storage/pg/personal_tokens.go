@@ -0,0 +1,159 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (pg *PgStorage) SavePersonalToken(ctx context.Context, token *models.PersonalToken) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.SavePersonalToken").With("user_id", token.UserId)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		INSERT INTO personal_tokens (user_fk, name, hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+	)
+	if err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var id uint64
+	err = stmt.GetContext(ctx, &id, token.UserId, token.Name, token.Hash, strings.Join(token.Scopes, " "), token.ExpiresAt)
+	if err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
+	}
+
+	return id, nil
+}
+
+const personalTokenColumns = `id, user_fk, name, hash, scopes, created_at, expires_at, last_used_at, revoked_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanPersonalToken scans personalTokenColumns, scopes are stored separated by spaces
+func scanPersonalToken(row scanner, t *models.PersonalToken) error {
+	var scopes string
+	err := row.Scan(&t.Id, &t.UserId, &t.Name, &t.Hash, &scopes, &t.CreatedAt, &t.ExpiresAt, &t.LastUsedAt, &t.RevokedAt)
+	t.Scopes = strings.Fields(scopes)
+	return err
+}
+
+// PersonalTokenByHash gets the token whether it is revoked or expired
+func (pg *PgStorage) PersonalTokenByHash(ctx context.Context, hash string) (*models.PersonalToken, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.PersonalTokenByHash")
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, fmt.Sprintf(`SELECT %s FROM personal_tokens WHERE hash = $1`, personalTokenColumns))
+	if err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var t models.PersonalToken
+	if err = scanPersonalToken(stmt.QueryRowxContext(ctx, hash), &t); err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeNotFound, getError(err))
+	}
+
+	return &t, nil
+}
+
+// UsePersonalToken sets last_used_at, but at most once a minute not to write on every request.
+// Returns storage.ErrNoRows if it has not been changed
+func (pg *PgStorage) UsePersonalToken(ctx context.Context, id uint64) ero.Error {
+	return pg.changePersonalTokens(ctx, "pg.PgStorage.UsePersonalToken", "last_used_at = NOW()",
+		"id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')", id)
+}
+
+func (pg *PgStorage) RevokePersonalToken(ctx context.Context, userId, id uint64) ero.Error {
+	return pg.changePersonalTokens(ctx, "pg.PgStorage.RevokePersonalToken", "revoked_at = NOW()", "user_fk = $1 AND id = $2", userId, id)
+}
+
+func (pg *PgStorage) changePersonalTokens(ctx context.Context, op, set, where string, args ...any) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", op)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, fmt.Sprintf(`
+		UPDATE personal_tokens
+		SET %s
+		WHERE %s AND revoked_at IS NULL`, set, where),
+	)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	res, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	return nil
+}
+
+// PersonalTokens selects not revoked and not expired tokens of the user, the latest first
+func (pg *PgStorage) PersonalTokens(ctx context.Context, userId uint64) (<-chan models.PersonalToken, <-chan ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.PersonalTokens").With("user_id", userId)
+
+	personalTokens := make(chan models.PersonalToken, 10)
+	errChan := make(chan ero.Error, 1)
+
+	go func() {
+		defer close(personalTokens)
+		defer close(errChan)
+
+		ctx, cancel := pg.withTimeout(ctx)
+		defer cancel()
+
+		stmt, err := pg.prepare(ctx, fmt.Sprintf(`
+			SELECT %s
+			FROM personal_tokens
+			WHERE user_fk = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+			ORDER BY created_at DESC, id DESC`, personalTokenColumns),
+		)
+		if err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+			return
+		}
+
+		rows, err := stmt.QueryxContext(ctx, userId)
+		if err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var t models.PersonalToken
+			if err = scanPersonalToken(rows, &t); err != nil {
+				errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+
+			select {
+			case personalTokens <- t:
+			case <-ctx.Done():
+				errChan <- ero.New(logCtx.With("error", ctx.Err()).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+			return
+		}
+	}()
+
+	return personalTokens, errChan
+}
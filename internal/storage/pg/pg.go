@@ -3,6 +3,8 @@ package pg
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/Onnywrite/tinkoff-prod/internal/storage"
 
@@ -12,30 +14,75 @@ import (
 )
 
 type PgStorage struct {
-	db *sqlx.DB
+	conn *sqlx.DB
+	// tx is not nil if PgStorage is bound to a transaction
+	tx *sqlx.Tx
+
+	stmts        *stmtCache
+	txPolicy     TxPolicy
+	queryTimeout time.Duration
+}
+
+var (
+	_ storage.Storage    = (*PgStorage)(nil)
+	_ storage.Transactor = (*PgStorage)(nil)
+)
+
+// Options tune the connection pool. Zero values leave database/sql defaults
+type Options struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// QueryTimeout limits every query, including reading all of its rows
+	QueryTimeout time.Duration
+	// TxPolicy is DefaultTxPolicy if zero
+	TxPolicy TxPolicy
 }
 
-func New(connString string) (*PgStorage, error) {
+func New(connString string, opts Options) (*PgStorage, error) {
 	db, err := sqlx.Connect("pgx", connString)
 	if err != nil {
 		return nil, err
 	}
 
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	// zero would mean no idle connections at all instead of the default
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+
+	if opts.TxPolicy == (TxPolicy{}) {
+		opts.TxPolicy = DefaultTxPolicy
+	}
+
 	return &PgStorage{
-		db: db,
+		conn:         db,
+		stmts:        newStmtCache(),
+		txPolicy:     opts.TxPolicy,
+		queryTimeout: opts.QueryTimeout,
 	}, nil
 }
 
 func (pg *PgStorage) Disconnect() error {
-	return pg.db.Close()
+	if pg.tx != nil {
+		return fmt.Errorf("pg.PgStorage: cannot disconnect inside of a transaction")
+	}
+	return errors.Join(pg.stmts.close(), pg.conn.Close())
 }
 
 // copied from https://github.com/jackc/pgerrcode/blob/master/errcode.go
 const (
-	notNullViolation    = "23502"
-	foreignKeyViolation = "23503"
-	uniqueViolation     = "23505"
-	checkViolation      = "23514"
+	notNullViolation     = "23502"
+	foreignKeyViolation  = "23503"
+	uniqueViolation      = "23505"
+	checkViolation       = "23514"
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
 )
 
 var errorsMap = map[string]error{
@@ -43,6 +90,8 @@ var errorsMap = map[string]error{
 	foreignKeyViolation:   storage.ErrForeignKeyConstraint,
 	uniqueViolation:       storage.ErrUniqueConstraint,
 	checkViolation:        storage.ErrCheckConstraint,
+	serializationFailure:  storage.ErrSerializationFailure,
+	deadlockDetected:      storage.ErrDeadlock,
 	sql.ErrNoRows.Error(): storage.ErrNoRows,
 }
 
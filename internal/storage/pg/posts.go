@@ -13,8 +13,10 @@ import (
 
 func (pg *PgStorage) SavePost(ctx context.Context, post *models.Post) (uint64, ero.Error) {
 	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.userBy").With("post_author_id", post.Author.Id)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
 
-	stmt, err := pg.db.PreparexContext(ctx, `
+	stmt, err := pg.prepare(ctx, `
 		INSERT INTO posts (author_fk, content, images_urls)
 		VALUES ($1, $2, $3)
 		RETURNING id`,
@@ -34,15 +36,48 @@ func (pg *PgStorage) SavePost(ctx context.Context, post *models.Post) (uint64, e
 }
 
 func (pg *PgStorage) Posts(ctx context.Context, offset, count int) (<-chan models.Post, <-chan ero.Error) {
-	return pg.postsBy(ctx, offset, count, "users.is_public = true")
+	return pg.postsBy(ctx, paging{offset: offset, count: count}, "users.is_public = true")
+}
+
+func (pg *PgStorage) PostsByKeyset(ctx context.Context, keyset storage.Keyset, count int) (<-chan models.Post, <-chan ero.Error) {
+	return pg.postsBy(ctx, paging{keyset: &keyset, count: count}, "users.is_public = true")
 }
 
 func (pg *PgStorage) UsersPosts(ctx context.Context, offset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error) {
-	return pg.postsBy(ctx, offset, count, "posts.author_fk = $3", userId)
+	return pg.postsBy(ctx, paging{offset: offset, count: count}, "posts.author_fk = $1", userId)
+}
+
+func (pg *PgStorage) UsersPostsByKeyset(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error) {
+	return pg.postsBy(ctx, paging{keyset: &keyset, count: count}, "posts.author_fk = $1", userId)
 }
 
-func (pg *PgStorage) postsBy(ctx context.Context, offset, count int, where string, args ...any) (<-chan models.Post, <-chan ero.Error) {
-	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.Posts").With("offset", offset).With("count", count)
+// timelineCond selects posts of the user and of everyone the user follows with approval
+const timelineCond = `(posts.author_fk = $1 OR posts.author_fk IN (
+	SELECT followee_fk FROM follows WHERE follower_fk = $1 AND approved_at IS NOT NULL))`
+
+func (pg *PgStorage) TimelinePosts(ctx context.Context, offset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error) {
+	return pg.postsBy(ctx, paging{offset: offset, count: count}, timelineCond, userId)
+}
+
+func (pg *PgStorage) TimelinePostsByKeyset(ctx context.Context, keyset storage.Keyset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error) {
+	return pg.postsBy(ctx, paging{keyset: &keyset, count: count}, timelineCond, userId)
+}
+
+// postColumns are selected from posts joined with users and countries, see postDest
+const postColumns = `posts.id, posts.content, posts.images_urls, posts.published_at, posts.updated_at,
+	users.id, users.name, users.lastname, users.email, users.is_public, users.image, users.password, users.birthday,
+	countries.id, countries.name, countries.alpha2, countries.alpha3, countries.region`
+
+func postDest(p *models.Post) []any {
+	return []any{&p.Id, &p.Content, &p.ImagesUrls, &p.PublishedAt, &p.UpdatedAt,
+		&p.Author.Id, &p.Author.Name, &p.Author.Lastname, &p.Author.Email, &p.Author.IsPublic,
+		&p.Author.Image, &p.Author.PasswordHash, &p.Author.Birthday,
+		&p.Author.Country.Id, &p.Author.Country.Name, &p.Author.Country.Alpha2,
+		&p.Author.Country.Alpha3, &p.Author.Country.Region}
+}
+
+func (pg *PgStorage) postsBy(ctx context.Context, page paging, where string, args ...any) (<-chan models.Post, <-chan ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.Posts").With("offset", page.offset).With("count", page.count)
 
 	posts := make(chan models.Post, 10)
 	errChan := make(chan ero.Error, 1)
@@ -51,50 +86,69 @@ func (pg *PgStorage) postsBy(ctx context.Context, offset, count int, where strin
 		defer close(posts)
 		defer close(errChan)
 
-		stmt, err := pg.db.PreparexContext(ctx, fmt.Sprintf(`
-			SELECT posts.id, posts.content, posts.images_urls, posts.published_at, posts.updated_at,
-				   users.id, users.name, users.lastname, users.email, users.is_public, users.image, users.password, users.birthday,
-				   countries.id, countries.name, countries.alpha2, countries.alpha3, countries.region
+		ctx, cancel := pg.withTimeout(ctx)
+		defer cancel()
+
+		pageCond, orderLimit, pageArgs := page.sql("posts.published_at", "posts.id", len(args))
+		stmt, err := pg.prepare(ctx, fmt.Sprintf(`
+			SELECT %s
 			FROM posts
 			JOIN users ON posts.author_fk = users.id
 			JOIN countries ON users.country_fk = countries.id
-			WHERE %s
-			ORDER BY published_at DESC
-			OFFSET $1
-			LIMIT $2`, where),
+			WHERE posts.deleted_at IS NULL AND %s AND %s
+			%s`, postColumns, where, pageCond, orderLimit),
 		)
 		if err != nil {
 			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
 			return
 		}
 
-		rows, err := stmt.QueryxContext(ctx, slices.Concat([]any{offset, count}, args)...)
+		rows, err := stmt.QueryxContext(ctx, slices.Concat(args, pageArgs)...)
 		if err != nil {
-			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
 			return
 		}
 		defer rows.Close()
 
+		// keyset pagination backwards selects rows in ascending order,
+		// so they are buffered to be sent reversed
+		var buffered []models.Post
 		for rows.Next() {
 			select {
 			case <-ctx.Done():
+				errChan <- ero.New(logCtx.With("error", ctx.Err()).Build(), ero.CodeInternal, storage.ErrInternal)
 				return
 			default:
 			}
 			var p models.Post
-			err = rows.Scan(&p.Id, &p.Content, &p.ImagesUrls, &p.PublishedAt, &p.UpdatedAt,
-				&p.Author.Id, &p.Author.Name, &p.Author.Lastname, &p.Author.Email, &p.Author.IsPublic,
-				&p.Author.Image, &p.Author.PasswordHash, &p.Author.Birthday,
-				&p.Author.Country.Id, &p.Author.Country.Name, &p.Author.Country.Alpha2,
-				&p.Author.Country.Alpha3, &p.Author.Country.Region)
+			err = rows.Scan(postDest(&p)...)
 			if err != nil {
 				errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
 				return
 			}
 
+			if page.reversed() {
+				buffered = append(buffered, p)
+				continue
+			}
+
 			select {
 			case posts <- p:
 			case <-ctx.Done():
+				errChan <- ero.New(logCtx.With("error", ctx.Err()).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+			return
+		}
+
+		for i := len(buffered) - 1; i >= 0; i-- {
+			select {
+			case posts <- buffered[i]:
+			case <-ctx.Done():
+				errChan <- ero.New(logCtx.With("error", ctx.Err()).Build(), ero.CodeInternal, storage.ErrInternal)
 				return
 			}
 		}
@@ -104,25 +158,107 @@ func (pg *PgStorage) postsBy(ctx context.Context, offset, count int, where strin
 }
 
 func (pg *PgStorage) UsersPostsNum(ctx context.Context, userId uint64) (uint64, ero.Error) {
-	return pg.postsNum(ctx, "WHERE posts.author_fk = $1", userId)
+	return pg.postsNum(ctx, "WHERE posts.deleted_at IS NULL AND posts.author_fk = $1", userId)
+}
+
+func (pg *PgStorage) TimelinePostsNum(ctx context.Context, userId uint64) (uint64, ero.Error) {
+	return pg.postsNum(ctx, "WHERE posts.deleted_at IS NULL AND "+timelineCond, userId)
 }
 
 func (pg *PgStorage) PostsNum(ctx context.Context) (uint64, ero.Error) {
-	return pg.postsNum(ctx, "JOIN users ON author_fk = users.id WHERE users.is_public = true")
+	return pg.postsNum(ctx, "JOIN users ON author_fk = users.id WHERE posts.deleted_at IS NULL AND users.is_public = true")
 }
 
 func (pg *PgStorage) postsNum(ctx context.Context, sql string, args ...any) (uint64, ero.Error) {
 	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.postsNum")
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
 
-	stmt, err := pg.db.PreparexContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM posts %s`, sql))
+	stmt, err := pg.prepare(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM posts %s`, sql))
 	if err != nil {
 		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
 	}
 	var estimate float64
 	err = stmt.GetContext(ctx, &estimate, args...)
 	if err != nil {
-		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
 	}
 
 	return uint64(estimate), nil
 }
+
+func (pg *PgStorage) Post(ctx context.Context, id uint64) (*models.Post, ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.Post").With("post_id", id)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM posts
+		JOIN users ON posts.author_fk = users.id
+		JOIN countries ON users.country_fk = countries.id
+		WHERE posts.id = $1 AND posts.deleted_at IS NULL`, postColumns),
+	)
+	if err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var p models.Post
+	if err = stmt.QueryRowxContext(ctx, id).Scan(postDest(&p)...); err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeNotFound, getError(err))
+	}
+
+	return &p, nil
+}
+
+// UpdatePost replaces content and images of the post and sets its updated_at
+func (pg *PgStorage) UpdatePost(ctx context.Context, post *models.Post) ero.Error {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.UpdatePost").With("post_id", post.Id)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		UPDATE posts
+		SET content = $2, images_urls = $3, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL`,
+	)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	res, err := stmt.ExecContext(ctx, post.Id, post.Content, post.ImagesUrls)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	return nil
+}
+
+// DeletePost marks the post deleted. Deleted posts and their likes are never selected
+func (pg *PgStorage) DeletePost(ctx context.Context, id uint64) ero.Error {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.DeletePost").With("post_id", id)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		UPDATE posts
+		SET deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL`,
+	)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	return nil
+}
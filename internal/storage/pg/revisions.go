@@ -0,0 +1,87 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+func (pg *PgStorage) SavePostRevision(ctx context.Context, revision models.PostRevision) ero.Error {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.SavePostRevision").With("post_id", revision.PostId)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		INSERT INTO post_revisions (post_fk, content, images_urls, created_at)
+		VALUES ($1, $2, $3, $4)`,
+	)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	_, err = stmt.ExecContext(ctx, revision.PostId, revision.Content, revision.ImagesUrls, revision.CreatedAt)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+	}
+
+	return nil
+}
+
+// PostRevisions selects all revisions of the post, the latest first
+func (pg *PgStorage) PostRevisions(ctx context.Context, postId uint64) (<-chan models.PostRevision, <-chan ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.PostRevisions").With("post_id", postId)
+
+	revisions := make(chan models.PostRevision, 10)
+	errChan := make(chan ero.Error, 1)
+
+	go func() {
+		defer close(revisions)
+		defer close(errChan)
+
+		ctx, cancel := pg.withTimeout(ctx)
+		defer cancel()
+
+		stmt, err := pg.prepare(ctx, `
+			SELECT id, post_fk, content, images_urls, created_at, replaced_at
+			FROM post_revisions
+			WHERE post_fk = $1
+			ORDER BY id DESC`,
+		)
+		if err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+			return
+		}
+
+		rows, err := stmt.QueryxContext(ctx, postId)
+		if err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var r models.PostRevision
+			err = rows.Scan(&r.Id, &r.PostId, &r.Content, &r.ImagesUrls, &r.CreatedAt, &r.ReplacedAt)
+			if err != nil {
+				errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+
+			select {
+			case revisions <- r:
+			case <-ctx.Done():
+				errChan <- ero.New(logCtx.With("error", ctx.Err()).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+			return
+		}
+	}()
+
+	return revisions, errChan
+}
@@ -0,0 +1,98 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+// headlineStart and headlineStop mark found words in ts_headline, they are removed from the content beforehand,
+// so a post cannot fake them
+const (
+	headlineStart = "\x02"
+	headlineStop  = "\x03"
+)
+
+// escapeHeadline HTML-escapes the content of the headline and replaces the marks with <b></b>
+func escapeHeadline(headline string) string {
+	return strings.NewReplacer(headlineStart, "<b>", headlineStop, "</b>").Replace(html.EscapeString(headline))
+}
+
+// SearchPosts selects public posts containing all words of query,
+// the most relevant first
+func (pg *PgStorage) SearchPosts(ctx context.Context, query string, offset, count int) (<-chan models.FoundPost, <-chan ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.SearchPosts").With("offset", offset).With("count", count)
+
+	posts := make(chan models.FoundPost, 10)
+	errChan := make(chan ero.Error, 1)
+
+	go func() {
+		defer close(posts)
+		defer close(errChan)
+
+		ctx, cancel := pg.withTimeout(ctx)
+		defer cancel()
+
+		stmt, err := pg.prepare(ctx, fmt.Sprintf(`
+			SELECT %s,
+				   ts_rank(posts.content_tsv, query) AS rank,
+				   ts_headline('simple', translate(posts.content, $4, ''), query, $5)
+			FROM posts
+			JOIN users ON posts.author_fk = users.id
+			JOIN countries ON users.country_fk = countries.id,
+			plainto_tsquery('simple', $1) query
+			WHERE posts.deleted_at IS NULL AND users.is_public = true AND posts.content_tsv @@ query
+			ORDER BY rank DESC, posts.published_at DESC, posts.id DESC
+			OFFSET $2
+			LIMIT $3`, postColumns),
+		)
+		if err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+			return
+		}
+
+		rows, err := stmt.QueryxContext(ctx, query, offset, count,
+			headlineStart+headlineStop, fmt.Sprintf("StartSel=%s, StopSel=%s, HighlightAll=true", headlineStart, headlineStop))
+		if err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var p models.FoundPost
+			err = rows.Scan(append(postDest(&p.Post), &p.Rank, &p.Headline)...)
+			if err != nil {
+				errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+			p.Headline = escapeHeadline(p.Headline)
+
+			select {
+			case posts <- p:
+			case <-ctx.Done():
+				errChan <- ero.New(logCtx.With("error", ctx.Err()).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+			return
+		}
+	}()
+
+	return posts, errChan
+}
+
+func (pg *PgStorage) SearchPostsNum(ctx context.Context, query string) (uint64, ero.Error) {
+	return pg.postsNum(ctx, `
+		JOIN users ON author_fk = users.id
+		WHERE posts.deleted_at IS NULL AND users.is_public = true
+		AND posts.content_tsv @@ plainto_tsquery('simple', $1)`, query)
+}
@@ -0,0 +1,168 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+// SaveSession saves an issued refresh token. If session.FamilyId is 0, a new family is started.
+// Returns the family id
+func (pg *PgStorage) SaveSession(ctx context.Context, session *models.Session) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.SaveSession").With("user_id", session.UserId).With("family_id", session.FamilyId)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		INSERT INTO sessions (jti, family_id, user_fk, user_agent, ip, expires_at)
+		VALUES ($1, COALESCE(NULLIF($2::BIGINT, 0), nextval('session_families_seq')), $3, $4, $5, $6)
+		RETURNING family_id`,
+	)
+	if err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var familyId uint64
+	err = stmt.GetContext(ctx, &familyId, session.Jti, session.FamilyId, session.UserId,
+		session.Device.UserAgent, session.Device.Ip, session.ExpiresAt)
+	if err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
+	}
+
+	return familyId, nil
+}
+
+// sessionColumns are selected from sessions s joined with the first token of the family, see sessionDest
+const sessionColumns = `s.jti, s.family_id, s.user_fk, s.user_agent, s.ip,
+	(SELECT MIN(created_at) FROM sessions f WHERE f.family_id = s.family_id),
+	s.created_at, s.expires_at, s.used_at, s.revoked_at`
+
+func sessionDest(s *models.Session) []any {
+	return []any{&s.Jti, &s.FamilyId, &s.UserId, &s.Device.UserAgent, &s.Device.Ip,
+		&s.StartedAt, &s.CreatedAt, &s.ExpiresAt, &s.UsedAt, &s.RevokedAt}
+}
+
+// Session gets the token whether it is used, revoked or expired
+func (pg *PgStorage) Session(ctx context.Context, jti string) (*models.Session, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.Session")
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, fmt.Sprintf(`SELECT %s FROM sessions s WHERE s.jti = $1`, sessionColumns))
+	if err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var s models.Session
+	if err = stmt.QueryRowxContext(ctx, jti).Scan(sessionDest(&s)...); err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeNotFound, getError(err))
+	}
+
+	return &s, nil
+}
+
+// UseSession marks the token used. Returns storage.ErrNoRows if it has already been used or revoked,
+// so only one of concurrent refreshes with the same token succeeds
+func (pg *PgStorage) UseSession(ctx context.Context, jti string) ero.Error {
+	return pg.changeSessions(ctx, "pg.PgStorage.UseSession", "used_at = NOW()", "jti = $1 AND used_at IS NULL", jti)
+}
+
+// RevokeSessionFamily revokes all tokens of the family.
+// Returns storage.ErrNoRows if the family has no tokens that are not revoked yet
+func (pg *PgStorage) RevokeSessionFamily(ctx context.Context, userId, familyId uint64) ero.Error {
+	return pg.changeSessions(ctx, "pg.PgStorage.RevokeSessionFamily", "revoked_at = NOW()", "user_fk = $1 AND family_id = $2", userId, familyId)
+}
+
+// RevokeSessions revokes all tokens of the user
+func (pg *PgStorage) RevokeSessions(ctx context.Context, userId uint64) ero.Error {
+	err := pg.changeSessions(ctx, "pg.PgStorage.RevokeSessions", "revoked_at = NOW()", "user_fk = $1", userId)
+	if err != nil && !errors.Is(err, storage.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+func (pg *PgStorage) changeSessions(ctx context.Context, op, set, where string, args ...any) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", op)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, fmt.Sprintf(`
+		UPDATE sessions
+		SET %s
+		WHERE %s AND revoked_at IS NULL`, set, where),
+	)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	res, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	return nil
+}
+
+// Sessions selects the latest token of every active family of the user, the latest first
+func (pg *PgStorage) Sessions(ctx context.Context, userId uint64) (<-chan models.Session, <-chan ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.Sessions").With("user_id", userId)
+
+	sessions := make(chan models.Session, 10)
+	errChan := make(chan ero.Error, 1)
+
+	go func() {
+		defer close(sessions)
+		defer close(errChan)
+
+		ctx, cancel := pg.withTimeout(ctx)
+		defer cancel()
+
+		stmt, err := pg.prepare(ctx, fmt.Sprintf(`
+			SELECT %s
+			FROM sessions s
+			WHERE s.user_fk = $1 AND s.used_at IS NULL AND s.revoked_at IS NULL AND s.expires_at > NOW()
+			ORDER BY s.created_at DESC`, sessionColumns),
+		)
+		if err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+			return
+		}
+
+		rows, err := stmt.QueryxContext(ctx, userId)
+		if err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var s models.Session
+			if err = rows.Scan(sessionDest(&s)...); err != nil {
+				errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+
+			select {
+			case sessions <- s:
+			case <-ctx.Done():
+				errChan <- ero.New(logCtx.With("error", ctx.Err()).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+			return
+		}
+	}()
+
+	return sessions, errChan
+}
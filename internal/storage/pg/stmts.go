@@ -0,0 +1,84 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// stmtCache prepares statements lazily, once per query,
+// and keeps them until close
+type stmtCache struct {
+	mu    sync.RWMutex
+	stmts map[string]*sqlx.Stmt
+}
+
+func newStmtCache() *stmtCache {
+	return &stmtCache{
+		stmts: make(map[string]*sqlx.Stmt),
+	}
+}
+
+func (c *stmtCache) get(ctx context.Context, conn *sqlx.DB, query string) (*sqlx.Stmt, error) {
+	c.mu.RLock()
+	stmt, ok := c.stmts[query]
+	c.mu.RUnlock()
+	if ok {
+		return stmt, nil
+	}
+
+	// preparing is a round trip, queries of other statements must not wait for it
+	prepared, err := conn.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if stmt, ok = c.stmts[query]; ok {
+		// another goroutine has prepared the same query first
+		prepared.Close()
+		return stmt, nil
+	}
+	c.stmts[query] = prepared
+
+	return prepared, nil
+}
+
+func (c *stmtCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for query, stmt := range c.stmts {
+		errs = append(errs, stmt.Close())
+		delete(c.stmts, query)
+	}
+
+	return errors.Join(errs...)
+}
+
+// prepare returns a cached prepared statement.
+// If pg is bound to a transaction, the statement is bound to it too
+func (pg *PgStorage) prepare(ctx context.Context, query string) (*sqlx.Stmt, error) {
+	stmt, err := pg.stmts.get(ctx, pg.conn, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if pg.tx != nil {
+		return pg.tx.StmtxContext(ctx, stmt), nil
+	}
+	return stmt, nil
+}
+
+// withTimeout limits ctx with the query timeout if it is set
+func (pg *PgStorage) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if pg.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, pg.queryTimeout)
+}
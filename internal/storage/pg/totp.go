@@ -0,0 +1,112 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+// SaveTotp starts or restarts enrollment with a new secret.
+// Returns storage.ErrNoRows if TOTP of the user is already enabled
+func (pg *PgStorage) SaveTotp(ctx context.Context, userId uint64, secret string) ero.Error {
+	return pg.exec(ctx, "pg.PgStorage.SaveTotp", `
+		INSERT INTO totp (user_fk, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_fk) DO UPDATE
+		SET secret = EXCLUDED.secret, created_at = NOW(), last_step = 0
+		WHERE totp.enabled_at IS NULL`, userId, secret)
+}
+
+func (pg *PgStorage) Totp(ctx context.Context, userId uint64) (*models.Totp, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.Totp").With("user_id", userId)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		SELECT user_fk, secret, created_at, enabled_at, last_step
+		FROM totp
+		WHERE user_fk = $1`,
+	)
+	if err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var t models.Totp
+	err = stmt.QueryRowxContext(ctx, userId).Scan(&t.UserId, &t.Secret, &t.CreatedAt, &t.EnabledAt, &t.LastStep)
+	if err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeNotFound, getError(err))
+	}
+
+	return &t, nil
+}
+
+// EnableTotp enables TOTP confirmed by the code of step.
+// Returns storage.ErrNoRows if it is not enrolled or already enabled
+func (pg *PgStorage) EnableTotp(ctx context.Context, userId uint64, step int64) ero.Error {
+	return pg.exec(ctx, "pg.PgStorage.EnableTotp", `
+		UPDATE totp
+		SET enabled_at = NOW(), last_step = $2
+		WHERE user_fk = $1 AND enabled_at IS NULL`, userId, step)
+}
+
+// UseTotpStep accepts a code of step once.
+// Returns storage.ErrNoRows if a code of the same or a later step has already been accepted
+func (pg *PgStorage) UseTotpStep(ctx context.Context, userId uint64, step int64) ero.Error {
+	return pg.exec(ctx, "pg.PgStorage.UseTotpStep", `
+		UPDATE totp
+		SET last_step = $2
+		WHERE user_fk = $1 AND enabled_at IS NOT NULL AND last_step < $2`, userId, step)
+}
+
+// DeleteTotp disables TOTP and deletes recovery codes of the user
+func (pg *PgStorage) DeleteTotp(ctx context.Context, userId uint64) ero.Error {
+	return pg.exec(ctx, "pg.PgStorage.DeleteTotp", `
+		WITH codes AS (DELETE FROM recovery_codes WHERE user_fk = $1)
+		DELETE FROM totp
+		WHERE user_fk = $1`, userId)
+}
+
+// SaveRecoveryCodes replaces all recovery codes of the user
+func (pg *PgStorage) SaveRecoveryCodes(ctx context.Context, userId uint64, hashes []string) ero.Error {
+	return pg.exec(ctx, "pg.PgStorage.SaveRecoveryCodes", `
+		WITH old AS (DELETE FROM recovery_codes WHERE user_fk = $1)
+		INSERT INTO recovery_codes (user_fk, hash)
+		SELECT $1, unnest($2::TEXT[])`, userId, hashes)
+}
+
+// UseRecoveryCode returns storage.ErrNoRows if the user has no such unused code
+func (pg *PgStorage) UseRecoveryCode(ctx context.Context, userId uint64, hash string) ero.Error {
+	return pg.exec(ctx, "pg.PgStorage.UseRecoveryCode", `
+		UPDATE recovery_codes
+		SET used_at = NOW()
+		WHERE id = (
+			SELECT id FROM recovery_codes
+			WHERE user_fk = $1 AND hash = $2 AND used_at IS NULL
+			LIMIT 1
+		)`, userId, hash)
+}
+
+// exec returns storage.ErrNoRows if query has affected no rows
+func (pg *PgStorage) exec(ctx context.Context, op, query string, args ...any) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", op)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, query)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	res, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	return nil
+}
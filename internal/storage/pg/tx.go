@@ -0,0 +1,101 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/storage"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
+	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
+)
+
+// TxPolicy tells how transactions are started and retried
+type TxPolicy struct {
+	Isolation sql.IsolationLevel
+	// MaxAttempts is how many times a transaction is run
+	// until it stops failing with serialization failure or deadlock
+	MaxAttempts int
+	// BaseDelay is doubled after each failed attempt up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+var DefaultTxPolicy = TxPolicy{
+	Isolation:   sql.LevelSerializable,
+	MaxAttempts: 5,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    500 * time.Millisecond,
+}
+
+// WithTx runs fn in a transaction, retrying it according to the TxPolicy.
+// If pg is already bound to a transaction, fn joins it
+func (pg *PgStorage) WithTx(ctx context.Context, fn func(tx storage.Storage) ero.Error) ero.Error {
+	if pg.tx != nil {
+		return fn(pg)
+	}
+
+	for attempt := 1; ; attempt++ {
+		eroErr := pg.runTx(ctx, fn)
+		if eroErr == nil {
+			return nil
+		}
+		if !isRetryable(eroErr) || attempt >= pg.txPolicy.MaxAttempts {
+			return eroErr
+		}
+
+		select {
+		case <-time.After(pg.txPolicy.delay(attempt)):
+		case <-ctx.Done():
+			return eroErr
+		}
+	}
+}
+
+func (pg *PgStorage) runTx(ctx context.Context, fn func(tx storage.Storage) ero.Error) (eroErr ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.WithTx")
+
+	tx, err := pg.conn.BeginTxx(ctx, &sql.TxOptions{Isolation: pg.txPolicy.Isolation})
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	txStorage := *pg
+	txStorage.tx = tx
+
+	if eroErr = fn(&txStorage); eroErr != nil {
+		tx.Rollback()
+		return eroErr
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeTemporaryUnavailable, getError(err))
+	}
+
+	return nil
+}
+
+func isRetryable(err error) bool {
+	return errors.Is(err, storage.ErrSerializationFailure) || errors.Is(err, storage.ErrDeadlock)
+}
+
+// delay is an exponential backoff with jitter, so that retried transactions do not collide again
+func (p TxPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d > p.MaxDelay || d <= 0 {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
@@ -2,6 +2,8 @@ package pg
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/Onnywrite/tinkoff-prod/internal/models"
 	"github.com/Onnywrite/tinkoff-prod/internal/storage"
@@ -9,19 +11,33 @@ import (
 	"github.com/Onnywrite/tinkoff-prod/pkg/erolog"
 )
 
+// userColumns selects a user of table, which is users or a CTE of it, joined with countries
+func userColumns(table string) string {
+	return fmt.Sprintf(`%[1]s.id, %[1]s.name, %[1]s.lastname, %[1]s.email, %[1]s.is_public, %[1]s.image, %[1]s.password, %[1]s.birthday,
+		%[1]s.token_generation, %[1]s.email_verified_at, %[1]s.role, %[1]s.suspended_at,
+		countries.id, countries.name, countries.alpha2, countries.alpha3, countries.region`, table)
+}
+
+func userDest(u *models.User) []any {
+	return []any{&u.Id, &u.Name, &u.Lastname, &u.Email, &u.IsPublic, &u.Image, &u.PasswordHash, &u.Birthday,
+		&u.TokenGeneration, &u.EmailVerifiedAt, &u.Role, &u.SuspendedAt,
+		&u.Country.Id, &u.Country.Name, &u.Country.Alpha2, &u.Country.Alpha3, &u.Country.Region}
+}
+
 func (pg *PgStorage) SaveUser(ctx context.Context, user *models.User) (*models.User, ero.Error) {
 	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.SaveUser").With("user_email", user.Email)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
 
-	stmt, err := pg.db.PreparexContext(ctx, `
-    	WITH u AS (
+	stmt, err := pg.prepare(ctx, fmt.Sprintf(`
+		WITH u AS (
 			INSERT INTO users (name, lastname, email, country_fk, is_public, image, password, birthday)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 			RETURNING *
 		)
-		SELECT u.id, u.name, u.lastname, u.email, u.is_public, u.image, u.password, u.birthday,
-			   countries.id, countries.name, countries.alpha2, countries.alpha3, countries.region
+		SELECT %s
 		FROM u
-		JOIN countries ON countries.id = country_fk`,
+		JOIN countries ON countries.id = country_fk`, userColumns("u")),
 	)
 	if err != nil {
 		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
@@ -33,15 +49,108 @@ func (pg *PgStorage) SaveUser(ctx context.Context, user *models.User) (*models.U
 	}
 
 	var saved models.User
-	err = row.Scan(&saved.Id, &saved.Name, &saved.Lastname, &saved.Email, &saved.IsPublic, &saved.Image, &saved.PasswordHash, &saved.Birthday,
-		&saved.Country.Id, &saved.Country.Name, &saved.Country.Alpha2, &saved.Country.Alpha3, &saved.Country.Region)
-	if err != nil {
+	if err = row.Scan(userDest(&saved)...); err != nil {
 		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
 	}
 
 	return &saved, nil
 }
 
+// UpdateUser updates name, lastname, image, country and is_public of the user
+func (pg *PgStorage) UpdateUser(ctx context.Context, user *models.User) (*models.User, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.UpdateUser").With("user_id", user.Id)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, fmt.Sprintf(`
+		WITH u AS (
+			UPDATE users
+			SET name = $2, lastname = $3, image = $4, country_fk = $5, is_public = $6
+			WHERE id = $1
+			RETURNING *
+		)
+		SELECT %s
+		FROM u
+		JOIN countries ON countries.id = country_fk`, userColumns("u")),
+	)
+	if err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	row := stmt.QueryRowxContext(ctx, user.Id, user.Name, user.Lastname, user.Image, user.Country.Id, user.IsPublic)
+	if err := row.Err(); err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
+	}
+
+	var updated models.User
+	if err = row.Scan(userDest(&updated)...); err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeNotFound, getError(err))
+	}
+
+	return &updated, nil
+}
+
+// UpdatePassword stores the new password hash and bumps the token generation,
+// so refresh tokens issued before are no longer valid
+func (pg *PgStorage) UpdatePassword(ctx context.Context, userId uint64, hash string) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.UpdatePassword").With("user_id", userId)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		UPDATE users
+		SET password = $2, token_generation = token_generation + 1
+		WHERE id = $1
+		RETURNING token_generation`,
+	)
+	if err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var generation uint64
+	if err = stmt.GetContext(ctx, &generation, userId, hash); err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeNotFound, getError(err))
+	}
+
+	return generation, nil
+}
+
+// RehashPassword replaces the hash of the same password, so the token generation is kept.
+// Returns storage.ErrNoRows if the password has been changed since oldHash was read
+func (pg *PgStorage) RehashPassword(ctx context.Context, userId uint64, oldHash, newHash string) ero.Error {
+	return pg.exec(ctx, "pg.PgStorage.RehashPassword", `
+		UPDATE users
+		SET password = $3
+		WHERE id = $1 AND password = $2`, userId, oldHash, newHash)
+}
+
+// VerifyEmail marks the email verified if it is still the email of the user.
+// Returns storage.ErrNoRows if it is not or has already been verified
+func (pg *PgStorage) VerifyEmail(ctx context.Context, userId uint64, email string) ero.Error {
+	logCtx := erolog.NewContextBuilder().With("op", "pg.PgStorage.VerifyEmail").With("user_id", userId)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `
+		UPDATE users
+		SET email_verified_at = NOW()
+		WHERE id = $1 AND email = $2 AND email_verified_at IS NULL`,
+	)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	res, err := stmt.ExecContext(ctx, userId, email)
+	if err != nil {
+		return ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ero.New(logCtx.Build(), ero.CodeNotFound, storage.ErrNoRows)
+	}
+
+	return nil
+}
+
 func (pg *PgStorage) UserByEmail(ctx context.Context, email string) (*models.User, ero.Error) {
 	return pg.userBy(ctx, "users.email = $1", email)
 }
@@ -50,16 +159,21 @@ func (pg *PgStorage) UserById(ctx context.Context, id uint64) (*models.User, ero
 	return pg.userBy(ctx, "users.id = $1", id)
 }
 
+func (pg *PgStorage) LockUser(ctx context.Context, id uint64) (*models.User, ero.Error) {
+	return pg.userBy(ctx, "users.id = $1 FOR UPDATE OF users", id)
+}
+
 func (pg *PgStorage) userBy(ctx context.Context, where string, args ...any) (*models.User, ero.Error) {
 	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.userBy").With("args", args)
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
 
-	stmt, err := pg.db.PreparexContext(ctx, `
-		SELECT users.id, users.name, users.lastname, users.email, users.is_public, users.image, users.password, users.birthday,
-			   countries.id AS c_id, countries.name AS c_name, countries.alpha2, countries.alpha3, countries.region
+	stmt, err := pg.prepare(ctx, fmt.Sprintf(`
+		SELECT %s
 		FROM users
 		JOIN countries
 		ON countries.id = country_fk
-		WHERE `+where,
+		WHERE %s`, userColumns("users"), where),
 	)
 	if err != nil {
 		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
@@ -71,8 +185,116 @@ func (pg *PgStorage) userBy(ctx context.Context, where string, args ...any) (*mo
 	}
 
 	var user models.User
-	row.Scan(&user.Id, &user.Name, &user.Lastname, &user.Email, &user.IsPublic, &user.Image, &user.PasswordHash, &user.Birthday,
-		&user.Country.Id, &user.Country.Name, &user.Country.Alpha2, &user.Country.Alpha3, &user.Country.Region)
+	if err = row.Scan(userDest(&user)...); err != nil {
+		return nil, ero.New(logCtx.With("error", err).Build(), ero.CodeUnknownServer, getError(err))
+	}
 
 	return &user, nil
 }
+
+// usersMatching filters users by a substring of email, name or lastname, an empty query matches everyone
+const usersMatching = `users.email ILIKE $1 OR users.name ILIKE $1 OR users.lastname ILIKE $1`
+
+// Users selects users matching query, see usersMatching, ordered by id
+func (pg *PgStorage) Users(ctx context.Context, query string, offset, count int) (<-chan models.User, <-chan ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.Users").With("offset", offset).With("count", count)
+
+	users := make(chan models.User, 10)
+	errChan := make(chan ero.Error, 1)
+
+	go func() {
+		defer close(users)
+		defer close(errChan)
+
+		ctx, cancel := pg.withTimeout(ctx)
+		defer cancel()
+
+		stmt, err := pg.prepare(ctx, fmt.Sprintf(`
+			SELECT %s
+			FROM users
+			JOIN countries ON countries.id = country_fk
+			WHERE %s
+			ORDER BY users.id
+			OFFSET $2
+			LIMIT $3`, userColumns("users"), usersMatching),
+		)
+		if err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+			return
+		}
+
+		rows, err := stmt.QueryxContext(ctx, likePattern(query), offset, count)
+		if err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var u models.User
+			if err = rows.Scan(userDest(&u)...); err != nil {
+				errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+
+			select {
+			case users <- u:
+			case <-ctx.Done():
+				errChan <- ero.New(logCtx.With("error", ctx.Err()).Build(), ero.CodeInternal, storage.ErrInternal)
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			errChan <- ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+			return
+		}
+	}()
+
+	return users, errChan
+}
+
+func (pg *PgStorage) UsersNum(ctx context.Context, query string) (uint64, ero.Error) {
+	logCtx := erolog.NewContextBuilder().WithParent(ctx).With("op", "pg.PgStorage.UsersNum")
+	ctx, cancel := pg.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := pg.prepare(ctx, `SELECT COUNT(*) FROM users WHERE `+usersMatching)
+	if err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, storage.ErrInternal)
+	}
+
+	var num uint64
+	if err = stmt.GetContext(ctx, &num, likePattern(query)); err != nil {
+		return 0, ero.New(logCtx.With("error", err).Build(), ero.CodeInternal, getError(err))
+	}
+
+	return num, nil
+}
+
+// SuspendUser returns storage.ErrNoRows if the user is not found or already suspended
+func (pg *PgStorage) SuspendUser(ctx context.Context, userId uint64) ero.Error {
+	return pg.exec(ctx, "pg.PgStorage.SuspendUser", `
+		UPDATE users
+		SET suspended_at = NOW()
+		WHERE id = $1 AND suspended_at IS NULL`, userId)
+}
+
+// UnsuspendUser returns storage.ErrNoRows if the user is not found or not suspended
+func (pg *PgStorage) UnsuspendUser(ctx context.Context, userId uint64) ero.Error {
+	return pg.exec(ctx, "pg.PgStorage.UnsuspendUser", `
+		UPDATE users
+		SET suspended_at = NULL
+		WHERE id = $1 AND suspended_at IS NOT NULL`, userId)
+}
+
+func (pg *PgStorage) SetRole(ctx context.Context, userId uint64, role models.Role) ero.Error {
+	return pg.exec(ctx, "pg.PgStorage.SetRole", `
+		UPDATE users
+		SET role = $2
+		WHERE id = $1`, userId, string(role))
+}
+
+// likePattern matches query anywhere, wildcards in it are escaped
+func likePattern(query string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query) + "%"
+}
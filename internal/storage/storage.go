@@ -1,7 +1,11 @@
 package storage
 
 import (
+	"context"
 	"errors"
+
+	"github.com/Onnywrite/tinkoff-prod/internal/models"
+	"github.com/Onnywrite/tinkoff-prod/pkg/ero"
 )
 
 var (
@@ -13,4 +17,115 @@ var (
 	ErrForeignKeyConstraint = errors.New("foreign key violation")
 	ErrNotNullConstraint    = errors.New("not null constraint violation")
 	ErrCheckConstraint      = errors.New("check constraint violation")
+
+	ErrSerializationFailure = errors.New("could not serialize access due to concurrent update")
+	ErrDeadlock             = errors.New("deadlock detected")
 )
+
+// Transactor runs fn in a transaction. Every operation of the transaction must use tx.
+// The transaction is committed if fn returns nil and rolled back otherwise.
+// fn may be called several times if the transaction is retried,
+// so it must not have side effects outside of tx
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(tx Storage) ero.Error) ero.Error
+}
+
+// Storage is everything services need from a storage backend.
+// Both pg.PgStorage and memory.MemStorage implement it
+type Storage interface {
+	Countries(ctx context.Context, regions ...string) ([]models.Country, ero.Error)
+	AllCountries(ctx context.Context) ([]models.Country, ero.Error)
+	Country(ctx context.Context, alpha2 string) (models.Country, ero.Error)
+
+	SaveUser(ctx context.Context, user *models.User) (*models.User, ero.Error)
+	UserByEmail(ctx context.Context, email string) (*models.User, ero.Error)
+	UserById(ctx context.Context, id uint64) (*models.User, ero.Error)
+	// LockUser is UserById that locks the user until the end of the transaction
+	LockUser(ctx context.Context, id uint64) (*models.User, ero.Error)
+	UpdateUser(ctx context.Context, user *models.User) (*models.User, ero.Error)
+	UpdatePassword(ctx context.Context, userId uint64, hash string) (uint64, ero.Error)
+	RehashPassword(ctx context.Context, userId uint64, oldHash, newHash string) ero.Error
+	VerifyEmail(ctx context.Context, userId uint64, email string) ero.Error
+	Users(ctx context.Context, query string, offset, count int) (<-chan models.User, <-chan ero.Error)
+	UsersNum(ctx context.Context, query string) (uint64, ero.Error)
+	SuspendUser(ctx context.Context, userId uint64) ero.Error
+	UnsuspendUser(ctx context.Context, userId uint64) ero.Error
+	SetRole(ctx context.Context, userId uint64, role models.Role) ero.Error
+
+	SaveSession(ctx context.Context, session *models.Session) (uint64, ero.Error)
+	Session(ctx context.Context, jti string) (*models.Session, ero.Error)
+	UseSession(ctx context.Context, jti string) ero.Error
+	RevokeSessionFamily(ctx context.Context, userId, familyId uint64) ero.Error
+	RevokeSessions(ctx context.Context, userId uint64) ero.Error
+	Sessions(ctx context.Context, userId uint64) (<-chan models.Session, <-chan ero.Error)
+
+	SavePersonalToken(ctx context.Context, token *models.PersonalToken) (uint64, ero.Error)
+	PersonalTokenByHash(ctx context.Context, hash string) (*models.PersonalToken, ero.Error)
+	UsePersonalToken(ctx context.Context, id uint64) ero.Error
+	RevokePersonalToken(ctx context.Context, userId, id uint64) ero.Error
+	PersonalTokens(ctx context.Context, userId uint64) (<-chan models.PersonalToken, <-chan ero.Error)
+
+	SaveTotp(ctx context.Context, userId uint64, secret string) ero.Error
+	Totp(ctx context.Context, userId uint64) (*models.Totp, ero.Error)
+	EnableTotp(ctx context.Context, userId uint64, step int64) ero.Error
+	UseTotpStep(ctx context.Context, userId uint64, step int64) ero.Error
+	DeleteTotp(ctx context.Context, userId uint64) ero.Error
+	SaveRecoveryCodes(ctx context.Context, userId uint64, hashes []string) ero.Error
+	UseRecoveryCode(ctx context.Context, userId uint64, hash string) ero.Error
+
+	SaveIdentity(ctx context.Context, identity *models.Identity) (uint64, ero.Error)
+	Identity(ctx context.Context, issuer, subject string) (*models.Identity, ero.Error)
+
+	SavePost(ctx context.Context, post *models.Post) (uint64, ero.Error)
+	Post(ctx context.Context, id uint64) (*models.Post, ero.Error)
+	UpdatePost(ctx context.Context, post *models.Post) ero.Error
+	DeletePost(ctx context.Context, id uint64) ero.Error
+	SavePostRevision(ctx context.Context, revision models.PostRevision) ero.Error
+	PostRevisions(ctx context.Context, postId uint64) (<-chan models.PostRevision, <-chan ero.Error)
+	Posts(ctx context.Context, offset, count int) (<-chan models.Post, <-chan ero.Error)
+	PostsByKeyset(ctx context.Context, keyset Keyset, count int) (<-chan models.Post, <-chan ero.Error)
+	UsersPosts(ctx context.Context, offset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error)
+	UsersPostsByKeyset(ctx context.Context, keyset Keyset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error)
+	PostsNum(ctx context.Context) (uint64, ero.Error)
+	UsersPostsNum(ctx context.Context, userId uint64) (uint64, ero.Error)
+	TimelinePosts(ctx context.Context, offset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error)
+	TimelinePostsByKeyset(ctx context.Context, keyset Keyset, count int, userId uint64) (<-chan models.Post, <-chan ero.Error)
+	TimelinePostsNum(ctx context.Context, userId uint64) (uint64, ero.Error)
+	SearchPosts(ctx context.Context, query string, offset, count int) (<-chan models.FoundPost, <-chan ero.Error)
+	SearchPostsNum(ctx context.Context, query string) (uint64, ero.Error)
+
+	SaveLike(ctx context.Context, like models.Like) ero.Error
+	DeleteLike(ctx context.Context, like models.Like) ero.Error
+	Likes(ctx context.Context, offset, count int, postId uint64) (<-chan models.Like, <-chan ero.Error)
+	LikesByKeyset(ctx context.Context, keyset Keyset, count int, postId uint64) (<-chan models.Like, <-chan ero.Error)
+	LikesNum(ctx context.Context, postId uint64) (uint64, ero.Error)
+	Like(ctx context.Context, userId, postId uint64) (models.Like, ero.Error)
+	PostsLikes(ctx context.Context, postIds []uint64, userId uint64, top int) (map[uint64]models.PostLikes, ero.Error)
+
+	SaveFollow(ctx context.Context, follow models.Follow) ero.Error
+	DeleteFollow(ctx context.Context, follow models.Follow) ero.Error
+	Follow(ctx context.Context, followerId, followeeId uint64) (*models.Follow, ero.Error)
+	ApproveFollow(ctx context.Context, follow models.Follow) ero.Error
+	// ApproveFollows approves every pending follow of the followee
+	ApproveFollows(ctx context.Context, followeeId uint64) ero.Error
+	Followers(ctx context.Context, offset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error)
+	FollowersByKeyset(ctx context.Context, keyset Keyset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error)
+	Followings(ctx context.Context, offset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error)
+	FollowingsByKeyset(ctx context.Context, keyset Keyset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error)
+	FollowersNum(ctx context.Context, userId uint64) (uint64, ero.Error)
+	FollowingsNum(ctx context.Context, userId uint64) (uint64, ero.Error)
+	FollowRequests(ctx context.Context, offset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error)
+	FollowRequestsByKeyset(ctx context.Context, keyset Keyset, count int, userId uint64) (<-chan models.Follow, <-chan ero.Error)
+	FollowRequestsNum(ctx context.Context, userId uint64) (uint64, ero.Error)
+
+	SaveComment(ctx context.Context, comment *models.Comment) (uint64, ero.Error)
+	Comment(ctx context.Context, id uint64) (*models.Comment, ero.Error)
+	UpdateComment(ctx context.Context, comment *models.Comment) ero.Error
+	DeleteComment(ctx context.Context, id uint64) ero.Error
+	Comments(ctx context.Context, offset, count int, postId uint64) (<-chan models.Comment, <-chan ero.Error)
+	RootComments(ctx context.Context, offset, count int, postId uint64) (<-chan models.Comment, <-chan ero.Error)
+	Replies(ctx context.Context, rootIds []uint64) (<-chan models.Comment, <-chan ero.Error)
+	CommentsNum(ctx context.Context, postId uint64) (uint64, ero.Error)
+	RootCommentsNum(ctx context.Context, postId uint64) (uint64, ero.Error)
+	PostsCommentsNum(ctx context.Context, postIds []uint64) (map[uint64]uint64, ero.Error)
+}
@@ -0,0 +1 @@
+-- the role may own other databases and is the one migrations are run as, so it is never dropped
@@ -0,0 +1,13 @@
+CREATE TABLE comments (
+    id BIGSERIAL PRIMARY KEY,
+    post_fk BIGINT NOT NULL REFERENCES posts(id),
+    author_fk INT NOT NULL REFERENCES users(id),
+    parent_id BIGINT NULL REFERENCES comments(id),
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NULL,
+    deleted_at TIMESTAMP NULL
+);
+
+CREATE INDEX comments_post_fk_created_at_idx ON comments USING btree (post_fk, created_at, id);
+CREATE INDEX comments_parent_id_idx ON comments(parent_id);
\ No newline at end of file
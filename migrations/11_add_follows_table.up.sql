@@ -0,0 +1,10 @@
+CREATE TABLE follows (
+    follower_fk INT REFERENCES users(id),
+    followee_fk INT REFERENCES users(id),
+    followed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (follower_fk, followee_fk),
+    CHECK (follower_fk <> followee_fk)
+);
+
+CREATE INDEX follows_followee_fk_followed_at_idx ON follows USING btree (followee_fk, followed_at DESC, follower_fk DESC);
+CREATE INDEX follows_follower_fk_followed_at_idx ON follows USING btree (follower_fk, followed_at DESC, followee_fk DESC);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS follows_requests_idx;
+
+ALTER TABLE follows DROP COLUMN IF EXISTS approved_at;
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE follows ADD COLUMN approved_at TIMESTAMP;
+
+-- follows of private users made before requests existed are kept
+UPDATE follows SET approved_at = followed_at;
+
+CREATE INDEX follows_requests_idx ON follows USING btree (followee_fk, followed_at DESC, follower_fk DESC) WHERE approved_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- token_generation is the rotation of refresh tokens, see tokens.Refresh.
+-- Bumping it invalidates all issued refresh tokens of the user
+ALTER TABLE users ADD COLUMN token_generation BIGINT NOT NULL DEFAULT 0;
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS sessions;
+
+DROP SEQUENCE IF EXISTS session_families_seq;
\ No newline at end of file
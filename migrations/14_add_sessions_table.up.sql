@@ -0,0 +1,18 @@
+CREATE SEQUENCE session_families_seq;
+
+-- sessions stores every issued refresh token. Tokens of one device form a family:
+-- each refresh marks the token used and issues the next one of the same family
+CREATE TABLE sessions (
+    jti CHAR(32) PRIMARY KEY,
+    family_id BIGINT NOT NULL,
+    user_fk INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX sessions_family_id_idx ON sessions USING btree (family_id);
+CREATE INDEX sessions_active_idx ON sessions USING btree (user_fk, created_at DESC) WHERE used_at IS NULL AND revoked_at IS NULL;
\ No newline at end of file
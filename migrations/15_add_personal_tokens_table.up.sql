@@ -0,0 +1,15 @@
+-- personal_tokens are long-lived tokens of integrations. Only SHA-256 of a token is stored,
+-- scopes are separated by spaces as in OAuth
+CREATE TABLE personal_tokens (
+    id SERIAL PRIMARY KEY,
+    user_fk INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    hash CHAR(64) UNIQUE NOT NULL,
+    scopes TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX personal_tokens_active_idx ON personal_tokens USING btree (user_fk, created_at DESC) WHERE revoked_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- totp keeps the second factor of users, secret is encrypted by the service.
+-- Recovery codes are hashed with SHA-256 as personal tokens
+CREATE TABLE totp (
+    user_fk INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    enabled_at TIMESTAMP,
+    last_step BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_fk INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX recovery_codes_user_idx ON recovery_codes USING btree (user_fk) WHERE used_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- identities link users to accounts at OpenID Connect providers, an account is identified by iss and sub
+CREATE TABLE identities (
+    id SERIAL PRIMARY KEY,
+    user_fk INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (issuer, subject)
+);
+
+CREATE INDEX identities_user_idx ON identities USING btree (user_fk);
\ No newline at end of file
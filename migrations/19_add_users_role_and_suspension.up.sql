@@ -0,0 +1,4 @@
+-- role grants access to the admin api, suspended users cannot sign in
+ALTER TABLE users
+    ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin')),
+    ADD COLUMN suspended_at TIMESTAMP;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- fails while argon2id hashes are stored, users must reset their passwords first
+ALTER TABLE users ALTER COLUMN password TYPE CHAR(60);
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- argon2id hashes are longer than bcrypt ones
+ALTER TABLE users ALTER COLUMN password TYPE VARCHAR(255);
\ No newline at end of file
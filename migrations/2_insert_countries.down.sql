@@ -0,0 +1 @@
+TRUNCATE countries RESTART IDENTITY;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS published_at_idx;
+DROP INDEX IF EXISTS author_fk_idx;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS likes;
\ No newline at end of file
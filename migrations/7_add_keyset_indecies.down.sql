@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS likes_post_fk_liked_at_user_fk_idx;
+DROP INDEX IF EXISTS posts_published_at_id_idx;
\ No newline at end of file
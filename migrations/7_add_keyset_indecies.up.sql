@@ -0,0 +1,2 @@
+CREATE INDEX posts_published_at_id_idx ON posts USING btree (published_at DESC, id DESC);
+CREATE INDEX likes_post_fk_liked_at_user_fk_idx ON likes USING btree (post_fk, liked_at DESC, user_fk DESC);
\ No newline at end of file
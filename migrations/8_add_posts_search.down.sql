@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS posts_content_tsv_idx;
+ALTER TABLE posts DROP COLUMN IF EXISTS content_tsv;
\ No newline at end of file
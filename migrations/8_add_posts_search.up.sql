@@ -0,0 +1,2 @@
+ALTER TABLE posts ADD COLUMN content_tsv tsvector GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED;
+CREATE INDEX posts_content_tsv_idx ON posts USING gin (content_tsv);
\ No newline at end of file
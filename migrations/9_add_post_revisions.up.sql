@@ -0,0 +1,12 @@
+ALTER TABLE posts ADD COLUMN deleted_at TIMESTAMP NULL;
+
+CREATE TABLE post_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    post_fk BIGINT NOT NULL REFERENCES posts(id),
+    content TEXT NOT NULL,
+    images_urls TEXT[] NULL,
+    created_at TIMESTAMP NOT NULL,
+    replaced_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX post_revisions_post_fk_idx ON post_revisions USING btree (post_fk, id DESC);
\ No newline at end of file
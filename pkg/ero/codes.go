@@ -8,6 +8,7 @@ const (
 	CodeExists               = 402
 	CodePermissionDenied     = 403
 	CodeNotFound             = 404
+	CodeTooManyRequests      = 429
 	CodeUnknownClient        = 499
 	CodeInternal             = 500
 	CodeUnimplemented        = 501
@@ -30,6 +31,8 @@ func ToGrpcCode(eroCode int) uint32 {
 		return 5
 	case CodeExists:
 		return 6
+	case CodeTooManyRequests:
+		return 8
 	case CodePermissionDenied:
 		return 7
 	case CodeUnimplemented:
@@ -58,6 +61,8 @@ func ToHttpCode(eroCode int) int {
 		return http.StatusNotFound
 	case CodeExists:
 		return http.StatusConflict
+	case CodeTooManyRequests:
+		return http.StatusTooManyRequests
 	case CodePermissionDenied:
 		return http.StatusPreconditionFailed
 	case CodeUnimplemented: